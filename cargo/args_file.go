@@ -0,0 +1,122 @@
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// LoadArgsFile reads the settings in the TOML or JSON file at path, returning them keyed by environment variable
+// name. A key like `install_args` sets BP_CARGO_INSTALL_ARGS, lists are joined with commas.
+func LoadArgsFile(path string) (map[string]string, error) {
+	var raw map[string]interface{}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+		}
+
+		err = json.Unmarshal(content, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s\n%w", path, err)
+		}
+	} else {
+		_, err := toml.DecodeFile(path, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s\n%w", path, err)
+		}
+	}
+
+	settings := make(map[string]string)
+	for key, value := range raw {
+		name := "BP_CARGO_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if !contains(KnownEnvironmentVariables, name) || name == "BP_CARGO_ARGS_FILE" {
+			return nil, fmt.Errorf("unknown key %q in %s", key, path)
+		}
+
+		s, err := argValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for key %q in %s: %w", key, path, err)
+		}
+		settings[name] = s
+	}
+
+	return settings, nil
+}
+
+func argValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if v != float64(int64(v)) {
+			return "", fmt.Errorf("%v is not a whole number", v)
+		}
+		return strconv.FormatInt(int64(v), 10), nil
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("lists may only contain strings")
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// ApplyArgsFile loads the file named by BP_CARGO_ARGS_FILE, relative to workingDir, and sets each of its settings
+// in the environment unless the environment variable is already set, so that the environment always wins
+func ApplyArgsFile(workingDir string, logger scribe.Emitter) error {
+	path := os.Getenv("BP_CARGO_ARGS_FILE")
+	if path == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	settings, err := LoadArgsFile(path)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	logger.Subprocess("Reading settings from %s", path)
+	for _, name := range names {
+		if _, ok := os.LookupEnv(name); ok {
+			logger.Action("%s is set in the environment, ignoring the value from the file", name)
+			continue
+		}
+
+		err = os.Setenv(name, settings[name])
+		if err != nil {
+			return fmt.Errorf("unable to set %s\n%w", name, err)
+		}
+		logger.Action("%s=%s", name, settings[name])
+	}
+	logger.Break()
+
+	return nil
+}
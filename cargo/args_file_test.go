@@ -0,0 +1,104 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testArgsFile(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		for _, name := range []string{
+			"BP_CARGO_ARGS_FILE",
+			"BP_CARGO_INSTALL_ARGS",
+			"BP_CARGO_UPX",
+			"BP_CARGO_MEMBER_TIMEOUT",
+			"BP_CARGO_WORKSPACE_MEMBERS",
+		} {
+			Expect(os.Unsetenv(name)).To(Succeed())
+		}
+	})
+
+	it("reads TOML settings keyed by environment variable", func() {
+		Expect(cargo.LoadArgsFile("testdata/args_file/cargo-args.toml")).To(Equal(map[string]string{
+			"BP_CARGO_INSTALL_ARGS":      "--locked",
+			"BP_CARGO_UPX":               "true",
+			"BP_CARGO_MEMBER_TIMEOUT":    "10m",
+			"BP_CARGO_WORKSPACE_MEMBERS": "api,worker",
+		}))
+	})
+
+	it("reads JSON settings", func() {
+		Expect(cargo.LoadArgsFile("testdata/args_file/cargo-args.json")).To(Equal(map[string]string{
+			"BP_CARGO_INSTALL_ARGS": "--locked",
+			"BP_CARGO_UPX":          "true",
+			"BP_CARGO_HTTP_TIMEOUT": "30",
+		}))
+	})
+
+	it("prefers the environment over the file", func() {
+		Expect(os.Setenv("BP_CARGO_ARGS_FILE", "args_file/cargo-args.toml")).To(Succeed())
+		Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--offline")).To(Succeed())
+
+		Expect(cargo.ApplyArgsFile("testdata", logger)).To(Succeed())
+		Expect(os.Getenv("BP_CARGO_INSTALL_ARGS")).To(Equal("--offline"))
+		Expect(os.Getenv("BP_CARGO_UPX")).To(Equal("true"))
+		Expect(os.Getenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Equal("api,worker"))
+		Expect(logBuf.String()).To(ContainSubstring("BP_CARGO_INSTALL_ARGS is set in the environment, ignoring the value from the file"))
+	})
+
+	it("does nothing when no file is set", func() {
+		Expect(cargo.ApplyArgsFile("testdata", logger)).To(Succeed())
+		Expect(logBuf.String()).To(BeEmpty())
+	})
+
+	context("when the file is invalid", func() {
+		var dir string
+
+		it.Before(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "args-file")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		it("names an unknown key", func() {
+			path := filepath.Join(dir, "args.toml")
+			Expect(ioutil.WriteFile(path, []byte("featuers = \"tls\"\n"), 0644)).To(Succeed())
+
+			_, err := cargo.LoadArgsFile(path)
+			Expect(err).To(MatchError(ContainSubstring("unknown key \"featuers\" in %s", path)))
+		})
+
+		it("names the key with an invalid value", func() {
+			path := filepath.Join(dir, "args.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"upx": {"level": 9}}`), 0644)).To(Succeed())
+
+			_, err := cargo.LoadArgsFile(path)
+			Expect(err).To(MatchError(ContainSubstring("invalid value for key \"upx\" in %s", path)))
+		})
+	})
+}
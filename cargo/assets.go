@@ -0,0 +1,129 @@
+package cargo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/fs"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// CollectAssets lists the files under srcDir, relative to it, that match one of patterns. A pattern that matches
+// a directory includes everything below it. The target directory and VCS metadata are never included.
+func CollectAssets(srcDir string, patterns []string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if rel == "target" || rel == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		for dir := rel; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			if matchesAny(patterns, dir) {
+				files = append(files, rel)
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to collect assets\n%w", err)
+	}
+
+	return files, nil
+}
+
+// ChecksumAssets computes a SHA256 over the names and contents of files, which are relative to srcDir
+func ChecksumAssets(srcDir string, files []string) (string, error) {
+	hash := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(hash, "%s\x00", file)
+
+		f, err := os.Open(filepath.Join(srcDir, file))
+		if err != nil {
+			return "", fmt.Errorf("unable to open %s\n%w", file, err)
+		}
+
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("unable to read %s\n%w", file, err)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// InstallAssets copies the files matching BP_CARGO_INCLUDE_FILES into the rust-assets launch layer. The layer is
+// left untouched when the assets have not changed since the last build, so that it can be reused from the
+// previous image independently of the binaries.
+func InstallAssets(assetsLayer packit.Layer, srcDir string, patterns []string, logger scribe.Emitter) (packit.Layer, error) {
+	files, err := CollectAssets(srcDir, patterns)
+	if err != nil {
+		return packit.Layer{}, err
+	}
+
+	if len(files) == 0 {
+		return packit.Layer{}, fmt.Errorf("BP_CARGO_INCLUDE_FILES [%s] did not match any files", strings.Join(patterns, ", "))
+	}
+
+	checksum, err := ChecksumAssets(srcDir, files)
+	if err != nil {
+		return packit.Layer{}, err
+	}
+
+	if previous, ok := assetsLayer.Metadata["assets_sha256"].(string); ok && previous == checksum {
+		logger.Subprocess("Reusing assets layer, %d files are unchanged", len(files))
+		assetsLayer.Launch = true
+		return assetsLayer, nil
+	}
+
+	assetsLayer, err = assetsLayer.Reset()
+	if err != nil {
+		return packit.Layer{}, err
+	}
+
+	logger.Subprocess("Copying %d files into assets layer", len(files))
+	for _, file := range files {
+		dest := filepath.Join(assetsLayer.Path, file)
+		err = os.MkdirAll(filepath.Dir(dest), 0755)
+		if err != nil {
+			return packit.Layer{}, fmt.Errorf("unable to create directory\n%w", err)
+		}
+
+		err = fs.Copy(filepath.Join(srcDir, file), dest)
+		if err != nil {
+			return packit.Layer{}, fmt.Errorf("unable to copy %s\n%w", file, err)
+		}
+	}
+
+	assetsLayer.Launch = true
+	assetsLayer.LaunchEnv.Override("RUST_ASSETS_DIR", assetsLayer.Path)
+	assetsLayer.Metadata = map[string]interface{}{
+		"assets_sha256": checksum,
+	}
+
+	return assetsLayer, nil
+}
@@ -0,0 +1,98 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testAssets(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir    string
+		layersDir string
+		logBuf    bytes.Buffer
+		logger    scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		srcDir, err = ioutil.TempDir("", "src-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		layersDir, err = ioutil.TempDir("", "layers")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(srcDir, "static", "css"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srcDir, "static", "index.html"), []byte("index"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srcDir, "static", "css", "site.css"), []byte("css"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srcDir, "config.yml"), []byte("config"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte(""), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(srcDir, "target", "static"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srcDir, "target", "static", "ignored"), []byte(""), 0644)).To(Succeed())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(srcDir)).To(Succeed())
+		Expect(os.RemoveAll(layersDir)).To(Succeed())
+	})
+
+	it("collects files and directories matching the patterns", func() {
+		files, err := cargo.CollectAssets(srcDir, []string{"static", "*.yml"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"config.yml", "static/css/site.css", "static/index.html"}))
+	})
+
+	it("copies the assets into the layer and records their checksum", func() {
+		layer, err := packit.Layers{Path: layersDir}.Get("rust-assets")
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = cargo.InstallAssets(layer, srcDir, []string{"static"}, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Cache).To(BeFalse())
+		Expect(layer.LaunchEnv).To(HaveKeyWithValue("RUST_ASSETS_DIR.override", filepath.Join(layersDir, "rust-assets")))
+		Expect(layer.Metadata).To(HaveKey("assets_sha256"))
+		Expect(ioutil.ReadFile(filepath.Join(layersDir, "rust-assets", "static", "css", "site.css"))).To(Equal([]byte("css")))
+		Expect(filepath.Join(layersDir, "rust-assets", "config.yml")).ToNot(BeAnExistingFile())
+	})
+
+	it("leaves the layer untouched when the assets are unchanged", func() {
+		files, err := cargo.CollectAssets(srcDir, []string{"static"})
+		Expect(err).NotTo(HaveOccurred())
+		checksum, err := cargo.ChecksumAssets(srcDir, files)
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err := packit.Layers{Path: layersDir}.Get("rust-assets")
+		Expect(err).NotTo(HaveOccurred())
+		layer.Metadata = map[string]interface{}{"assets_sha256": checksum}
+
+		layer, err = cargo.InstallAssets(layer, srcDir, []string{"static"}, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Metadata).To(Equal(map[string]interface{}{"assets_sha256": checksum}))
+		Expect(filepath.Join(layersDir, "rust-assets")).ToNot(BeADirectory())
+		Expect(logBuf.String()).To(ContainSubstring("Reusing assets layer, 2 files are unchanged"))
+	})
+
+	it("fails when nothing matches", func() {
+		layer, err := packit.Layers{Path: layersDir}.Get("rust-assets")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = cargo.InstallAssets(layer, srcDir, []string{"public"}, logger)
+		Expect(err).To(MatchError("BP_CARGO_INCLUDE_FILES [public] did not match any files"))
+	})
+}
@@ -0,0 +1,40 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+)
+
+// DefaultBacktrace returns the value for RUST_BACKTRACE from BP_CARGO_DEFAULT_BACKTRACE, `1` for a true value and
+// `full` for full backtraces. Empty means RUST_BACKTRACE is left alone.
+func DefaultBacktrace() (string, error) {
+	value := strings.TrimSpace(os.Getenv("BP_CARGO_DEFAULT_BACKTRACE"))
+	if value == "" {
+		return "", nil
+	}
+
+	if strings.EqualFold(value, "full") {
+		return "full", nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for BP_CARGO_DEFAULT_BACKTRACE %q, must be true, false or full", value)
+	}
+
+	if !enabled {
+		return "", nil
+	}
+
+	return "1", nil
+}
+
+// ConfigureBacktrace sets RUST_BACKTRACE in the launch environment of binaryLayer, as a default so that a value set
+// at runtime still wins
+func ConfigureBacktrace(binaryLayer *packit.Layer, backtrace string) {
+	binaryLayer.LaunchEnv.Default("RUST_BACKTRACE", backtrace)
+}
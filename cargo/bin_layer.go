@@ -0,0 +1,50 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// DefaultBinLayerName is the launch layer that binaries are installed into, unless BP_CARGO_BIN_LAYER_NAME names
+// another one
+const DefaultBinLayerName = "rust-bin"
+
+// reservedLayerNames are the layers that the buildpack uses for other things than the binaries, and the names that the
+// lifecycle keeps for itself
+var reservedLayerNames = []string{
+	"rust-cargo",
+	RegistryLayerName,
+	TargetLayerName,
+	SccacheLayerName,
+	"rust-assets",
+	"rust-depgraph",
+	"rust-otel",
+	"rust-processes",
+	"rust-sbom",
+	"build",
+	"launch",
+	"sbom",
+	"store",
+}
+
+var validLayerName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// BinLayerName returns the name of the launch layer from BP_CARGO_BIN_LAYER_NAME, defaulting to rust-bin. The name is
+// used as a directory and file name in the layers directory, so only letters, digits, `.`, `_` and `-` are allowed.
+func BinLayerName() (string, error) {
+	name := os.Getenv("BP_CARGO_BIN_LAYER_NAME")
+	if name == "" {
+		return DefaultBinLayerName, nil
+	}
+
+	if !validLayerName.MatchString(name) {
+		return "", fmt.Errorf("invalid value for BP_CARGO_BIN_LAYER_NAME %q, it must start with a letter or digit and only contain letters, digits, '.', '_' and '-'", name)
+	}
+
+	if contains(reservedLayerNames, name) {
+		return "", fmt.Errorf("BP_CARGO_BIN_LAYER_NAME %q is already used by the buildpack or the lifecycle, choose another name", name)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,375 @@
+package cargo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/fs"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// ParseListEnv reads a comma delimited list from the environment, dropping empty entries
+func ParseListEnv(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// ListBinaries returns the names of the regular files in binDir, sorted by name
+func ListBinaries(binDir string) ([]string, error) {
+	files, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.Type().IsRegular() {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DefaultBinary picks the binary to run by default from binaries. With more than one binary, it prefers the
+// `default-run` binary of the package in srcDir, then a binary named after the package and then the only `[[bin]]`
+// target. Names from Cargo.toml are mapped through renames. ambiguous is true when none of these matched and the
+// first binary was picked.
+func DefaultBinary(srcDir string, binaries []string, renames map[string]string) (name string, ambiguous bool, err error) {
+	if len(binaries) == 0 {
+		return "", false, nil
+	}
+
+	if len(binaries) == 1 {
+		return binaries[0], false, nil
+	}
+
+	manifest, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", false, err
+	}
+
+	var candidates []string
+	if manifest.Package != nil {
+		candidates = append(candidates, manifest.Package.DefaultRun, manifest.Package.Name)
+	}
+	if len(manifest.Bins) == 1 {
+		candidates = append(candidates, manifest.Bins[0].Name)
+	}
+
+	for _, candidate := range candidates {
+		if to, ok := renames[candidate]; ok {
+			candidate = to
+		}
+
+		if candidate != "" && contains(binaries, candidate) {
+			return candidate, false, nil
+		}
+	}
+
+	return binaries[0], true, nil
+}
+
+// SelectLaunchBinaries removes every binary in binDir that is not in selected
+func SelectLaunchBinaries(binDir string, selected []string, logger scribe.Emitter) error {
+	available, err := ListBinaries(binDir)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for _, name := range selected {
+		keep[name] = true
+	}
+
+	var missing []string
+	for _, name := range selected {
+		if !contains(available, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("launch binaries [%s] were not built, available binaries are [%s]",
+			strings.Join(missing, ", "), strings.Join(available, ", "))
+	}
+
+	for _, name := range available {
+		if keep[name] {
+			continue
+		}
+
+		logger.Subprocess("Removing %s from launch layer", name)
+		err := os.Remove(filepath.Join(binDir, name))
+		if err != nil {
+			return fmt.Errorf("unable to remove files\n%w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddExtraLaunchBinaries makes sure every binary in extras is present in binDir. Binaries that were not built are
+// copied in from the first of searchDirs that contains them.
+func AddExtraLaunchBinaries(binDir string, extras []string, searchDirs []string, logger scribe.Emitter) error {
+	available, err := ListBinaries(binDir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range extras {
+		if seen[name] {
+			return fmt.Errorf("extra launch binary %s is listed more than once", name)
+		}
+		seen[name] = true
+
+		if strings.ContainsRune(name, os.PathSeparator) {
+			return fmt.Errorf("invalid extra launch binary %q, name may not contain a path separator", name)
+		}
+
+		if contains(available, name) {
+			continue
+		}
+
+		source := findBinary(name, searchDirs)
+		if source == "" {
+			return fmt.Errorf("extra launch binary %s was not built and could not be found in [%s]",
+				name, strings.Join(searchDirs, ", "))
+		}
+
+		logger.Subprocess("Copying %s into launch layer", source)
+		err = os.MkdirAll(binDir, 0755)
+		if err != nil {
+			return fmt.Errorf("unable to create directory\n%w", err)
+		}
+
+		err = fs.Copy(source, filepath.Join(binDir, name))
+		if err != nil {
+			return fmt.Errorf("unable to copy %s\n%w", source, err)
+		}
+	}
+
+	return nil
+}
+
+func findBinary(name string, searchDirs []string) string {
+	for _, dir := range searchDirs {
+		candidate := filepath.Join(dir, name)
+		info, err := os.Stat(candidate)
+		if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ParseRenames parses a comma delimited list of `from=to` binary renames
+func ParseRenames(spec string) (map[string]string, error) {
+	renames := make(map[string]string)
+	targets := make(map[string]string)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid rename %q, must be of the form `internal-name=deployed-name`", item)
+		}
+
+		from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if strings.ContainsRune(to, os.PathSeparator) {
+			return nil, fmt.Errorf("invalid rename %q, deployed name may not contain a path separator", item)
+		}
+
+		if _, ok := renames[from]; ok {
+			return nil, fmt.Errorf("binary %s is renamed more than once", from)
+		}
+
+		if other, ok := targets[to]; ok {
+			return nil, fmt.Errorf("binaries %s and %s are both renamed to %s", other, from, to)
+		}
+
+		renames[from] = to
+		targets[to] = from
+	}
+
+	return renames, nil
+}
+
+// RenameBinaries renames the binaries in binDir, failing if a binary is missing or a rename collides with
+// another binary
+func RenameBinaries(binDir string, renames map[string]string, logger scribe.Emitter) error {
+	available, err := ListBinaries(binDir)
+	if err != nil {
+		return err
+	}
+
+	var froms []string
+	for from := range renames {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	for _, from := range froms {
+		to := renames[from]
+		if !contains(available, from) {
+			return fmt.Errorf("unable to rename %s, available binaries are [%s]", from, strings.Join(available, ", "))
+		}
+
+		if _, renamed := renames[to]; contains(available, to) && !renamed {
+			return fmt.Errorf("unable to rename %s to %s, a binary named %s already exists", from, to, to)
+		}
+	}
+
+	// rename through temporary names so that swaps like `a=b,b=a` work
+	for _, from := range froms {
+		err := os.Rename(filepath.Join(binDir, from), filepath.Join(binDir, from+".rename"))
+		if err != nil {
+			return fmt.Errorf("unable to rename %s\n%w", from, err)
+		}
+	}
+
+	for _, from := range froms {
+		to := renames[from]
+		logger.Subprocess("Renaming %s to %s", from, to)
+		err := os.Rename(filepath.Join(binDir, from+".rename"), filepath.Join(binDir, to))
+		if err != nil {
+			return fmt.Errorf("unable to rename %s\n%w", from, err)
+		}
+	}
+
+	return nil
+}
+
+// StripNonBinaries removes everything in layerDir except the bin directory, like the `.crates.toml` and
+// `.crates2.json` that cargo install keeps track of installed packages in, so that only the binaries are launched
+func StripNonBinaries(layerDir string, logger scribe.Emitter) error {
+	files, err := os.ReadDir(layerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	var removed []string
+	for _, file := range files {
+		if file.IsDir() && file.Name() == "bin" {
+			continue
+		}
+
+		err := os.RemoveAll(filepath.Join(layerDir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", file.Name(), err)
+		}
+		removed = append(removed, file.Name())
+	}
+
+	if len(removed) > 0 {
+		logger.Subprocess("Removed %s from the launch layer, only the binaries are kept", strings.Join(removed, ", "))
+	}
+
+	return nil
+}
+
+// LogBinarySummary lists each binary in binDir with its size, along with the profile and the target that they were
+// built with. Only binDir is listed, so cargo's bookkeeping files never show up in the summary.
+func LogBinarySummary(binDir string, profile string, target string, logger scribe.Emitter) error {
+	binaries, err := ListBinaries(binDir)
+	if err != nil {
+		return err
+	}
+
+	logger.Process("Binary summary")
+	logger.Subprocess("Profile: %s", profile)
+	logger.Subprocess("Target: %s", describeTarget(target))
+	logger.Subprocess("Binaries:")
+	for _, name := range binaries {
+		info, err := os.Stat(filepath.Join(binDir, name))
+		if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", name, err)
+		}
+		logger.Action("%s: %s", name, FormatSize(info.Size()))
+	}
+	logger.Break()
+
+	return nil
+}
+
+// ChecksumBinaries computes the SHA256 of each binary in binDir, keyed by binary name
+func ChecksumBinaries(binDir string) (map[string]string, error) {
+	binaries, err := ListBinaries(binDir)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string)
+	for _, name := range binaries {
+		file, err := os.Open(filepath.Join(binDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s\n%w", name, err)
+		}
+
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s\n%w", name, err)
+		}
+
+		checksums[name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return checksums, nil
+}
+
+// WriteChecksums writes the checksums to path in the format used by `sha256sum`, with binaries listed relative to
+// the layer root so that `sha256sum -c` can be run from there
+func WriteChecksums(path string, checksums map[string]string) error {
+	var names []string
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&lines, "%s  bin/%s\n", checksums[name], name)
+	}
+
+	err := os.WriteFile(path, []byte(lines.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBinaries(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binDir string
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		binDir, err = ioutil.TempDir("", "bin-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("app"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "tool"), []byte("tool"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "verify"), []byte("verify"), 0755)).To(Succeed())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(binDir)).To(Succeed())
+	})
+
+	context("listing binaries", func() {
+		it("returns the sorted file names", func() {
+			Expect(os.Mkdir(filepath.Join(binDir, "a-dir"), 0755)).To(Succeed())
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app", "tool", "verify"}))
+		})
+
+		it("handles a missing directory", func() {
+			Expect(cargo.ListBinaries(filepath.Join(binDir, "missing"))).To(BeEmpty())
+		})
+	})
+
+	context("selecting launch binaries", func() {
+		it("keeps only the selected binary", func() {
+			Expect(cargo.SelectLaunchBinaries(binDir, []string{"app"}, logger)).To(Succeed())
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app"}))
+			Expect(logBuf.String()).To(ContainSubstring("Removing tool from launch layer"))
+			Expect(logBuf.String()).To(ContainSubstring("Removing verify from launch layer"))
+		})
+
+		it("keeps multiple selected binaries", func() {
+			Expect(cargo.SelectLaunchBinaries(binDir, []string{"app", "tool"}, logger)).To(Succeed())
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app", "tool"}))
+		})
+
+		it("fails when a selected binary was not built", func() {
+			err := cargo.SelectLaunchBinaries(binDir, []string{"app", "missing"}, logger)
+			Expect(err).To(MatchError("launch binaries [missing] were not built, available binaries are [app, tool, verify]"))
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app", "tool", "verify"}))
+		})
+	})
+
+	context("adding extra launch binaries", func() {
+		var helperDir string
+
+		it.Before(func() {
+			var err error
+			helperDir, err = ioutil.TempDir("", "helper-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(helperDir, "migrate"), []byte("migrate"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(helperDir, "app"), []byte("other app"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(helperDir, "notes"), []byte("notes"), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(helperDir)).To(Succeed())
+		})
+
+		it("copies binaries that were not built from the search dirs", func() {
+			Expect(cargo.AddExtraLaunchBinaries(binDir, []string{"migrate"}, []string{"/does/not/exist", helperDir}, logger)).To(Succeed())
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app", "migrate", "tool", "verify"}))
+			Expect(ioutil.ReadFile(filepath.Join(binDir, "migrate"))).To(Equal([]byte("migrate")))
+			Expect(logBuf.String()).To(ContainSubstring("Copying %s into launch layer", filepath.Join(helperDir, "migrate")))
+		})
+
+		it("prefers binaries that were built", func() {
+			Expect(cargo.AddExtraLaunchBinaries(binDir, []string{"app"}, []string{helperDir}, logger)).To(Succeed())
+			Expect(ioutil.ReadFile(filepath.Join(binDir, "app"))).To(Equal([]byte("app")))
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+
+		it("fails when a binary cannot be found", func() {
+			err := cargo.AddExtraLaunchBinaries(binDir, []string{"notes"}, []string{helperDir}, logger)
+			Expect(err).To(MatchError(ContainSubstring("extra launch binary notes was not built and could not be found in [%s]", helperDir)))
+		})
+
+		it("fails when a binary is listed twice", func() {
+			err := cargo.AddExtraLaunchBinaries(binDir, []string{"migrate", "migrate"}, []string{helperDir}, logger)
+			Expect(err).To(MatchError("extra launch binary migrate is listed more than once"))
+		})
+
+		it("rejects names with a path separator", func() {
+			err := cargo.AddExtraLaunchBinaries(binDir, []string{"../migrate"}, []string{helperDir}, logger)
+			Expect(err).To(MatchError(ContainSubstring("name may not contain a path separator")))
+		})
+	})
+
+	context("renaming binaries", func() {
+		it("parses the rename mapping", func() {
+			Expect(cargo.ParseRenames("app=server, tool=cli,")).To(Equal(map[string]string{"app": "server", "tool": "cli"}))
+			Expect(cargo.ParseRenames("")).To(BeEmpty())
+		})
+
+		it("rejects invalid mappings", func() {
+			_, err := cargo.ParseRenames("app")
+			Expect(err).To(MatchError("invalid rename \"app\", must be of the form `internal-name=deployed-name`"))
+
+			_, err = cargo.ParseRenames("app=bin/server")
+			Expect(err).To(MatchError("invalid rename \"app=bin/server\", deployed name may not contain a path separator"))
+
+			_, err = cargo.ParseRenames("app=server,app=other")
+			Expect(err).To(MatchError("binary app is renamed more than once"))
+
+			_, err = cargo.ParseRenames("app=server,tool=server")
+			Expect(err).To(MatchError("binaries app and tool are both renamed to server"))
+		})
+
+		it("renames the binaries", func() {
+			Expect(cargo.RenameBinaries(binDir, map[string]string{"app": "server"}, logger)).To(Succeed())
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"server", "tool", "verify"}))
+			Expect(ioutil.ReadFile(filepath.Join(binDir, "server"))).To(Equal([]byte("app")))
+			Expect(logBuf.String()).To(ContainSubstring("Renaming app to server"))
+		})
+
+		it("swaps binary names", func() {
+			Expect(cargo.RenameBinaries(binDir, map[string]string{"app": "tool", "tool": "app"}, logger)).To(Succeed())
+			Expect(ioutil.ReadFile(filepath.Join(binDir, "tool"))).To(Equal([]byte("app")))
+			Expect(ioutil.ReadFile(filepath.Join(binDir, "app"))).To(Equal([]byte("tool")))
+		})
+
+		it("fails when a rename collides with an existing binary", func() {
+			err := cargo.RenameBinaries(binDir, map[string]string{"app": "tool"}, logger)
+			Expect(err).To(MatchError("unable to rename app to tool, a binary named tool already exists"))
+			Expect(cargo.ListBinaries(binDir)).To(Equal([]string{"app", "tool", "verify"}))
+		})
+
+		it("fails when the binary does not exist", func() {
+			err := cargo.RenameBinaries(binDir, map[string]string{"missing": "server"}, logger)
+			Expect(err).To(MatchError("unable to rename missing, available binaries are [app, tool, verify]"))
+		})
+	})
+
+	context("choosing the default binary", func() {
+		var srcDir string
+
+		it.Before(func() {
+			var err error
+			srcDir, err = ioutil.TempDir("", "src-dir")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(srcDir)).To(Succeed())
+		})
+
+		it("uses the only binary", func() {
+			name, ambiguous, err := cargo.DefaultBinary(srcDir, []string{"tool"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("tool"))
+			Expect(ambiguous).To(BeFalse())
+		})
+
+		it("returns nothing without binaries", func() {
+			name, _, err := cargo.DefaultBinary(srcDir, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+		})
+
+		it("prefers default-run and then the package name", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\ndefault-run = \"tool\"\n"), 0644)).To(Succeed())
+
+			name, ambiguous, err := cargo.DefaultBinary(srcDir, []string{"app", "tool", "verify"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("tool"))
+			Expect(ambiguous).To(BeFalse())
+
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+
+			name, _, err = cargo.DefaultBinary(srcDir, []string{"app", "tool", "verify"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("app"))
+		})
+
+		it("uses the only [[bin]] target, following renames", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n\n[[bin]]\nname = \"server\"\n"), 0644)).To(Succeed())
+
+			name, ambiguous, err := cargo.DefaultBinary(srcDir, []string{"api", "verify"}, map[string]string{"server": "api"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("api"))
+			Expect(ambiguous).To(BeFalse())
+		})
+
+		it("picks the first binary of a workspace and reports the choice as ambiguous", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"api\", \"worker\"]\n"), 0644)).To(Succeed())
+
+			name, ambiguous, err := cargo.DefaultBinary(srcDir, []string{"api", "worker"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("api"))
+			Expect(ambiguous).To(BeTrue())
+		})
+	})
+
+	context("stripping the launch layer", func() {
+		var layerDir string
+
+		it.Before(func() {
+			var err error
+			layerDir, err = ioutil.TempDir("", "layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(layerDir, "bin"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(layerDir, "bin", "app"), []byte("app"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(layerDir, ".crates.toml"), []byte{}, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(layerDir, ".crates2.json"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(layerDir)).To(Succeed())
+		})
+
+		it("keeps only the bin directory", func() {
+			Expect(cargo.StripNonBinaries(layerDir, logger)).To(Succeed())
+
+			files, err := ioutil.ReadDir(layerDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(filepath.Join(layerDir, "bin", "app")).To(BeARegularFile())
+			Expect(logBuf.String()).To(ContainSubstring("Removed .crates.toml, .crates2.json from the launch layer, only the binaries are kept"))
+		})
+
+		it("logs nothing when there is nothing to remove", func() {
+			Expect(os.Remove(filepath.Join(layerDir, ".crates.toml"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(layerDir, ".crates2.json"))).To(Succeed())
+
+			Expect(cargo.StripNonBinaries(layerDir, logger)).To(Succeed())
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+
+		it("summarizes the binaries without cargo's bookkeeping", func() {
+			Expect(ioutil.WriteFile(filepath.Join(layerDir, "bin", "worker"), make([]byte, 2048), 0755)).To(Succeed())
+
+			Expect(cargo.LogBinarySummary(filepath.Join(layerDir, "bin"), "release", "x86_64-unknown-linux-musl", logger)).To(Succeed())
+			Expect(logBuf.String()).To(ContainSubstring("Binary summary"))
+			Expect(logBuf.String()).To(ContainSubstring("Profile: release"))
+			Expect(logBuf.String()).To(ContainSubstring("Target: x86_64-unknown-linux-musl"))
+			Expect(logBuf.String()).To(MatchRegexp(`app: 3 B\n\s+worker: 2\.0 KiB`))
+			Expect(logBuf.String()).NotTo(ContainSubstring(".crates"))
+		})
+
+		it("names the host when there is no build target", func() {
+			Expect(cargo.LogBinarySummary(filepath.Join(layerDir, "bin"), "dev", "", logger)).To(Succeed())
+			Expect(logBuf.String()).To(ContainSubstring("Target: the host"))
+		})
+	})
+
+	context("parsing list env vars", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_TEST_LIST")).To(Succeed())
+		})
+
+		it("trims and drops empty entries", func() {
+			Expect(os.Setenv("BP_CARGO_TEST_LIST", " app, ,tool,")).To(Succeed())
+			Expect(cargo.ParseListEnv("BP_CARGO_TEST_LIST")).To(Equal([]string{"app", "tool"}))
+		})
+
+		it("handles unset", func() {
+			Expect(cargo.ParseListEnv("BP_CARGO_TEST_LIST")).To(BeEmpty())
+		})
+	})
+}
@@ -1,9 +1,14 @@
 package cargo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,96 +24,1292 @@ import (
 // Runner is something capable of running Cargo
 type Runner interface {
 	Install(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error
-	InstallMember(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error
+	InstallMember(ctx context.Context, memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error
 	WorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]url.URL, error)
+	DependencyGraph(srcDir string, workLayer packit.Layer, destLayer packit.Layer) (string, error)
+	EnsureComponents(srcDir string, components []string) error
+	TargetInstalled(srcDir string, target string) (bool, error)
+	TargetPackages(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error)
+	Test(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error
+	Version(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) (string, error)
+	Fetch(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error
+	Clean(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error
+	Clippy(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error
+	CheckToolchain() error
+	Examples(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error)
+}
+
+//go:generate mockery --name MemberStreamer --case=underscore
+
+// MemberStreamer is implemented by runners that can resolve workspace members one at a time, so that building can
+// start before every member is known. StreamWorkspaceMembers closes members when it returns.
+type MemberStreamer interface {
+	StreamWorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer, members chan<- url.URL) error
+}
+
+//go:generate mockery --name CommandPlanner --case=underscore
+
+// CommandPlanner is implemented by runners that can resolve the arguments they would pass to cargo for a member
+// without running cargo, so that BP_CARGO_DRY_RUN can log them. No arguments are returned for a skipped member.
+type CommandPlanner interface {
+	InstallCommand(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error)
 }
 
 // Build does the actual install of Rust
-func Build(runner Runner, clock chronos.Clock, logger scribe.Emitter) packit.BuildFunc {
+func Build(runner Runner, compressor Compressor, clock chronos.Clock, logger scribe.Emitter) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 		logger.Process("Cargo is checking if your Rust project needs to be built")
 
-		cargoLayer, err := context.Layers.Get("rust-cargo")
+		// the summary is printed when the build ends, including when a gate fails it
+		var gates GateSummary
+		defer func() { gates.Log(logger) }()
+
+		// checked before any layer is touched, so that a missing toolchain doesn't leave a partial cache behind
+		err := runner.CheckToolchain()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = ApplyArgsFile(context.WorkingDir, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		strict, err := ParseBoolEnv("BP_CARGO_STRICT_CONFIG")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if strict {
+			err = ValidateEnvironment(os.Environ())
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		target, err := EnvironmentTarget()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if target != "" {
+			logger.Subprocess("Building for target %s", target)
+		}
+
+		maxImageSize, err := MaxImageSize()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		cacheOnly, err := ParseBoolEnv("BP_CARGO_CACHE_ONLY")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if cacheOnly {
+			logger.Subprocess("BP_CARGO_CACHE_ONLY is set, the application is built to warm the cache but will not be added to the image")
+		}
+
+		verbosity, err := Verbosity()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// scribe has no log levels, so the buildpack's own decisions are only logged when BP_CARGO_VERBOSE is set
+		debug := func(format string, v ...interface{}) {
+			if verbosity > 0 {
+				logger.Detail(format, v...)
+			}
+		}
+
+		if verbosity > 0 {
+			logger.Subprocess("BP_CARGO_VERBOSE is set, cargo is run with -%s", strings.Repeat("v", verbosity))
+		}
+
+		customHome, err := CustomCargoHome()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		disableCache, err := ParseBoolEnv("BP_CARGO_DISABLE_CACHE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if disableCache && cacheOnly {
+			return packit.BuildResult{}, fmt.Errorf("BP_CARGO_DISABLE_CACHE and BP_CARGO_CACHE_ONLY cannot both be set")
+		}
+
+		if disableCache {
+			logger.Subprocess("BP_CARGO_DISABLE_CACHE is set, caching is disabled and the application is built from scratch")
+		}
+
+		dryRun, err := ParseBoolEnv("BP_CARGO_DRY_RUN")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// planned before any layer is touched, everything after this point changes the layers
+		if dryRun {
+			return planBuild(runner, context, !disableCache, logger)
+		}
+
+		cargoLayer, err := context.Layers.Get("rust-cargo")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		cargoLayer, err = withCache(cargoLayer, !disableCache)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		_, cacheHit := cargoLayer.Metadata["built_at"]
+		cargoHome := CargoHome(cargoLayer)
+		debug("Using %s as CARGO_HOME", cargoHome)
+
+		// layers that the build uses, but that are left out of the result so that they are not cached
+		var unmanagedLayers []string
+		if customHome != "" {
+			logger.Subprocess("Using %s from BP_CARGO_HOME as CARGO_HOME, the %s and %s cache layers are not kept", customHome, cargoLayer.Name, RegistryLayerName)
+			unmanagedLayers = []string{cargoLayer.Name, RegistryLayerName}
+		}
+
+		binLayerName, err := BinLayerName()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		binaryLayer, err := context.Layers.Get(binLayerName)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		binaryLayer.Launch = true
+
+		registryLayer, err := context.Layers.Get(RegistryLayerName)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		registryLayer, err = withCache(registryLayer, !disableCache)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// cache layers that only some builds use, they are kept after the registry layer
+		var extraCacheLayers []packit.Layer
+
+		useSccache, err := ParseBoolEnv("BP_CARGO_SCCACHE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if useSccache {
+			sccacheAvailable, err := SccacheEnabled()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if sccacheAvailable {
+				sccacheLayer, err := context.Layers.Get(SccacheLayerName)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				sccacheLayer, err = withCache(sccacheLayer, !disableCache)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				version, err := SccacheVersion()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				err = os.MkdirAll(sccacheLayer.Path, 0755)
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("unable to create %s\n%w", sccacheLayer.Path, err)
+				}
+
+				sccacheLayer.Metadata = map[string]interface{}{
+					"sccache_version": version,
+				}
+				extraCacheLayers = append(extraCacheLayers, sccacheLayer)
+
+				logger.Subprocess("Compiling with %s, the cache is kept in the %s layer", version, SccacheLayerName)
+			} else {
+				logger.Subprocess("WARNING: BP_CARGO_SCCACHE is set, but sccache could not be found on the PATH, building without it")
+				useSccache = false
+			}
+		}
+
+		stripMethod, err := StripMethod()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		switch stripMethod {
+		case StripWithTool:
+			logger.Subprocess("Debug symbols are removed with strip once the binaries are installed")
+		case StripWithRustFlags:
+			logger.Subprocess("WARNING: BP_CARGO_STRIP is set, but strip could not be found on the PATH, passing `%s` to rustc instead", StripRustFlags)
+		}
+
+		then := clock.Now()
+
+		// timed logs how long a phase took, like `Fetched workspace members (1.2s)`
+		timed := func(done string, start time.Time) {
+			logger.Action("%s (%s)", done, clock.Now().Sub(start).Round(time.Millisecond))
+		}
+
+		srcDir, err := ProjectDir(context.WorkingDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = CheckLockfile(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = ValidateWorkspaceMembers(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if installBins := RequestedBins(); len(installBins) > 0 {
+			err = ValidateInstallBins(srcDir, installBins)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			logger.Subprocess("Installing only the binaries [%s] from BP_CARGO_INSTALL_BINS", strings.Join(installBins, ", "))
+		}
+
+		// the version is only recorded for auditing, so not being able to read it doesn't stop the build
+		rustVersion, err := runner.Version(srcDir, cargoLayer, binaryLayer)
+		if err != nil {
+			logger.Subprocess("WARNING: unable to determine the Rust toolchain version: %s", err)
+		} else {
+			logger.Subprocess("Using %s", rustVersion)
+		}
+
+		targetLayer, err := context.Layers.Get(TargetLayerName)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		targetLayer, err = PrepareTargetLayer(targetLayer, rustVersion, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		targetLayer, err = withCache(targetLayer, !disableCache)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		debug("Using %s as CARGO_TARGET_DIR", targetLayer.Path)
+
+		// the target directory used to be kept in the cargo layer
+		err = os.RemoveAll(filepath.Join(cargoLayer.Path, "target"))
+		if err != nil {
+			return packit.BuildResult{}, fmt.Errorf("unable to remove the old target directory\n%w", err)
+		}
+
+		sourceChecksum, err := SourceChecksum(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		configChecksum, err := ConfigChecksum(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		debug("Source checksum %s, configuration checksum %s", sourceChecksum, configChecksum)
+
+		// the inputs are declared by the build scripts of the previous build, so they are read before anything is built
+		previousInputs, _ := cargoLayer.Metadata["build_script_inputs_sha256"].(string)
+		currentInputs := buildScriptInputsChecksum(targetLayer.Path, srcDir, logger)
+
+		commit, err := GitCommit(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// collectLayers writes the optional layers next to the binaries, which were either just built or reused from
+		// the last build, and checks the size of the result. Reused binaries stay in the previous image, they are not
+		// on disk to be measured.
+		collectLayers := func(processes []packit.Process, buildTarget string, reused bool) ([]packit.Layer, error) {
+			layers := []packit.Layer{
+				cargoLayer,
+				binaryLayer,
+			}
+
+			// the steps write to their own layers and don't depend on each other, so their file work runs
+			// concurrently. Anything that runs cargo is done first, one command at a time, because the runner logs
+			// directly rather than to the task's logger.
+			var tasks []Task
+
+			includeFiles := ParseListEnv("BP_CARGO_INCLUDE_FILES")
+			if len(includeFiles) > 0 {
+				assetsLayer, err := context.Layers.Get("rust-assets")
+				if err != nil {
+					return nil, err
+				}
+
+				index := len(layers)
+				layers = append(layers, assetsLayer)
+				tasks = append(tasks, func(logger scribe.Emitter) error {
+					assetsLayer, err := InstallAssets(assetsLayer, srcDir, includeFiles, logger)
+					if err != nil {
+						return err
+					}
+
+					layers[index] = assetsLayer
+					return nil
+				})
+			}
+
+			emitOTel, err := ParseBoolEnv("BP_CARGO_EMIT_OTEL")
+			if err != nil {
+				return nil, err
+			}
+
+			if emitOTel {
+				otelLayer, err := context.Layers.Get("rust-otel")
+				if err != nil {
+					return nil, err
+				}
+
+				// the attributes describe the build, so they are only available to the build steps that follow
+				otelLayer.Build = true
+
+				duration := clock.Now().Sub(then)
+				layers = append(layers, otelLayer)
+				tasks = append(tasks, func(logger scribe.Emitter) error {
+					attributes, err := CollectOTelAttributes(context, duration, cacheHit, rustVersion, filepath.Join(binaryLayer.Path, "bin"))
+					if err != nil {
+						return err
+					}
+
+					err = WriteOTelAttributes(otelLayer, attributes)
+					if err != nil {
+						return err
+					}
+
+					logger.Subprocess("Build attributes written to %s", filepath.Join(otelLayer.Path, OTelAttributesFile))
+					logger.Break()
+					return nil
+				})
+			}
+
+			emitDepGraph, err := ParseBoolEnv("BP_CARGO_EMIT_DEPGRAPH")
+			if err != nil {
+				return nil, err
+			}
+
+			if emitDepGraph {
+				depGraphLayer, err := context.Layers.Get("rust-depgraph")
+				if err != nil {
+					return nil, err
+				}
+
+				// the graph is for tooling that runs during the build, it is not shipped
+				depGraphLayer.Build = true
+
+				graph, err := runner.DependencyGraph(srcDir, cargoLayer, binaryLayer)
+				if err != nil {
+					return nil, err
+				}
+
+				layers = append(layers, depGraphLayer)
+				tasks = append(tasks, func(logger scribe.Emitter) error {
+					err := WriteDependencyGraph(depGraphLayer.Path, graph)
+					if err != nil {
+						return err
+					}
+
+					logger.Subprocess("Dependency graph written to %s", filepath.Join(depGraphLayer.Path, DependencyGraphFile))
+					logger.Break()
+					return nil
+				})
+			}
+
+			emitProcesses, err := ParseBoolEnv("BP_CARGO_EMIT_PROCESSES")
+			if err != nil {
+				return nil, err
+			}
+
+			if emitProcesses {
+				processesLayer, err := context.Layers.Get("rust-processes")
+				if err != nil {
+					return nil, err
+				}
+
+				// the list is for tooling that runs during the build, launch.toml has the processes in the image
+				processesLayer.Build = true
+
+				layers = append(layers, processesLayer)
+				tasks = append(tasks, func(logger scribe.Emitter) error {
+					err := WriteProcesses(processesLayer.Path, NewProcessList(processes, DefaultProcessType))
+					if err != nil {
+						return err
+					}
+
+					logger.Subprocess("Process list written to %s", filepath.Join(processesLayer.Path, ProcessesFile))
+					logger.Break()
+					return nil
+				})
+			}
+
+			emitSBOM, err := ParseBoolEnv("BP_CARGO_EMIT_SBOM")
+			if err != nil {
+				return nil, err
+			}
+
+			if emitSBOM {
+				sbomLayer, err := context.Layers.Get("rust-sbom")
+				if err != nil {
+					return nil, err
+				}
+
+				// the SBOM describes what is shipped, so it goes into the image and is available to later buildpacks
+				sbomLayer.Launch = true
+				sbomLayer.Build = true
+
+				sbomExclude := ParseListEnv("BP_CARGO_SBOM_EXCLUDE")
+				sbomWithHashes, err := ParseBoolEnv("BP_CARGO_SBOM_WITH_HASHES")
+				if err != nil {
+					return nil, err
+				}
+
+				lockfile, err := ParseLockfile(filepath.Join(srcDir, "Cargo.lock"))
+				if err != nil {
+					return nil, err
+				}
+
+				packages, err := runner.TargetPackages(srcDir, cargoLayer, binaryLayer)
+				if err != nil {
+					return nil, err
+				}
+
+				layers = append(layers, sbomLayer)
+				tasks = append(tasks, func(logger scribe.Emitter) error {
+					lockfile, omitted := lockfile.Select(packages)
+					if len(omitted) > 0 {
+						var names []string
+						for _, pkg := range omitted {
+							names = append(names, fmt.Sprintf("%s %s", pkg.Name, pkg.Version))
+						}
+						logger.Subprocess("Leaving crates that are not compiled for %s out of the SBOM: %s", describeTarget(buildTarget), strings.Join(names, ", "))
+					}
+
+					sbom, err := NewSBOM(lockfile, sbomExclude, sbomWithHashes, logger)
+					if err != nil {
+						return err
+					}
+
+					err = WriteSBOM(sbomLayer.Path, sbom)
+					if err != nil {
+						return err
+					}
+
+					logger.Subprocess("SBOM written to %s", filepath.Join(sbomLayer.Path, SBOMFile))
+					logger.Break()
+					return nil
+				})
+			}
+
+			err = RunTasks(logger, PostBuildConcurrency, tasks...)
+			if err != nil {
+				return nil, err
+			}
+
+			layers = append(layers, registryLayer, targetLayer)
+			layers = append(layers, extraCacheLayers...)
+			layers = withoutLayers(layers, unmanagedLayers)
+
+			if !reused {
+				err = CheckImageSize(layers, maxImageSize, logger)
+				if maxImageSize > 0 {
+					gates.Record("image size", err)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return layers, nil
+		}
+
+		reuse := !cacheOnly && !disableCache && previousInputs == currentInputs &&
+			canReuseBinaries(binaryLayer, sourceChecksum, configChecksum, rustVersion, commit)
+		if reuse && (os.Getenv("BP_CARGO_VALIDATE_CMD") != "" || maxImageSize > 0) {
+			// the binaries of the previous image are not on disk, so they can't be validated or measured
+			debug("Not reusing cached binaries, BP_CARGO_VALIDATE_CMD and BP_CARGO_MAX_IMAGE_SIZE check freshly built binaries")
+		} else if reuse {
+			logger.Subprocess("Reusing cached binaries")
+
+			renames, err := ParseRenames(os.Getenv("BP_CARGO_RENAME_BIN"))
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			extraProcesses := append(ParseListEnv("BP_CARGO_EXTRA_LAUNCH_BINS"), cachedExamples(binaryLayer)...)
+			processes, err := launchProcesses(srcDir, binaryLayer.Path, cachedBinaries(binaryLayer), extraProcesses, renames, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// only compiling is skipped, the layers that describe the build are written as usual
+			buildTarget, err := BuildTarget(srcDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			layers, err := collectLayers(processes, buildTarget, true)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			return packit.BuildResult{
+				Layers: layers,
+				Launch: packit.LaunchMetadata{
+					Processes: processes,
+				},
+			}, nil
+		} else if _, ok := binaryLayer.Metadata["source_sha256"]; ok && !cacheOnly {
+			debug("Not reusing cached binaries, the sources, configuration, build script inputs, git commit or Rust toolchain have changed")
+		}
+
+		err = NormalizePermissions(cargoLayer.Path, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = NormalizePermissions(targetLayer.Path, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if customHome == "" {
+			err = NormalizePermissions(registryLayer.Path, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		preserver := mtimes.NewPreserver(logger)
+		err = preserver.Restore(cargoLayer.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = preserver.Restore(targetLayer.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// keeps the modification times of both cache layers, so that cargo sees unchanged objects as fresh
+		preserveCache := func() error {
+			err := preserver.Preserve(cargoLayer.Path)
+			if err != nil {
+				return err
+			}
+			return preserver.Preserve(targetLayer.Path)
+		}
+
+		targetDir := targetLayer.Path
+
+		toolchain, err := LoadToolchain(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if previous, _ := cargoLayer.Metadata["toolchain_channel"].(string); cacheHit && previous != toolchain.Channel {
+			logger.Subprocess("Pinned toolchain has changed from %s to %s since the last build, removing cached build output",
+				describeChannel(previous), describeChannel(toolchain.Channel))
+			targetLayer, err = targetLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			targetLayer, err = PrepareTargetLayer(targetLayer, rustVersion, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+		if previousInputs != "" && currentInputs != "" && previousInputs != currentInputs {
+			logger.Subprocess("Build script inputs have changed since the last build, affected build scripts will be rerun")
+		}
+
+		cleanEnv, err := ParseBoolEnv("BP_CARGO_CLEAN_ENV")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if cleanEnv {
+			logger.Subprocess("Running cargo with a clean environment, only %s are passed through", strings.Join(CleanEnvironAllowlist, ", "))
+		}
+
+		if tokens := RegistryTokens(os.Environ()); len(tokens) > 0 {
+			logger.Subprocess("Passing registry tokens from %s to cargo", strings.Join(tokens, ", "))
+		}
+
+		httpConfig, err := LoadHTTPConfig()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		lockChecksum, err := LockfileChecksum(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// a custom cargo home is shared with other builds, the buildpack passes its settings through the environment
+		// and leaves the registry in it alone
+		if customHome == "" {
+			err = WriteCargoConfig(cargoHome, CargoConfig{HTTP: httpConfig})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// the checkouts of git dependencies are kept in cargo home, they go stale the same way crate sources do
+			if previous, ok := cargoLayer.Metadata["cargo_lock_sha256"].(string); cacheHit && ok && (lockChecksum == "" || previous != lockChecksum) {
+				logger.Subprocess("Cargo.lock has changed since the last build, removing git dependency checkouts from CARGO_HOME")
+				err = PruneGitCheckouts(cargoHome)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			// without a Cargo.lock the dependencies are resolved again, so unchanged sources can't be assumed
+			if previous, ok := registryLayer.Metadata["cargo_lock_sha256"].(string); ok && (lockChecksum == "" || previous != lockChecksum) {
+				logger.Subprocess("Cargo.lock has changed since the last build, removing extracted crate sources from the registry cache")
+				err = PruneRegistrySources(registryLayer.Path)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			err = LinkRegistry(cargoHome, registryLayer.Path)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			registryLayer.Metadata = map[string]interface{}{
+				"cargo_lock_sha256": lockChecksum,
+			}
+		}
+
+		offline, err := ParseBoolEnv("BP_CARGO_OFFLINE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if offline {
+			logger.Subprocess("BP_CARGO_OFFLINE is set, cargo will only use vendored and cached dependencies")
+			CheckOfflineSources(srcDir, logger)
+		}
+
+		prefetchDeps, err := ParseBoolEnv("BP_CARGO_PREFETCH")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if prefetchDeps && offline {
+			logger.Subprocess("Skipping BP_CARGO_PREFETCH because BP_CARGO_OFFLINE is set")
+			prefetchDeps = false
+		}
+
+		// the priority is set first, so that cargo fetch runs with it too
+		priority, err := LoadPriority()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		ApplyPriority(priority, logger)
+
+		coverage, err := ParseBoolEnv("BP_CARGO_COVERAGE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if previous, _ := cargoLayer.Metadata["coverage"].(bool); cacheHit && previous != coverage {
+			logger.Subprocess("Coverage instrumentation has changed since the last build, everything will be rebuilt")
+		}
+
+		if coverage {
+			logger.Subprocess("WARNING: building with coverage instrumentation, this image is not meant for production")
+			ConfigureCoverage(&binaryLayer)
+		}
+
+		if flags := os.Getenv("BP_CARGO_RUSTFLAGS"); flags != "" && os.Getenv("RUSTFLAGS") == "" {
+			project, path, err := ProjectRustFlags(srcDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			for _, option := range RustFlagConflicts(project, strings.Fields(flags)) {
+				logger.Subprocess("WARNING: BP_CARGO_RUSTFLAGS and build.rustflags in %s both set -C %s, the value from BP_CARGO_RUSTFLAGS is used", path, option)
+			}
+		}
+
+		rustFlags, err := EffectiveRustFlags(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		if rustFlags != "" {
+			debug("Using RUSTFLAGS=%s", rustFlags)
+		}
+
+		if previous, _ := cargoLayer.Metadata["rustflags"].(string); cacheHit && previous != rustFlags {
+			logger.Subprocess("RUSTFLAGS have changed since the last build, everything will be rebuilt")
+		}
+
+		backtrace, err := DefaultBacktrace()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if backtrace != "" {
+			ConfigureBacktrace(&binaryLayer, backtrace)
+		}
+
+		if len(toolchain.Components) > 0 {
+			err = runner.EnsureComponents(srcDir, toolchain.Components)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		profile, err := ValidateProfile(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if profile != DefaultProfile {
+			logger.Subprocess("Building with the %s profile", profile)
+		}
+
+		if previous, ok := binaryLayer.Metadata["profile"].(string); ok && previous != profile {
+			logger.Subprocess("Build profile has changed from %s to %s since the last build, binaries will be rebuilt", previous, profile)
+		}
+
+		profileSettings, err := ProfileSettings()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		profileKey := ProfileSettingsKey(profileSettings)
+		if previous, _ := cargoLayer.Metadata["profile_settings"].(string); cacheHit && previous != profileKey {
+			logger.Subprocess("Profile settings have changed since the last build, affected crates will be rebuilt")
+		}
+
+		buildTarget, err := BuildTarget(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if previous, _ := cargoLayer.Metadata["build_target"].(string); cacheHit && previous != buildTarget {
+			logger.Subprocess("Build target has changed from %s to %s", describeTarget(previous), describeTarget(buildTarget))
+		}
+
+		if buildTarget != "" {
+			// cargo reports a missing standard library as a long list of unresolved crates, so it is called out first
+			installed, err := runner.TargetInstalled(srcDir, buildTarget)
+			switch {
+			case err != nil:
+				logger.Subprocess("WARNING: unable to check if the standard library for %s is installed: %s", buildTarget, err)
+			case !installed && IsMuslTarget(buildTarget):
+				return packit.BuildResult{}, fmt.Errorf("the standard library for %s is not installed, add `%s` to `targets` in rust-toolchain.toml so that the Rust buildpack installs it with rustup",
+					buildTarget, buildTarget)
+			case !installed:
+				logger.Subprocess("WARNING: the standard library for %s is not installed, add `%s` to `targets` in rust-toolchain.toml or use a Rust toolchain that includes it",
+					buildTarget, buildTarget)
+			}
+		}
+
+		if IsMuslTarget(buildTarget) {
+			logger.Subprocess("Building statically linked binaries for %s", buildTarget)
+		}
+
+		// the toolchain is set up at this point, so cargo fetch overlaps with the checks that follow, none of which run
+		// cargo. The fetch is always waited for, so that it doesn't outlive a build that fails in the meantime.
+		var prefetch chan error
+		if prefetchDeps {
+			logger.Subprocess("Fetching dependencies while the build is prepared")
+			done := make(chan error, 1)
+			prefetch = done
+			go func() {
+				done <- runner.Fetch(srcDir, cargoLayer, binaryLayer)
+			}()
+		}
+		defer func() {
+			if prefetch != nil {
+				<-prefetch
+			}
+		}()
+
+		err = CheckNativeToolchain(srcDir, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		requireReproducible, err := ParseBoolEnv("BP_CARGO_REQUIRE_REPRODUCIBLE_SOURCES")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		err = CheckReproducibleSources(srcDir, requireReproducible, logger)
+		if requireReproducible {
+			gates.Record("reproducible sources", err)
+		}
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		memberTimeout, err := MemberTimeout()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if prefetch != nil {
+			err = <-prefetch
+			prefetch = nil
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("unable to prefetch dependencies\n%w", err)
+			}
+		}
+
+		streamMembers, err := ParseBoolEnv("BP_CARGO_STREAM_MEMBERS")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		isPathSet, err := IsPathSet()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		features, err := LoadFeatureSelection()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if features.All && len(features.Features) > 0 {
+			logger.Subprocess("WARNING: BP_CARGO_ALL_FEATURES is set, so BP_CARGO_FEATURES [%s] has no effect", strings.Join(features.Features, ", "))
+		}
+
+		featuresKey := features.Key()
+		if previous, _ := cargoLayer.Metadata["features"].(string); cacheHit && previous != featuresKey {
+			logger.Subprocess("Features have changed since the last build, affected crates will be rebuilt")
+		}
+
+		var enabledGates []string
+		if requireReproducible {
+			enabledGates = append(enabledGates, "reproducible sources")
+		}
+		runTests, err := ParseBoolEnv("BP_CARGO_RUN_TESTS")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		if runTests {
+			enabledGates = append(enabledGates, "tests")
+		}
+		runClippy, err := ParseBoolEnv("BP_CARGO_RUN_CLIPPY")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		if runClippy {
+			enabledGates = append(enabledGates, "clippy")
+		}
+		if os.Getenv("BP_CARGO_VALIDATE_CMD") != "" {
+			enabledGates = append(enabledGates, "validation command")
+		}
+		if maxImageSize > 0 {
+			enabledGates = append(enabledGates, "image size")
+		}
+		LoadBuildConfiguration(profile, buildTarget, features, enabledGates).Log(logger)
+
+		requested := features.Requested()
+
+		clean, err := ParseBoolEnv("BP_CARGO_CLEAN")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		var examples []string
+		if len(RequestedExamples()) > 0 {
+			examples, err = runner.Examples(srcDir, cargoLayer, binaryLayer)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if len(examples) > 0 {
+				logger.Subprocess("Installing examples [%s] alongside the binaries", strings.Join(examples, ", "))
+			} else {
+				logger.Subprocess("WARNING: BP_CARGO_INSTALL_EXAMPLES is set, but the project has no examples")
+			}
+		}
+
+		if clean {
+			logger.Subprocess("BP_CARGO_CLEAN is set, removing the build output in the %s layer", TargetLayerName)
+			err = runner.Clean(srcDir, cargoLayer, binaryLayer)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		if runClippy {
+			clippyStart := clock.Now()
+			// clippy shares the rust-target layer with the build, so dependencies it checks are not checked again
+			err = runner.Clippy(srcDir, cargoLayer, binaryLayer)
+			gates.Record("clippy", err)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			timed("Ran clippy", clippyStart)
+		}
+
+		virtual, err := IsVirtualManifest(srcDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if virtual {
+			debug("Cargo.toml is a virtual manifest, members are installed by path")
+		}
+
+		installStart := clock.Now()
+		if streamer, ok := runner.(MemberStreamer); ok && streamMembers {
+			// members are resolved while they are installed, so fetching them is part of the install phase
+			members, err := installStreamedMembers(streamer, runner, memberTimeout, isPathSet, virtual, srcDir, cargoLayer, binaryLayer, logger, func() {
+				// keep what was compiled so far for the next build
+				if preserveErr := preserveCache(); preserveErr != nil {
+					logger.Subprocess("WARNING: unable to preserve the cache layers: %s", preserveErr)
+				}
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// members are only all known once they have been built, so typos in BP_CARGO_FEATURES are caught afterwards
+			err = checkMemberFeatures(srcDir, members, requested)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		} else {
+			members, err := runner.WorkspaceMembers(srcDir, cargoLayer, binaryLayer)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			timed("Fetched workspace members", installStart)
+			installStart = clock.Now()
+
+			err = checkMemberFeatures(srcDir, members, requested)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if len(members) == 0 {
+				logger.Subprocess("WARNING: no members detected, trying to install with no path. This may fail.")
+				// run `cargo install`
+				err = runner.Install(srcDir, cargoLayer, binaryLayer)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			} else if singleRootPackage(members, virtual) || isPathSet {
+				// run `cargo install`
+				err = runner.Install(srcDir, cargoLayer, binaryLayer)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			} else { // if len(members) > 1 and --path not set
+				// run `cargo install --path=` for each member in the workspace
+				for _, member := range members {
+					err = installMember(runner, memberTimeout, member.Path, srcDir, cargoLayer, binaryLayer)
+					if err != nil {
+						// keep what was compiled so far for the next build
+						if preserveErr := preserveCache(); preserveErr != nil {
+							logger.Subprocess("WARNING: unable to preserve the cache layers: %s", preserveErr)
+						}
+						return packit.BuildResult{}, err
+					}
+				}
+			}
+		}
+
+		timed("Installed binaries", installStart)
+
+		if runTests {
+			testStart := clock.Now()
+			// the test binaries stay in the rust-target layer, only the installed binaries are launched
+			err = runner.Test(srcDir, cargoLayer, binaryLayer)
+			gates.Record("tests", err)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			timed("Ran tests", testStart)
+		}
+
+		if useSccache {
+			// the statistics are only informational, so a server that can't be stopped doesn't fail the build
+			if err := StopSccache(logger); err != nil {
+				logger.Subprocess("WARNING: %s", err)
+			}
+		}
+
+		built, err := ListBinaries(filepath.Join(binaryLayer.Path, "bin"))
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		cargoLayer.Cache = true
+		if len(built) == 0 {
+			return packit.BuildResult{}, fmt.Errorf("no binaries were produced, check BP_CARGO_FEATURES and the binary targets of the packages that were built")
+		}
+
+		extraBins := ParseListEnv("BP_CARGO_EXTRA_LAUNCH_BINS")
+		if len(extraBins) > 0 {
+			searchDirs := append([]string{filepath.Join(cargoHome, "bin")}, filepath.SplitList(os.Getenv("PATH"))...)
+			err = AddExtraLaunchBinaries(filepath.Join(binaryLayer.Path, "bin"), extraBins, searchDirs, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		launchBins := ParseListEnv("BP_CARGO_LAUNCH_BIN")
+		for _, name := range extraBins {
+			if contains(launchBins, name) {
+				return packit.BuildResult{}, fmt.Errorf("binary %s is listed in both BP_CARGO_LAUNCH_BIN and BP_CARGO_EXTRA_LAUNCH_BINS", name)
+			}
+		}
+
+		if len(launchBins) > 0 {
+			err = SelectLaunchBinaries(filepath.Join(binaryLayer.Path, "bin"), append(append(launchBins, extraBins...), examples...), logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
-		binaryLayer, err := context.Layers.Get("rust-bin")
+		renames, err := ParseRenames(os.Getenv("BP_CARGO_RENAME_BIN"))
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		binaryLayer.Launch = true
+		if len(renames) > 0 {
+			err = RenameBinaries(filepath.Join(binaryLayer.Path, "bin"), renames, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
-		then := clock.Now()
+		shipped, err := ListBinaries(filepath.Join(binaryLayer.Path, "bin"))
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
 
-		preserver := mtimes.NewPreserver(logger)
-		err = preserver.Restore(cargoLayer.Path)
+		// examples are started by name, they are never the default process
+		processes, err := launchProcesses(srcDir, binaryLayer.Path, shipped, append(append([]string{}, extraBins...), examples...), renames, logger)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		members, err := runner.WorkspaceMembers(context.WorkingDir, cargoLayer, binaryLayer)
+		if validateCmd := os.Getenv("BP_CARGO_VALIDATE_CMD"); validateCmd != "" {
+			validateTimeout, err := ParseDurationEnv("BP_CARGO_VALIDATE_TIMEOUT", DefaultValidateTimeout)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			err = RunValidation(validateCmd, srcDir, filepath.Join(binaryLayer.Path, "bin"), validateTimeout, logger)
+			gates.Record("validation command", err)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		// stripped before compressing, strip can't read binaries packed by UPX
+		if stripMethod == StripWithTool && !cacheOnly {
+			err = StripBinaries(filepath.Join(binaryLayer.Path, "bin"), logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		compress, err := ParseBoolEnv("BP_CARGO_UPX")
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		isPathSet, err := IsPathSet()
+		// compressed binaries are never shipped in cache-only mode, so there is no point in compressing them
+		if compress && !cacheOnly {
+			err = CompressBinaries(compressor, filepath.Join(binaryLayer.Path, "bin"), logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		stripNonBin, err := ParseBoolEnvDefault("BP_CARGO_STRIP_NON_BIN", true)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		if len(members) == 0 {
-			logger.Subprocess("WARNING: no members detected, trying to install with no path. This may fail.")
-			// run `cargo install`
-			err = runner.Install(context.WorkingDir, cargoLayer, binaryLayer)
+		// files that the buildpack adds to the layer, like checksums.txt, are written after this
+		if stripNonBin {
+			err = StripNonBinaries(binaryLayer.Path, logger)
 			if err != nil {
 				return packit.BuildResult{}, err
 			}
-		} else if (len(members) == 1 && members[0].Path == "/workspace") || isPathSet {
-			// run `cargo install`
-			err = runner.Install(context.WorkingDir, cargoLayer, binaryLayer)
+		}
+
+		checksums, err := ChecksumBinaries(filepath.Join(binaryLayer.Path, "bin"))
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		emitChecksums, err := ParseBoolEnv("BP_CARGO_EMIT_CHECKSUMS")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if emitChecksums {
+			err = WriteChecksums(filepath.Join(binaryLayer.Path, "checksums.txt"), checksums)
 			if err != nil {
 				return packit.BuildResult{}, err
 			}
-		} else { // if len(members) > 1 and --path not set
-			// run `cargo install --path=` for each member in the workspace
-			for _, member := range members {
-				err = runner.InstallMember(member.Path, context.WorkingDir, cargoLayer, binaryLayer)
-				if err != nil {
-					return packit.BuildResult{}, err
-				}
+		}
+
+		writeVersionFile, err := ParseBoolEnv("BP_CARGO_VERSION_FILE")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if commit == "" && writeVersionFile {
+			logger.Detail("No git commit found in BP_CARGO_GIT_SHA or a .git directory, %s will not be written", VersionFile)
+		} else if commit == "" {
+			debug("No git commit found in BP_CARGO_GIT_SHA or a .git directory, it will not be recorded")
+		} else if writeVersionFile {
+			err = os.WriteFile(filepath.Join(binaryLayer.Path, VersionFile), []byte(commit+"\n"), 0644)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("unable to write %s\n%w", VersionFile, err)
+			}
+		}
+
+		if !cacheOnly {
+			err = LogBinarySummary(filepath.Join(binaryLayer.Path, "bin"), profile, buildTarget, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
 			}
 		}
 
-		err = preserver.Preserve(cargoLayer.Path)
+		err = preserveCache()
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		logger.Action("Completed in %s", time.Since(then).Round(time.Millisecond))
+		timed("Completed", then)
 		logger.Break()
 
+		builtAt, err := FormatTimestamp(clock.Now())
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		buildScriptInputs := buildScriptInputsChecksum(targetDir, srcDir, logger)
+
 		cargoLayer.Metadata = map[string]interface{}{
-			"built_at": clock.Now().Format(time.RFC3339Nano),
+			"built_at":                   builtAt,
+			"build_script_inputs_sha256": buildScriptInputs,
+			"cargo_lock_sha256":          lockChecksum,
+		}
+
+		if coverage {
+			cargoLayer.Metadata["coverage"] = true
+		}
+
+		if rustFlags != "" {
+			cargoLayer.Metadata["rustflags"] = rustFlags
+		}
+
+		if profileKey != "" {
+			cargoLayer.Metadata["profile_settings"] = profileKey
+		}
+
+		if buildTarget != "" {
+			cargoLayer.Metadata["build_target"] = buildTarget
+		}
+
+		if featuresKey != "" {
+			cargoLayer.Metadata["features"] = featuresKey
+		}
+
+		if toolchain.Channel != "" {
+			cargoLayer.Metadata["toolchain_channel"] = toolchain.Channel
+		}
+
+		if cacheOnly {
+			// the binaries proved that the build works, only the cache is kept
+			_, err = binaryLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			return packit.BuildResult{
+				Layers: withoutLayers(append([]packit.Layer{cargoLayer, registryLayer, targetLayer}, extraCacheLayers...), unmanagedLayers),
+			}, nil
 		}
 
 		binaryLayer.Metadata = map[string]interface{}{
-			"built_at": clock.Now().Format(time.RFC3339Nano),
+			"built_at":      builtAt,
+			"binary_sha256": checksums,
+			"profile":       profile,
+			"source_sha256": sourceChecksum,
+			"config_sha256": configChecksum,
+		}
+
+		if rustVersion != "" {
+			binaryLayer.Metadata["rust_version"] = rustVersion
+		}
+
+		if buildTarget != "" {
+			binaryLayer.Metadata["build_target"] = buildTarget
+		}
+
+		if len(examples) > 0 {
+			binaryLayer.Metadata["examples"] = examples
+		}
+
+		if commit != "" {
+			binaryLayer.Metadata["git_sha"] = commit
+		}
+
+		layers, err := collectLayers(processes, buildTarget, false)
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
 		return packit.BuildResult{
-			Layers: []packit.Layer{
-				cargoLayer,
-				binaryLayer,
+			Layers: layers,
+			Launch: packit.LaunchMetadata{
+				Processes: processes,
 			},
 		}, nil
 	}
 }
 
+// withoutLayers drops the layers named in names from layers
+func withoutLayers(layers []packit.Layer, names []string) []packit.Layer {
+	if len(names) == 0 {
+		return layers
+	}
+
+	var kept []packit.Layer
+	for _, layer := range layers {
+		if !contains(names, layer.Name) {
+			kept = append(kept, layer)
+		}
+	}
+	return kept
+}
+
 func IsPathSet() (bool, error) {
 	envArgs, err := FilterInstallArgs(os.Getenv("BP_CARGO_INSTALL_ARGS"))
 	if err != nil {
@@ -123,3 +1324,309 @@ func IsPathSet() (bool, error) {
 
 	return false, nil
 }
+
+// MemberTimeout returns the deadline for building each workspace member from BP_CARGO_MEMBER_TIMEOUT, zero means
+// no deadline
+func MemberTimeout() (time.Duration, error) {
+	return ParseDurationEnv("BP_CARGO_MEMBER_TIMEOUT", 0)
+}
+
+// ParseDurationEnv reads a non-negative duration from the environment, unset or empty is def
+func ParseDurationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("invalid value for %s: %s is negative", name, value)
+	}
+
+	return d, nil
+}
+
+// checkMemberFeatures fails if a feature in requested is not defined by any of members, or the project in srcDir when
+// there are no members
+func checkMemberFeatures(srcDir string, members []url.URL, requested []string) error {
+	if len(requested) == 0 {
+		return nil
+	}
+
+	memberDirs := []string{srcDir}
+	if len(members) > 0 {
+		memberDirs = nil
+		for _, member := range members {
+			memberDirs = append(memberDirs, member.Path)
+		}
+	}
+
+	return CheckFeatures(memberDirs, requested)
+}
+
+// singleRootPackage is true when the only member is the package at the root of the project, which a plain
+// `cargo install` builds. A virtual manifest has no package at its root, so its members are always installed by path.
+func singleRootPackage(members []url.URL, virtual bool) bool {
+	return !virtual && len(members) == 1 && members[0].Path == "/workspace"
+}
+
+// installStreamedMembers builds each workspace member as soon as streamer resolves it and returns the members that
+// were resolved. A lone `/workspace` member, or any members when `--path` is set, are installed with a single
+// `cargo install` like they are without streaming. onBuildError is called when building a member fails.
+func installStreamedMembers(streamer MemberStreamer, runner Runner, timeout time.Duration, isPathSet bool, virtual bool, srcDir string,
+	workLayer packit.Layer, destLayer packit.Layer, logger scribe.Emitter, onBuildError func()) ([]url.URL, error) {
+	stream := make(chan url.URL)
+	done := make(chan error, 1)
+	go func() {
+		done <- streamer.StreamWorkspaceMembers(srcDir, workLayer, destLayer, stream)
+	}()
+
+	var members []url.URL
+	var buildErr error
+	for member := range stream {
+		members = append(members, member)
+
+		// after a failure, the rest of the stream is drained so that the streamer can finish
+		if buildErr != nil || isPathSet || len(members) < 2 {
+			continue
+		}
+
+		// whether the first member is the only one is not known until the second arrives
+		if len(members) == 2 {
+			buildErr = installMember(runner, timeout, members[0].Path, srcDir, workLayer, destLayer)
+		}
+
+		if buildErr == nil {
+			buildErr = installMember(runner, timeout, member.Path, srcDir, workLayer, destLayer)
+		}
+	}
+
+	err := <-done
+	if buildErr != nil {
+		onBuildError()
+		return nil, buildErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) > 1 && !isPathSet {
+		return members, nil
+	}
+
+	if len(members) == 1 && !singleRootPackage(members, virtual) && !isPathSet {
+		err = installMember(runner, timeout, members[0].Path, srcDir, workLayer, destLayer)
+		if err != nil {
+			onBuildError()
+			return nil, err
+		}
+		return members, nil
+	}
+
+	if len(members) == 0 {
+		logger.Subprocess("WARNING: no members detected, trying to install with no path. This may fail.")
+	}
+
+	// run `cargo install`
+	err = runner.Install(srcDir, workLayer, destLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func installMember(runner Runner, timeout time.Duration, memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := runner.InstallMember(ctx, memberPath, srcDir, workLayer, destLayer)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("member %s did not finish building within BP_CARGO_MEMBER_TIMEOUT (%s)\n%w", memberPath, timeout, err)
+	}
+
+	return err
+}
+
+// withCache marks layer as a cache layer. Without caching, whatever a previous build left in the layer is removed, and
+// the layer is not cached afterwards, so the next build starts from scratch as well rather than from a partial cache.
+func withCache(layer packit.Layer, cache bool) (packit.Layer, error) {
+	if cache {
+		layer.Cache = true
+		return layer, nil
+	}
+
+	layer, err := layer.Reset()
+	if err != nil {
+		return packit.Layer{}, err
+	}
+	return layer, nil
+}
+
+// canReuseBinaries is true when the binaries in the previous image were built from the same sources, configuration,
+// toolchain and git commit, so that they can be shipped again without running cargo. The layer is reused as it is,
+// so a different commit has to rebuild to record it.
+func canReuseBinaries(binaryLayer packit.Layer, sourceChecksum string, configChecksum string, rustVersion string, commit string) bool {
+	previousSource, _ := binaryLayer.Metadata["source_sha256"].(string)
+	previousConfig, _ := binaryLayer.Metadata["config_sha256"].(string)
+	previousVersion, _ := binaryLayer.Metadata["rust_version"].(string)
+	previousCommit, _ := binaryLayer.Metadata["git_sha"].(string)
+
+	return previousSource == sourceChecksum &&
+		previousConfig == configChecksum &&
+		rustVersion != "" && previousVersion == rustVersion &&
+		previousCommit == commit &&
+		len(cachedBinaries(binaryLayer)) > 0
+}
+
+// cachedBinaries lists the binaries that the previous build recorded in the metadata of binaryLayer
+func cachedBinaries(binaryLayer packit.Layer) []string {
+	var names []string
+	switch checksums := binaryLayer.Metadata["binary_sha256"].(type) {
+	case map[string]interface{}:
+		for name := range checksums {
+			names = append(names, name)
+		}
+	case map[string]string:
+		for name := range checksums {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// launchProcesses registers a process for each shipped binary, with the primary binary as the default process.
+// extraBins are the names from BP_CARGO_EXTRA_LAUNCH_BINS, before they are renamed.
+func launchProcesses(srcDir string, binaryLayerPath string, shipped []string, extraBins []string, renames map[string]string, logger scribe.Emitter) ([]packit.Process, error) {
+	var extraNames []string
+	for _, name := range extraBins {
+		if to, ok := renames[name]; ok {
+			name = to
+		}
+		extraNames = append(extraNames, name)
+	}
+
+	// extra binaries get a process of their own, the default process runs one of the binaries cargo built
+	var primaries []string
+	for _, name := range shipped {
+		if !contains(extraNames, name) {
+			primaries = append(primaries, name)
+		}
+	}
+
+	defaultBin, ambiguous, err := DefaultBinary(srcDir, primaries, renames)
+	if err != nil {
+		return nil, err
+	}
+
+	if ambiguous {
+		logger.Subprocess("WARNING: binaries [%s] were built, using %s for the default process, set BP_CARGO_LAUNCH_BIN to ship only one of them",
+			strings.Join(primaries, ", "), defaultBin)
+	}
+
+	var processes []packit.Process
+	if defaultBin != "" {
+		logger.Subprocess("Assigning %s as the default process", defaultBin)
+		processes = append(processes, packit.Process{
+			Type:    DefaultProcessType,
+			Command: filepath.Join(binaryLayerPath, "bin", defaultBin),
+			Direct:  true,
+		})
+	}
+
+	// every binary can also be started by name, including binaries that build scripts generated
+	for _, name := range primaries {
+		if name == DefaultProcessType {
+			continue
+		}
+
+		processes = append(processes, packit.Process{
+			Type:    name,
+			Command: filepath.Join(binaryLayerPath, "bin", name),
+			Direct:  true,
+		})
+	}
+
+	for _, name := range extraNames {
+		processes = append(processes, packit.Process{
+			Type:    name,
+			Command: filepath.Join(binaryLayerPath, "bin", name),
+			Direct:  true,
+		})
+	}
+
+	return processes, nil
+}
+
+// buildScriptInputsChecksum is the checksum of the inputs that the build scripts of the previous build declared.
+// It only decides what is logged, so a checksum that can't be computed is a warning and is left empty.
+func buildScriptInputsChecksum(targetDir string, srcDir string, logger scribe.Emitter) string {
+	checksum, err := func() (string, error) {
+		packageDirs, err := PackageDirs(srcDir)
+		if err != nil {
+			return "", err
+		}
+
+		inputs, err := ParseBuildScriptInputs(targetDir, packageDirs)
+		if err != nil {
+			return "", err
+		}
+
+		return inputs.Checksum()
+	}()
+	if err != nil {
+		logger.Subprocess("WARNING: unable to checksum the build script inputs: %s", err)
+		return ""
+	}
+	return checksum
+}
+
+// FormatTimestamp formats t in UTC, using the format set by BP_CARGO_TIMESTAMP_FORMAT or RFC3339Nano by default
+func FormatTimestamp(t time.Time) (string, error) {
+	t = t.UTC()
+
+	format := os.Getenv("BP_CARGO_TIMESTAMP_FORMAT")
+	switch strings.ToLower(format) {
+	case "", "rfc3339nano":
+		return t.Format(time.RFC3339Nano), nil
+	case "rfc3339":
+		return t.Format(time.RFC3339), nil
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	default:
+		return "", fmt.Errorf("invalid BP_CARGO_TIMESTAMP_FORMAT %q, must be one of `RFC3339Nano`, `RFC3339` or `unix`", format)
+	}
+}
+
+// ParseBoolEnv reads a boolean flag from the environment, unset or empty is false
+func ParseBoolEnv(name string) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+
+	return b, nil
+}
+
+// ParseBoolEnvDefault is ParseBoolEnv for variables that default to def when they are unset or empty
+func ParseBoolEnvDefault(name string, def bool) (bool, error) {
+	if value, ok := os.LookupEnv(name); !ok || value == "" {
+		return def, nil
+	}
+	return ParseBoolEnv(name)
+}
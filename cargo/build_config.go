@@ -0,0 +1,84 @@
+package cargo
+
+import (
+	"os"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// BuildConfiguration is the resolved configuration of a build, it is logged before cargo runs so that the effect of
+// the BP_CARGO_* variables is visible without reading the whole build output
+type BuildConfiguration struct {
+	Profile  string
+	Target   string
+	Features FeatureSelection
+	Members  []string
+	Package  string
+	Binaries []string
+	Gates    []string
+}
+
+// LoadBuildConfiguration collects the members and binaries selected in the environment, the other values are
+// resolved by the build and passed in
+func LoadBuildConfiguration(profile string, target string, features FeatureSelection, gates []string) BuildConfiguration {
+	var members []string
+	if filter, ok := os.LookupEnv("BP_CARGO_WORKSPACE_MEMBERS"); ok {
+		for _, member := range strings.Split(filter, ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				members = append(members, member)
+			}
+		}
+	}
+
+	return BuildConfiguration{
+		Profile:  profile,
+		Target:   target,
+		Features: features,
+		Members:  members,
+		Package:  strings.TrimSpace(os.Getenv("BP_CARGO_PACKAGE")),
+		Binaries: ParseListEnv("BP_CARGO_LAUNCH_BIN"),
+		Gates:    gates,
+	}
+}
+
+// Log prints the configuration as a single block
+func (b BuildConfiguration) Log(logger scribe.Emitter) {
+	logger.Process("Build configuration")
+	logger.Subprocess("Profile: %s", b.Profile)
+	logger.Subprocess("Target: %s", describeTarget(b.Target))
+	logger.Subprocess("Features: %s", describeFeatures(b.Features))
+
+	switch {
+	case b.Package != "":
+		logger.Subprocess("Members: %s", b.Package)
+	case len(b.Members) > 0:
+		logger.Subprocess("Members: %s", strings.Join(b.Members, ", "))
+	default:
+		logger.Subprocess("Members: all")
+	}
+
+	logger.Subprocess("Binaries: %s", describeList(b.Binaries, "all"))
+	logger.Subprocess("Gates: %s", describeList(b.Gates, "none"))
+	logger.Break()
+}
+
+func describeFeatures(features FeatureSelection) string {
+	if features.All {
+		return "all"
+	}
+
+	var parts []string
+	if !features.NoDefault {
+		parts = append(parts, "default")
+	}
+	parts = append(parts, features.Features...)
+	return describeList(parts, "none")
+}
+
+func describeList(items []string, empty string) string {
+	if len(items) == 0 {
+		return empty
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,92 @@
+package cargo_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBuildConfiguration(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it("logs the defaults", func() {
+		cargo.LoadBuildConfiguration("release", "", cargo.FeatureSelection{}, nil).Log(logger)
+
+		Expect(logBuf.String()).To(Equal(`  Build configuration
+    Profile: release
+    Target: the host
+    Features: default
+    Members: all
+    Binaries: all
+    Gates: none
+
+`))
+	})
+
+	context("when the build is configured", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "api, worker")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_LAUNCH_BIN", "api")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_LAUNCH_BIN")).To(Succeed())
+		})
+
+		it("logs the resolved values", func() {
+			features := cargo.FeatureSelection{Features: []string{"tls", "metrics"}, NoDefault: true}
+			gates := []string{"reproducible sources", "image size"}
+			cargo.LoadBuildConfiguration("dist", "aarch64-unknown-linux-gnu", features, gates).Log(logger)
+
+			Expect(logBuf.String()).To(Equal(`  Build configuration
+    Profile: dist
+    Target: aarch64-unknown-linux-gnu
+    Features: tls, metrics
+    Members: api, worker
+    Binaries: api
+    Gates: reproducible sources, image size
+
+`))
+		})
+	})
+
+	context("when a single package is selected", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_PACKAGE", "api")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_PACKAGE")).To(Succeed())
+		})
+
+		it("logs the package as the member", func() {
+			cargo.LoadBuildConfiguration("release", "", cargo.FeatureSelection{All: true}, nil).Log(logger)
+
+			Expect(logBuf.String()).To(ContainSubstring("Features: all\n"))
+			Expect(logBuf.String()).To(ContainSubstring("Members: api\n"))
+		})
+	})
+
+	it("logs no features when the defaults are disabled", func() {
+		cargo.LoadBuildConfiguration("release", "", cargo.FeatureSelection{NoDefault: true}, nil).Log(logger)
+
+		Expect(logBuf.String()).To(ContainSubstring("Features: none\n"))
+	})
+}
@@ -0,0 +1,201 @@
+package cargo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/fs"
+)
+
+// BuildScriptInputs are the files and environment variables that build scripts have declared as inputs with
+// `cargo:rerun-if-changed` and `cargo:rerun-if-env-changed`
+type BuildScriptInputs struct {
+	Files   []string
+	EnvVars []string
+}
+
+// ParseBuildScriptInputs reads the build script output left in targetDir by a previous build. packageDirs maps the
+// names of the packages of the project to their directories. Only files declared by the build scripts of those
+// packages are inputs, resolved against the directory of the package like cargo does, since the sources of other
+// dependencies only change with Cargo.lock. Environment variables declared by any build script are inputs.
+func ParseBuildScriptInputs(targetDir string, packageDirs map[string]string) (BuildScriptInputs, error) {
+	var outputs []string
+	for _, pattern := range []string{"*/build/*/output", "*/*/build/*/output"} {
+		matches, err := filepath.Glob(filepath.Join(targetDir, pattern))
+		if err != nil {
+			return BuildScriptInputs{}, fmt.Errorf("unable to find build script output\n%w", err)
+		}
+		outputs = append(outputs, matches...)
+	}
+
+	files := make(map[string]bool)
+	envVars := make(map[string]bool)
+	for _, output := range outputs {
+		packageDir, local := packageDirs[buildPackageName(filepath.Base(filepath.Dir(output)))]
+
+		err := parseBuildScriptOutput(output, func(file string) {
+			if !local {
+				return
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(packageDir, file)
+			}
+			files[file] = true
+		}, envVars)
+		if err != nil {
+			return BuildScriptInputs{}, err
+		}
+	}
+
+	return BuildScriptInputs{Files: sortedKeys(files), EnvVars: sortedKeys(envVars)}, nil
+}
+
+// buildHashSuffix matches the hash that cargo appends to the name of a package's build directory
+var buildHashSuffix = regexp.MustCompile(`-[0-9a-f]{16}$`)
+
+// buildPackageName returns the name of the package that a build directory like `app-0123456789abcdef` belongs to
+func buildPackageName(dir string) string {
+	return buildHashSuffix.ReplaceAllString(dir, "")
+}
+
+// PackageDirs maps the name of the package in srcDir, and of every member of its workspace, to its directory
+func PackageDirs(srcDir string) (map[string]string, error) {
+	manifest, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := map[string]string{}
+	if manifest.Package != nil {
+		dirs[manifest.Package.Name] = srcDir
+	}
+
+	if manifest.Workspace == nil {
+		return dirs, nil
+	}
+
+	members, err := manifest.Workspace.MemberDirs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range members {
+		member, err := ParseManifest(filepath.Join(dir, "Cargo.toml"))
+		if err != nil {
+			return nil, err
+		}
+
+		if member.Package != nil {
+			dirs[member.Package.Name] = dir
+		}
+	}
+
+	return dirs, nil
+}
+
+func parseBuildScriptOutput(path string, addFile func(string), envVars map[string]bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	// build scripts may print lines of any length, so the output is not read with a bufio.Scanner
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("unable to read %s\n%w", path, err)
+		}
+
+		directive := strings.TrimSpace(line)
+		directive = strings.TrimPrefix(strings.TrimPrefix(directive, "cargo::"), "cargo:")
+
+		if value := strings.TrimPrefix(directive, "rerun-if-changed="); value != directive {
+			addFile(value)
+		} else if value := strings.TrimPrefix(directive, "rerun-if-env-changed="); value != directive {
+			envVars[value] = true
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// Checksum computes a checksum over the contents of the input files and the values of the input environment
+// variables. Missing files are included as missing, so that creating them changes the checksum.
+func (b BuildScriptInputs) Checksum() (string, error) {
+	hash := sha256.New()
+
+	for _, file := range b.Files {
+		sum, err := checksumPath(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "file:%s=%s\n", file, sum)
+	}
+
+	for _, envVar := range b.EnvVars {
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			value = "<unset>"
+		}
+		fmt.Fprintf(hash, "env:%s=%s\n", envVar, value)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func checksumPath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "<missing>", nil
+		}
+		return "", fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	if info.IsDir() {
+		sum, err := fs.NewChecksumCalculator().Sum(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to checksum %s\n%w", path, err)
+		}
+		return sum, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
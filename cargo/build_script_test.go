@@ -0,0 +1,114 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/fs"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBuildScript(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		targetDir  string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fs.Copy("testdata/build_script", filepath.Join(workingDir, "app"))).To(Succeed())
+		workingDir = filepath.Join(workingDir, "app")
+		targetDir = filepath.Join(workingDir, "target")
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(filepath.Dir(workingDir))).To(Succeed())
+		Expect(os.Unsetenv("APP_CONFIG_PROFILE")).To(Succeed())
+	})
+
+	it("parses the declared inputs from build script output", func() {
+		inputs, err := cargo.ParseBuildScriptInputs(targetDir, map[string]string{"app": workingDir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inputs).To(Equal(cargo.BuildScriptInputs{
+			Files:   []string{filepath.Join(workingDir, "build.rs"), filepath.Join(workingDir, "data", "config.txt")},
+			EnvVars: []string{"APP_CONFIG_PROFILE", "OPENSSL_DIR"},
+		}))
+	})
+
+	it("resolves files against the directory of the package that declared them", func() {
+		memberDir := filepath.Join(workingDir, "crates", "api")
+		inputs, err := cargo.ParseBuildScriptInputs(targetDir, map[string]string{"app": memberDir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inputs.Files).To(Equal([]string{filepath.Join(memberDir, "build.rs"), filepath.Join(memberDir, "data", "config.txt")}))
+	})
+
+	it("reads build script output with long lines", func() {
+		output := filepath.Join(targetDir, "release", "build", "app-0123456789abcdef", "output")
+		Expect(ioutil.WriteFile(output, []byte("cargo:rustc-env=BLOB="+strings.Repeat("x", 128*1024)+"\ncargo:rerun-if-env-changed=LONG_LINE"), 0644)).To(Succeed())
+
+		inputs, err := cargo.ParseBuildScriptInputs(targetDir, map[string]string{"app": workingDir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inputs.EnvVars).To(ContainElement("LONG_LINE"))
+	})
+
+	it("has no inputs when there is no previous build", func() {
+		inputs, err := cargo.ParseBuildScriptInputs(filepath.Join(workingDir, "missing"), map[string]string{"app": workingDir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inputs).To(Equal(cargo.BuildScriptInputs{}))
+	})
+
+	it("maps the packages of a workspace to their directories", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"crates/*\"]\n"), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(workingDir, "crates", "api"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "crates", "api", "Cargo.toml"), []byte("[package]\nname = \"api\"\n"), 0644)).To(Succeed())
+
+		Expect(cargo.PackageDirs(workingDir)).To(Equal(map[string]string{"api": filepath.Join(workingDir, "crates", "api")}))
+	})
+
+	context("computing the checksum", func() {
+		var (
+			inputs   cargo.BuildScriptInputs
+			original string
+		)
+
+		it.Before(func() {
+			var err error
+			inputs, err = cargo.ParseBuildScriptInputs(targetDir, map[string]string{"app": workingDir})
+			Expect(err).ToNot(HaveOccurred())
+
+			original, err = inputs.Checksum()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		it("changes when a data file changes", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "data", "config.txt"), []byte("greeting=bye"), 0644)).To(Succeed())
+			Expect(inputs.Checksum()).ToNot(Equal(original))
+		})
+
+		it("changes when a data file is removed", func() {
+			Expect(os.Remove(filepath.Join(workingDir, "data", "config.txt"))).To(Succeed())
+			Expect(inputs.Checksum()).ToNot(Equal(original))
+		})
+
+		it("changes when an environment variable changes", func() {
+			Expect(os.Setenv("APP_CONFIG_PROFILE", "prod")).To(Succeed())
+			Expect(inputs.Checksum()).ToNot(Equal(original))
+		})
+
+		it("does not change for unrelated source changes", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}"), 0644)).To(Succeed())
+			Expect(inputs.Checksum()).To(Equal(original))
+		})
+	})
+}
@@ -2,11 +2,17 @@ package cargo_test
 
 import (
 	"bytes"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,6 +27,15 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// emptySHA256 is the checksum of no input
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// unlockedConfigSHA256 is the checksum of the configuration when only BP_CARGO_LOCKED=false is set
+const unlockedConfigSHA256 = "340445649609f18d9f0012dfba3d0d6ddc3e423db0dae5263d066b5c12c6cb61"
+
+// appSHA256 is the checksum of the binary written by installApp
+const appSHA256 = "a172cedcae47474b615c54d510a5d84a8dea3032e958587430b413538be3f333"
+
 func testBuild(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
@@ -31,11 +46,23 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		timestamp  string
 		buffer     *bytes.Buffer
 		mockRunner mocks.Runner
+		mockUPX    mocks.Compressor
 		clock      chronos.Clock
 
+		rustVersion    string
+		rustVersionErr error
+		toolchainErr   error
+
 		build packit.BuildFunc
 	)
 
+	// installApp stands in for cargo, writing an `app` binary to the destination layer, the last argument
+	installApp := func(args mock.Arguments) {
+		binDir := filepath.Join(args.Get(len(args)-1).(packit.Layer).Path, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("app"), 0755)).To(Succeed())
+	}
+
 	it.Before(func() {
 		var err error
 		workingDir, err = ioutil.TempDir("", "working-dir")
@@ -49,18 +76,38 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		now := time.Now()
 		clock = chronos.NewClock(func() time.Time { return now })
-		timestamp = now.Format(time.RFC3339Nano)
+		timestamp = now.UTC().Format(time.RFC3339Nano)
 		buffer = bytes.NewBuffer(nil)
 
 		mockRunner = mocks.Runner{}
+		mockUPX = mocks.Compressor{}
+
+		// most of the projects here have no Cargo.lock, which locked builds require
+		Expect(os.Setenv("BP_CARGO_LOCKED", "false")).To(Succeed())
+
+		rustVersion = "cargo 1.56.0 (4ed5d137b 2021-10-04), rustc 1.56.0 (09c42c458 2021-10-18)"
+		rustVersionErr = nil
+		mockRunner.On(
+			"Version",
+			workingDir,
+			mock.AnythingOfType("packit.Layer"),
+			mock.AnythingOfType("packit.Layer")).Return(
+			func(string, packit.Layer, packit.Layer) string { return rustVersion },
+			func(string, packit.Layer, packit.Layer) error { return rustVersionErr }).Maybe()
+
+		toolchainErr = nil
+		mockRunner.On("CheckToolchain").Return(func() error { return toolchainErr }).Maybe()
 
 		logger := scribe.NewEmitter(buffer)
 
-		build = cargo.Build(&mockRunner, clock, logger)
+		build = cargo.Build(&mockRunner, &mockUPX, clock, logger)
 	})
 
 	it.After(func() {
 		mockRunner.AssertExpectations(t)
+		mockUPX.AssertExpectations(t)
+
+		Expect(os.Unsetenv("BP_CARGO_LOCKED")).To(Succeed())
 
 		Expect(os.RemoveAll(workingDir)).To(Succeed())
 		Expect(os.RemoveAll(layersDir)).To(Succeed())
@@ -81,7 +128,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 				"Install",
 				workingDir,
 				mock.AnythingOfType("packit.Layer"),
-				mock.AnythingOfType("packit.Layer")).Return(nil)
+				mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
 
 			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
 			result, err := build(packit.BuildContext{
@@ -107,7 +154,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":                   timestamp,
+							"build_script_inputs_sha256": emptySHA256,
+							"cargo_lock_sha256":          "",
 						},
 					},
 					{
@@ -121,13 +170,82 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":      timestamp,
+							"binary_sha256": map[string]string{"app": appSHA256},
+							"profile":       "release",
+							"rust_version":  rustVersion,
+							"source_sha256": emptySHA256,
+							"config_sha256": unlockedConfigSHA256,
+						},
+					},
+					{
+						Name:             "rust-registry",
+						Path:             filepath.Join(layersDir, "rust-registry"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"cargo_lock_sha256": "",
+						},
+					},
+					{
+						Name:             "rust-target",
+						Path:             filepath.Join(layersDir, "rust-target"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"rust_version": rustVersion,
 						},
 					},
 				},
+				Launch: packit.LaunchMetadata{
+					Processes: []packit.Process{
+						{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+						{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					},
+				},
 			}))
 		})
 
+		it("passes member paths with spaces to the runner decoded", func() {
+			member1, err := url.Parse("file:///work%20space/member%20one")
+			Expect(err).ToNot(HaveOccurred())
+			member2, err := url.Parse("file:///work space/member two")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockRunner.On(
+				"WorkspaceMembers",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member1, *member2}, nil)
+
+			for _, path := range []string{"/work space/member one", "/work space/member two"} {
+				mockRunner.On(
+					"InstallMember",
+					mock.Anything,
+					path,
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+			}
+
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			_, err = build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
 		it("builds a multi-member project", func() {
 			member1, err := url.Parse("file:///workspace1")
 			Expect(err).ToNot(HaveOccurred())
@@ -142,17 +260,19 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 			mockRunner.On(
 				"InstallMember",
+				mock.Anything,
 				member1.Path,
 				workingDir,
 				mock.AnythingOfType("packit.Layer"),
-				mock.AnythingOfType("packit.Layer")).Return(nil)
+				mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
 
 			mockRunner.On(
 				"InstallMember",
+				mock.Anything,
 				member2.Path,
 				workingDir,
 				mock.AnythingOfType("packit.Layer"),
-				mock.AnythingOfType("packit.Layer")).Return(nil)
+				mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
 
 			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
 			result, err := build(packit.BuildContext{
@@ -178,7 +298,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":                   timestamp,
+							"build_script_inputs_sha256": emptySHA256,
+							"cargo_lock_sha256":          "",
 						},
 					},
 					{
@@ -192,9 +314,48 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":      timestamp,
+							"binary_sha256": map[string]string{"app": appSHA256},
+							"profile":       "release",
+							"rust_version":  rustVersion,
+							"source_sha256": emptySHA256,
+							"config_sha256": unlockedConfigSHA256,
+						},
+					},
+					{
+						Name:             "rust-registry",
+						Path:             filepath.Join(layersDir, "rust-registry"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"cargo_lock_sha256": "",
 						},
 					},
+					{
+						Name:             "rust-target",
+						Path:             filepath.Join(layersDir, "rust-target"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"rust_version": rustVersion,
+						},
+					},
+				},
+				Launch: packit.LaunchMetadata{
+					Processes: []packit.Process{
+						{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+						{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					},
 				},
 			}))
 		})
@@ -212,10 +373,11 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 			mockRunner.On(
 				"InstallMember",
+				mock.Anything,
 				member1.Path,
 				workingDir,
 				mock.AnythingOfType("packit.Layer"),
-				mock.AnythingOfType("packit.Layer")).Return(nil)
+				mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
 
 			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
 			result, err := build(packit.BuildContext{
@@ -241,7 +403,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":                   timestamp,
+							"build_script_inputs_sha256": emptySHA256,
+							"cargo_lock_sha256":          "",
 						},
 					},
 					{
@@ -255,37 +419,3422 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Metadata: map[string]interface{}{
-							"built_at": timestamp,
+							"built_at":      timestamp,
+							"binary_sha256": map[string]string{"app": appSHA256},
+							"profile":       "release",
+							"rust_version":  rustVersion,
+							"source_sha256": emptySHA256,
+							"config_sha256": unlockedConfigSHA256,
+						},
+					},
+					{
+						Name:             "rust-registry",
+						Path:             filepath.Join(layersDir, "rust-registry"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"cargo_lock_sha256": "",
+						},
+					},
+					{
+						Name:             "rust-target",
+						Path:             filepath.Join(layersDir, "rust-target"),
+						Build:            false,
+						Launch:           false,
+						Cache:            true,
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Metadata: map[string]interface{}{
+							"rust_version": rustVersion,
 						},
 					},
 				},
+				Launch: packit.LaunchMetadata{
+					Processes: []packit.Process{
+						{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+						{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					},
+				},
 			}))
 		})
-	})
 
-	context("failure cases", func() {
-		context("when the rust layer cannot be retrieved", func() {
+		it("registers a process for every binary in the launch layer", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"),
+				[]byte("[package]\nname = \"worker\"\n\n[[bin]]\nname = \"api\"\n\n[[bin]]\nname = \"worker\"\n\n[[bin]]\nname = \"cli\"\n"), 0644)).To(Succeed())
+
+			member, err := url.Parse("file:///workspace")
+			Expect(err).ToNot(HaveOccurred())
+			mockRunner.On(
+				"WorkspaceMembers",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+			mockRunner.On(
+				"Install",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+				binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+				Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+				// `generated` stands in for a binary that a build script wrote, it is not in Cargo.toml
+				for _, name := range []string{"api", "cli", "generated", "worker"} {
+					Expect(ioutil.WriteFile(filepath.Join(binDir, name), []byte(name), 0755)).To(Succeed())
+				}
+			}).Return(nil)
+
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			binDir := filepath.Join(layersDir, "rust-bin", "bin")
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{Type: "web", Command: filepath.Join(binDir, "worker"), Direct: true},
+				{Type: "api", Command: filepath.Join(binDir, "api"), Direct: true},
+				{Type: "cli", Command: filepath.Join(binDir, "cli"), Direct: true},
+				{Type: "generated", Command: filepath.Join(binDir, "generated"), Direct: true},
+				{Type: "worker", Command: filepath.Join(binDir, "worker"), Direct: true},
+			}))
+			Expect(buffer.String()).NotTo(ContainSubstring("WARNING: binaries"))
+		})
+
+		it("logs the toolchain version and continues when it cannot be read", func() {
+			member, err := url.Parse("file:///workspace")
+			Expect(err).ToNot(HaveOccurred())
+			mockRunner.On(
+				"WorkspaceMembers",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+			mockRunner.On(
+				"Install",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("Using cargo 1.56.0 (4ed5d137b 2021-10-04), rustc 1.56.0 (09c42c458 2021-10-18)"))
+			Expect(result.Layers[1].Metadata["rust_version"]).To(Equal(rustVersion))
+
+			rustVersion = ""
+			rustVersionErr = errors.New("rustc --version failed: exit status 1")
+			buffer.Reset()
+
+			result, err = build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("WARNING: unable to determine the Rust toolchain version: rustc --version failed: exit status 1"))
+			Expect(result.Layers[1].Metadata).NotTo(HaveKey("rust_version"))
+		})
+
+		it("warns when the default process is ambiguous", func() {
+			member1, err := url.Parse("file:///workspace1")
+			Expect(err).ToNot(HaveOccurred())
+			member2, err := url.Parse("file:///workspace2")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockRunner.On(
+				"WorkspaceMembers",
+				workingDir,
+				mock.AnythingOfType("packit.Layer"),
+				mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member1, *member2}, nil)
+
+			for _, member := range []*url.URL{member1, member2} {
+				name := filepath.Base(member.Path)
+				mockRunner.On(
+					"InstallMember",
+					mock.Anything,
+					member.Path,
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(4).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, name), []byte(name), 0755)).To(Succeed())
+				}).Return(nil)
+			}
+
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "workspace1"), Direct: true},
+				{Type: "workspace1", Command: filepath.Join(layersDir, "rust-bin", "bin", "workspace1"), Direct: true},
+				{Type: "workspace2", Command: filepath.Join(layersDir, "rust-bin", "bin", "workspace2"), Direct: true},
+			}))
+			Expect(buffer.String()).To(ContainSubstring("WARNING: binaries [workspace1, workspace2] were built, using workspace1 for the default process"))
+		})
+
+		context("when BP_CARGO_UPX is set", func() {
 			it.Before(func() {
-				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"), nil, 0000)).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_UPX", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_UPX_ARGS", "--best --lzma")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("some-binary"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "broken"), []byte("some-binary"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
 			})
 
-			it("returns an error", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_UPX")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_UPX_ARGS")).To(Succeed())
+			})
+
+			it("compresses each binary and skips failures", func() {
+				mockUPX.On("Available").Return(true)
+				mockUPX.On("Compress", filepath.Join(layersDir, "rust-bin", "bin", "app"), []string{"--best", "--lzma"}).Run(func(args mock.Arguments) {
+					Expect(ioutil.WriteFile(args.String(0), []byte("small"), 0755)).To(Succeed())
+				}).Return(nil)
+				mockUPX.On("Compress", filepath.Join(layersDir, "rust-bin", "bin", "broken"), []string{"--best", "--lzma"}).Return(fmt.Errorf("expected"))
+
 				_, err := build(packit.BuildContext{
 					WorkingDir: workingDir,
 					Layers:     packit.Layers{Path: layersDir},
-					Plan: packit.BuildpackPlan{
-						Entries: []packit.BuildpackPlanEntry{
-							{Name: "rust"},
-						},
-					},
 				})
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("app: 11 bytes -> 5 bytes"))
+				Expect(buffer.String()).To(ContainSubstring("Skipping broken: expected"))
+			})
+
+			it("fails when upx is not available", func() {
+				mockUPX.On("Available").Return(false)
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("upx not found on PATH, but BP_CARGO_UPX is enabled"))
+			})
+		})
+
+		context("when BP_CARGO_LAUNCH_BIN is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_LAUNCH_BIN", "app")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("app"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "verify"), []byte("verify"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_LAUNCH_BIN")).To(Succeed())
+			})
+
+			it("ships only the selected binary in the launch layer", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "verify")).ToNot(BeAnExistingFile())
+			})
+		})
+
+		context("when BP_CARGO_EXTRA_LAUNCH_BINS is set", func() {
+			var helperDir, originalPath string
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_EXTRA_LAUNCH_BINS", "verify,migrate")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_LAUNCH_BIN", "app")).To(Succeed())
+
+				var err error
+				helperDir, err = ioutil.TempDir("", "helper-dir")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(helperDir, "migrate"), []byte("migrate"), 0755)).To(Succeed())
+				originalPath = os.Getenv("PATH")
+				Expect(os.Setenv("PATH", fmt.Sprintf("%s%c%s", originalPath, os.PathListSeparator, helperDir))).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("app"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "verify"), []byte("verify"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "bench"), []byte("bench"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Setenv("PATH", originalPath)).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_EXTRA_LAUNCH_BINS")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_LAUNCH_BIN")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_RENAME_BIN")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_EMIT_PROCESSES")).To(Succeed())
+				Expect(os.RemoveAll(helperDir)).To(Succeed())
+			})
+
+			it("ships the extra binaries and registers a process for each", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "verify")).To(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "migrate")).To(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "bench")).ToNot(BeAnExistingFile())
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					{Type: "verify", Command: filepath.Join(layersDir, "rust-bin", "bin", "verify"), Direct: true},
+					{Type: "migrate", Command: filepath.Join(layersDir, "rust-bin", "bin", "migrate"), Direct: true},
+				}))
+			})
+
+			it("registers renamed binaries under their new name", func() {
+				Expect(os.Setenv("BP_CARGO_RENAME_BIN", "migrate=db-migrate")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.Processes[3].Type).To(Equal("db-migrate"))
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "db-migrate")).To(BeAnExistingFile())
+			})
+
+			it("writes the registered processes to processes.json when BP_CARGO_EMIT_PROCESSES is set", func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_PROCESSES", "true")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers).To(HaveLen(5))
+				Expect(result.Layers[2].Name).To(Equal("rust-processes"))
+				Expect(result.Layers[2].Build).To(BeTrue())
+				Expect(result.Layers[2].Launch).To(BeFalse())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-processes", cargo.ProcessesFile))
+				Expect(err).NotTo(HaveOccurred())
+
+				var list cargo.ProcessList
+				Expect(json.Unmarshal(content, &list)).To(Succeed())
+				Expect(list.SchemaVersion).To(Equal(1))
+				Expect(list.Processes).To(HaveLen(len(result.Launch.Processes)))
+				for i, process := range result.Launch.Processes {
+					Expect(list.Processes[i]).To(Equal(cargo.ProcessEntry{
+						Type:    process.Type,
+						Command: process.Command,
+						Args:    []string{},
+						Default: process.Type == "web",
+					}))
+				}
+			})
+
+			it("fails when a binary is also a launch binary", func() {
+				Expect(os.Setenv("BP_CARGO_LAUNCH_BIN", "app,verify")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("binary verify is listed in both BP_CARGO_LAUNCH_BIN and BP_CARGO_EXTRA_LAUNCH_BINS"))
+			})
+		})
+
+		context("when BP_CARGO_EMIT_SBOM is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_SBOM", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_SBOM_EXCLUDE", "internal-*")).To(Succeed())
+
+				lockfile, err := ioutil.ReadFile(filepath.Join("testdata", "lockfile_internal.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), lockfile, 0644)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_EMIT_SBOM")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_SBOM_EXCLUDE")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_SBOM_WITH_HASHES")).To(Succeed())
+			})
+
+			it("leaves excluded crates out of the SBOM but still builds", func() {
+				mockRunner.On(
+					"TargetPackages",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]string{"app 0.1.0", "internal-tools 0.3.0", "serde 1.0.130"}, nil)
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeAnExistingFile())
+				Expect(result.Layers).To(HaveLen(5))
+				Expect(result.Layers[2].Name).To(Equal("rust-sbom"))
+				Expect(result.Layers[2].Launch).To(BeTrue())
+				Expect(result.Layers[2].Build).To(BeTrue())
+				Expect(result.Layers[2].Cache).To(BeFalse())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-sbom", cargo.SBOMFile))
+				Expect(err).NotTo(HaveOccurred())
+
+				var sbom cargo.SBOM
+				Expect(json.Unmarshal(content, &sbom)).To(Succeed())
+				Expect(sbom.Components).To(Equal([]cargo.SBOMComponent{
+					{Name: "app", Version: "0.1.0"},
+					{Name: "serde", Version: "1.0.130", Source: "registry+https://github.com/rust-lang/crates.io-index"},
+				}))
+				Expect(buffer.String()).To(ContainSubstring("Excluding internal-tools 0.3.0 from the SBOM"))
+				Expect(buffer.String()).NotTo(ContainSubstring("not compiled for"))
+			})
+
+			it("includes checksums when BP_CARGO_SBOM_WITH_HASHES is set", func() {
+				Expect(os.Setenv("BP_CARGO_SBOM_WITH_HASHES", "true")).To(Succeed())
+				mockRunner.On(
+					"TargetPackages",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]string{"app 0.1.0", "internal-tools 0.3.0", "serde 1.0.130"}, nil)
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-sbom", cargo.SBOMFile))
+				Expect(err).NotTo(HaveOccurred())
+
+				var sbom cargo.SBOM
+				Expect(json.Unmarshal(content, &sbom)).To(Succeed())
+				Expect(sbom.Components[1].Name).To(Equal("serde"))
+				Expect(sbom.Components[1].SHA256).To(Equal("f12d06de37cf59146fbdecab66aa99f9fe4f78722e3607577a5375d66bd0c913"))
+				Expect(sbom.Components[1].DownloadURL).To(Equal("https://static.crates.io/crates/serde/serde-1.0.130.crate"))
+			})
+
+			it("leaves crates that are not compiled for the target out of the SBOM", func() {
+				mockRunner.On(
+					"TargetPackages",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]string{"app 0.1.0", "internal-tools 0.3.0"}, nil)
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-sbom", cargo.SBOMFile))
+				Expect(err).NotTo(HaveOccurred())
+
+				var sbom cargo.SBOM
+				Expect(json.Unmarshal(content, &sbom)).To(Succeed())
+				Expect(sbom.Components).To(Equal([]cargo.SBOMComponent{
+					{Name: "app", Version: "0.1.0"},
+				}))
+				Expect(buffer.String()).To(ContainSubstring("Leaving crates that are not compiled for the host out of the SBOM: serde 1.0.130"))
+			})
+		})
+
+		context("when BP_CARGO_ALL_FEATURES is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_ALL_FEATURES", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_FEATURES", "json")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\nfeatures = \"json\"\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_ALL_FEATURES")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+			})
+
+			it("warns that the feature list is ignored and records the features", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("WARNING: BP_CARGO_ALL_FEATURES is set, so BP_CARGO_FEATURES [json] has no effect"))
+				Expect(buffer.String()).To(ContainSubstring("Features have changed since the last build"))
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("features", "all"))
+			})
+		})
+
+		context("when BP_CARGO_PROFILE is set", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-bin.toml"),
+					[]byte("launch = true\n[metadata]\nprofile = \"release\"\n"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_PROFILE")).To(Succeed())
+			})
+
+			it("records the profile and logs that it changed", func() {
+				Expect(os.Setenv("BP_CARGO_PROFILE", "dev")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[1].Metadata).To(HaveKeyWithValue("profile", "dev"))
+				Expect(buffer.String()).To(ContainSubstring("Building with the dev profile"))
+				Expect(buffer.String()).To(ContainSubstring("Build profile has changed from release to dev since the last build"))
+			})
+		})
+
+		context("when BP_CARGO_PROFILE is not defined in Cargo.toml", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_PROFILE", "staging")).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"),
+					[]byte("[package]\nname = \"app\"\nversion = \"0.1.0\"\n"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_PROFILE")).To(Succeed())
+			})
+
+			it("fails listing the valid profiles", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`unknown profile "staging" in BP_CARGO_PROFILE, valid profiles are [bench, dev, release, test]`))
+			})
+		})
+
+		context("when BP_CARGO_DEFAULT_BACKTRACE is set", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_DEFAULT_BACKTRACE")).To(Succeed())
+			})
+
+			it("sets RUST_BACKTRACE as a launch default that can be overridden", func() {
+				Expect(os.Setenv("BP_CARGO_DEFAULT_BACKTRACE", "true")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[1].LaunchEnv).To(Equal(packit.Environment{
+					"RUST_BACKTRACE.default": "1",
+				}))
+			})
+
+			it("sets full backtraces", func() {
+				Expect(os.Setenv("BP_CARGO_DEFAULT_BACKTRACE", "full")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[1].LaunchEnv).To(Equal(packit.Environment{
+					"RUST_BACKTRACE.default": "full",
+				}))
+			})
+
+			it("leaves RUST_BACKTRACE alone when false", func() {
+				Expect(os.Setenv("BP_CARGO_DEFAULT_BACKTRACE", "false")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[1].LaunchEnv).To(BeEmpty())
+			})
+		})
+
+		context("when BP_CARGO_COVERAGE is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_COVERAGE", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_COVERAGE")).To(Succeed())
+			})
+
+			it("configures the launch environment and records coverage in the cache metadata", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("coverage", true))
+				Expect(result.Layers[1].LaunchEnv).To(Equal(packit.Environment{
+					"LLVM_PROFILE_FILE.default": cargo.DefaultLLVMProfileFile,
+				}))
+				Expect(buffer.String()).To(ContainSubstring("WARNING: building with coverage instrumentation"))
+			})
+
+			it("logs when coverage was not used for the cached build", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\n"), 0644)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Coverage instrumentation has changed since the last build"))
+			})
+		})
+
+		context("when building for a musl target", func() {
+			var targetInstalled bool
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-musl")).To(Succeed())
+
+				targetInstalled = true
+				mockRunner.On("TargetInstalled", workingDir, "x86_64-unknown-linux-musl").Return(
+					func(string, string) bool { return targetInstalled }, nil)
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil).Maybe()
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil).Maybe()
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			})
+
+			it("builds static binaries and launches them from the rust-bin layer", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Building statically linked binaries for x86_64-unknown-linux-musl"))
+				Expect(buffer.String()).To(ContainSubstring("Target: x86_64-unknown-linux-musl"))
+				Expect(result.Layers[1].Metadata["build_target"]).To(Equal("x86_64-unknown-linux-musl"))
+				Expect(result.Launch.Processes).To(ContainElement(packit.Process{
+					Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true,
+				}))
+			})
+
+			it("fails when the musl standard library is not installed", func() {
+				targetInstalled = false
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("the standard library for x86_64-unknown-linux-musl is not installed, add `x86_64-unknown-linux-musl` to `targets` in rust-toolchain.toml so that the Rust buildpack installs it with rustup"))
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when the build target changes between builds", func() {
+			var targetInstalled bool
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_TARGET", "aarch64-unknown-linux-gnu")).To(Succeed())
+
+				targetInstalled = true
+				mockRunner.On("TargetInstalled", workingDir, "aarch64-unknown-linux-gnu").Return(
+					func(string, string) bool { return targetInstalled }, nil)
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\nbuild_target = \"x86_64-unknown-linux-gnu\"\n"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			})
+
+			it("warns when the standard library for the target is not installed", func() {
+				targetInstalled = false
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Build target has changed from x86_64-unknown-linux-gnu to aarch64-unknown-linux-gnu"))
+				Expect(buffer.String()).To(ContainSubstring("WARNING: the standard library for aarch64-unknown-linux-gnu is not installed, add `aarch64-unknown-linux-gnu` to `targets` in rust-toolchain.toml or use a Rust toolchain that includes it"))
+			})
+
+			it("does not log a target change when the target is unchanged", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\nbuild_target = \"aarch64-unknown-linux-gnu\"\n"), 0644)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("Build target has changed"))
+			})
+		})
+
+		context("when BP_CARGO_CACHE_ONLY is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_CACHE_ONLY", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_CACHE_ONLY")).To(Succeed())
+			})
+
+			it("builds the application but only keeps the cache layers", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(3))
+				Expect(result.Layers[0].Name).To(Equal("rust-cargo"))
+				Expect(result.Layers[0].Cache).To(BeTrue())
+				Expect(result.Layers[0].Launch).To(BeFalse())
+				Expect(result.Layers[0].Metadata).To(HaveKey("built_at"))
+				Expect(result.Layers[1].Name).To(Equal("rust-registry"))
+				Expect(result.Layers[1].Launch).To(BeFalse())
+				Expect(result.Launch.Processes).To(BeEmpty())
+
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).NotTo(BeAnExistingFile())
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_CACHE_ONLY is set, the application is built to warm the cache but will not be added to the image"))
+				Expect(buffer.String()).NotTo(ContainSubstring("Binary summary"))
+			})
+		})
+
+		context("when BP_CARGO_DISABLE_CACHE is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_DISABLE_CACHE", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"2021-01-01T00:00:00Z\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-target.toml"),
+					[]byte(fmt.Sprintf("cache = true\n[metadata]\nrust_version = %q\n", rustVersion)), 0644)).To(Succeed())
+
+				deps := filepath.Join(layersDir, "rust-target", "release", "deps")
+				Expect(os.MkdirAll(deps, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(deps, "libserde-abc123.rlib"), []byte("cached"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_DISABLE_CACHE")).To(Succeed())
+			})
+
+			it("builds from scratch and doesn't cache the layers", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_DISABLE_CACHE is set, caching is disabled and the application is built from scratch"))
+				Expect(filepath.Join(layersDir, "rust-target", "release", "deps", "libserde-abc123.rlib")).NotTo(BeAnExistingFile())
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[0].Name).To(Equal("rust-cargo"))
+				Expect(result.Layers[0].Cache).To(BeFalse())
+				Expect(result.Layers[1].Name).To(Equal("rust-bin"))
+				Expect(result.Layers[1].Launch).To(BeTrue())
+				Expect(result.Layers[2].Name).To(Equal("rust-registry"))
+				Expect(result.Layers[2].Cache).To(BeFalse())
+				Expect(result.Layers[3].Name).To(Equal("rust-target"))
+				Expect(result.Layers[3].Cache).To(BeFalse())
+
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when BP_CARGO_RUSTFLAGS is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_RUSTFLAGS", "-C opt-level=3")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(workingDir, ".cargo"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".cargo", "config.toml"),
+					[]byte("[build]\nrustflags = \"-C target-cpu=native -C opt-level=2\"\n"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RUSTFLAGS")).To(Succeed())
+			})
+
+			it("records the RUSTFLAGS and warns about options that the project sets too", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("WARNING: BP_CARGO_RUSTFLAGS and build.rustflags in %s both set -C opt-level, the value from BP_CARGO_RUSTFLAGS is used",
+					filepath.Join(workingDir, ".cargo", "config.toml"))))
+				Expect(buffer.String()).NotTo(ContainSubstring("RUSTFLAGS have changed"))
+				Expect(result.Layers[0].Metadata["rustflags"]).To(Equal("-C target-cpu=native -C opt-level=2 -C opt-level=3"))
+			})
+
+			it("notices when the RUSTFLAGS have changed since the last build", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"2021-01-01T00:00:00Z\"\nrustflags = \"-C target-cpu=native -C opt-level=2\"\n"), 0644)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("RUSTFLAGS have changed since the last build, everything will be rebuilt"))
+			})
+		})
+
+		context("when BP_CARGO_BIN_LAYER_NAME is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_BIN_LAYER_NAME", "api-bin")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.MatchedBy(func(layer packit.Layer) bool { return layer.Name == "api-bin" })).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_BIN_LAYER_NAME")).To(Succeed())
+			})
+
+			it("installs the binaries into the named launch layer", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[1].Name).To(Equal("api-bin"))
+				Expect(result.Layers[1].Path).To(Equal(filepath.Join(layersDir, "api-bin")))
+				Expect(result.Layers[1].Launch).To(BeTrue())
+				Expect(result.Layers[1].Metadata).To(HaveKey("binary_sha256"))
+				Expect(result.Launch.Processes).To(ContainElement(packit.Process{
+					Type: "web", Command: filepath.Join(layersDir, "api-bin", "bin", "app"), Direct: true,
+				}))
+				Expect(filepath.Join(layersDir, "rust-bin")).NotTo(BeADirectory())
+			})
+		})
+
+		context("when BP_CARGO_DRY_RUN is set", func() {
+			var mockPlanner mocks.CommandPlanner
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_DRY_RUN", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_FEATURES", "metrics")).To(Succeed())
+
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"api\", \"worker\"]\n"), 0644)).To(Succeed())
+				for _, member := range []string{"api", "worker"} {
+					Expect(os.MkdirAll(filepath.Join(workingDir, member), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, member, "Cargo.toml"), []byte(fmt.Sprintf("[package]\nname = %q\n", member)), 0644)).To(Succeed())
+				}
+
+				var members []url.URL
+				for _, member := range []string{"api", "worker"} {
+					u, err := url.Parse("file://" + filepath.Join(workingDir, member))
+					Expect(err).ToNot(HaveOccurred())
+					members = append(members, *u)
+				}
+
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(members, nil)
+
+				mockPlanner = mocks.CommandPlanner{}
+				mockPlanner.On(
+					"InstallCommand",
+					mock.AnythingOfType("string"),
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(func(memberPath string, _ string, _ packit.Layer, _ packit.Layer) []string {
+					return []string{"install", "--path", memberPath, "--features", "metrics"}
+				}, nil)
+
+				build = cargo.Build(struct {
+					*mocks.Runner
+					*mocks.CommandPlanner
+				}{&mockRunner, &mockPlanner}, &mockUPX, clock, scribe.NewEmitter(buffer))
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_DRY_RUN")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+				mockPlanner.AssertExpectations(t)
+			})
+
+			it("logs the plan without building or changing any layer", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(packit.BuildResult{}))
+
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_DRY_RUN is set, the build is planned but nothing is built"))
+				Expect(buffer.String()).To(ContainSubstring("Build plan"))
+				Expect(buffer.String()).To(MatchRegexp(`Members:\s+api\s+worker`))
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_FEATURES=metrics"))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("cargo install --path %s --features metrics", filepath.Join(workingDir, "worker"))))
+				Expect(buffer.String()).To(ContainSubstring("rust-bin: binaries, launched"))
+				Expect(buffer.String()).To(ContainSubstring("rust-target: cached"))
+
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+				mockRunner.AssertNotCalled(t, "InstallMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+				entries, err := ioutil.ReadDir(layersDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(BeEmpty())
+			})
+
+			it("keeps the cache layers of the previous build as they are", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-target.toml"), []byte("cache = true\n\n[metadata]\n  rust_version = \"1.56.0\"\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-target", "release"), 0755)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers).To(HaveLen(1))
+				Expect(result.Layers[0].Name).To(Equal("rust-target"))
+				Expect(result.Layers[0].Cache).To(BeTrue())
+				Expect(result.Layers[0].Metadata).To(Equal(map[string]interface{}{"rust_version": "1.56.0"}))
+				Expect(filepath.Join(layersDir, "rust-target", "release")).To(BeADirectory())
+			})
+		})
+
+		context("when the build script inputs can't be read", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				// a directory where cargo writes the output of a build script can't be read as one
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-target", "release", "build", "app-0123456789abcdef", "output"), 0755)).To(Succeed())
+			})
+
+			it("warns and builds anyway", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("WARNING: unable to checksum the build script inputs:"))
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("build_script_inputs_sha256", ""))
+			})
+		})
+
+		context("when cargo install leaves its bookkeeping in the launch layer", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					installApp(args)
+					layerDir := args.Get(2).(packit.Layer).Path
+					Expect(ioutil.WriteFile(filepath.Join(layerDir, ".crates.toml"), []byte{}, 0644)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(layerDir, ".crates2.json"), []byte{}, 0644)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_STRIP_NON_BIN")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_EMIT_CHECKSUMS")).To(Succeed())
+			})
+
+			it("removes everything but the binaries, before the buildpack writes its own files", func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_CHECKSUMS", "true")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "rust-bin", ".crates.toml")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", ".crates2.json")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeARegularFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "checksums.txt")).To(BeARegularFile())
+			})
+
+			it("keeps the bookkeeping when BP_CARGO_STRIP_NON_BIN is false", func() {
+				Expect(os.Setenv("BP_CARGO_STRIP_NON_BIN", "false")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "rust-bin", ".crates.toml")).To(BeARegularFile())
+				Expect(filepath.Join(layersDir, "rust-bin", ".crates2.json")).To(BeARegularFile())
+			})
+		})
+
+		context("when BP_CARGO_RUN_CLIPPY is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_RUN_CLIPPY", "true")).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RUN_CLIPPY")).To(Succeed())
+			})
+
+			it("runs clippy before installing", func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				var linted bool
+				mockRunner.On(
+					"Clippy",
+					workingDir,
+					mock.MatchedBy(func(layer packit.Layer) bool { return layer.Name == "rust-cargo" }),
+					mock.MatchedBy(func(layer packit.Layer) bool { return layer.Name == "rust-bin" })).Run(func(mock.Arguments) { linted = true }).Return(nil)
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					Expect(linted).To(BeTrue())
+					installApp(args)
+				}).Return(nil)
+
+				_, err = build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("clippy: passed"))
+			})
+
+			it("fails without installing when clippy fails", func() {
+				mockRunner.On(
+					"Clippy",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(fmt.Errorf("clippy failed: exit status 101"))
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("clippy failed: exit status 101"))
+				Expect(buffer.String()).To(ContainSubstring("clippy: failed"))
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when the project is a virtual manifest with one member", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"app\"]\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "app"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "app", "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it("installs the member by path instead of installing the root", func() {
+				for _, memberPath := range []string{filepath.Join(workingDir, "app"), "/workspace"} {
+					mockRunner.ExpectedCalls = nil
+					mockRunner.Calls = nil
+					mockRunner.On("Version", mock.Anything, mock.Anything, mock.Anything).Return(rustVersion, nil).Maybe()
+					mockRunner.On("CheckToolchain").Return(nil).Maybe()
+
+					member, err := url.Parse(fmt.Sprintf("file://%s", memberPath))
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"InstallMember",
+						mock.Anything,
+						memberPath,
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					_, err = build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					mockRunner.AssertCalled(t, "InstallMember", mock.Anything, memberPath, workingDir, mock.Anything, mock.Anything)
+					mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+				}
+			})
+		})
+
+		context("when BP_CARGO_CLEAN is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_CLEAN", "true")).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_CLEAN")).To(Succeed())
+			})
+
+			it("cleans the rust-target layer before installing", func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				var cleaned bool
+				mockRunner.On(
+					"Clean",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(mock.Arguments) { cleaned = true }).Return(nil)
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					Expect(cleaned).To(BeTrue())
+					installApp(args)
+				}).Return(nil)
+
+				_, err = build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_CLEAN is set, removing the build output in the rust-target layer"))
+			})
+
+			it("fails when cleaning fails", func() {
+				mockRunner.On(
+					"Clean",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(fmt.Errorf("clean failed: exit status 101"))
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("clean failed: exit status 101"))
+			})
+		})
+
+		context("when BP_CARGO_RUN_TESTS is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_RUN_TESTS", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RUN_TESTS")).To(Succeed())
+			})
+
+			it("runs the tests after the install with the work layer", func() {
+				mockRunner.On(
+					"Test",
+					workingDir,
+					mock.MatchedBy(func(layer packit.Layer) bool { return layer.Name == "rust-cargo" }),
+					mock.MatchedBy(func(layer packit.Layer) bool { return layer.Name == "rust-bin" })).Return(nil)
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Gates: tests"))
+				Expect(buffer.String()).To(ContainSubstring("Build gates\n    tests: passed\n"))
+			})
+
+			it("fails the build when the tests fail", func() {
+				mockRunner.On(
+					"Test",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(fmt.Errorf("tests failed: exit status 101"))
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("tests failed: exit status 101"))
+				Expect(buffer.String()).To(ContainSubstring("Build gates\n    tests: failed\n"))
+			})
+		})
+
+		context("when builds are locked", func() {
+			it.Before(func() {
+				Expect(os.Unsetenv("BP_CARGO_LOCKED")).To(Succeed())
+			})
+
+			it("fails early when there is no Cargo.lock", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("there is no Cargo.lock in %s, which is required because builds are locked by default, "+
+					"commit Cargo.lock or set BP_CARGO_LOCKED=false to let cargo resolve the dependencies", workingDir)))
+			})
+
+			it("fails on an invalid value", func() {
+				Expect(os.Setenv("BP_CARGO_LOCKED", "sometimes")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid value for BP_CARGO_LOCKED")))
+			})
+		})
+
+		context("when there is no Cargo.lock and builds are unlocked", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}\n"), 0644)).To(Succeed())
+			})
+
+			it("detects and builds the project", func() {
+				_, err := cargo.Detect(scribe.NewEmitter(bytes.NewBuffer(nil)))(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+
+				_, err = build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeARegularFile())
+			})
+		})
+
+		context("when BP_CARGO_HOME is set", func() {
+			var home string
+
+			it.Before(func() {
+				var err error
+				home, err = ioutil.TempDir("", "cargo-home")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Setenv("BP_CARGO_HOME", home)).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_HOME")).To(Succeed())
+				Expect(os.RemoveAll(home)).To(Succeed())
+			})
+
+			it("uses it instead of the rust-cargo and rust-registry cache layers", func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				result, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(2))
+				Expect(result.Layers[0].Name).To(Equal("rust-bin"))
+				Expect(result.Layers[1].Name).To(Equal("rust-target"))
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Using %s from BP_CARGO_HOME as CARGO_HOME, the rust-cargo and rust-registry cache layers are not kept", home)))
+				Expect(filepath.Join(home, "registry")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "rust-cargo", "home", "registry")).NotTo(BeAnExistingFile())
+			})
+
+			it("fails when the directory does not exist", func() {
+				Expect(os.Setenv("BP_CARGO_HOME", filepath.Join(home, "missing"))).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("BP_CARGO_HOME is set to %s, which cannot be used as CARGO_HOME", filepath.Join(home, "missing")))))
+			})
+
+			it("fails when the path is relative", func() {
+				Expect(os.Setenv("BP_CARGO_HOME", "cargo-home")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`BP_CARGO_HOME must be an absolute path, got "cargo-home"`))
+			})
+
+			it("fails when the directory is not writable", func() {
+				if os.Geteuid() == 0 {
+					t.Skip("root can write to read-only directories")
+				}
+
+				Expect(os.Chmod(home, 0555)).To(Succeed())
+				defer func() { Expect(os.Chmod(home, 0755)).To(Succeed()) }()
+
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbPath,
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("BP_CARGO_HOME is set to %s, which is not writable by the build user", home)))
+			})
+		})
+
+		context("when BP_CARGO_SCCACHE is set", func() {
+			var (
+				toolsDir string
+				path     string
+			)
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_SCCACHE", "true")).To(Succeed())
+
+				var err error
+				toolsDir, err = ioutil.TempDir("", "tools")
+				Expect(err).NotTo(HaveOccurred())
+
+				path = os.Getenv("PATH")
+				Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_SCCACHE")).To(Succeed())
+				Expect(os.Setenv("PATH", path)).To(Succeed())
+				Expect(os.RemoveAll(toolsDir)).To(Succeed())
+			})
+
+			it("keeps the sccache cache in its own layer and records the version", func() {
+				script := "#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then echo \"sccache 0.2.15\"; fi\n"
+				Expect(ioutil.WriteFile(filepath.Join(toolsDir, "sccache"), []byte(script), 0755)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Compiling with sccache 0.2.15, the cache is kept in the rust-sccache layer"))
+				Expect(buffer.String()).To(ContainSubstring("sccache statistics"))
+
+				sccacheLayer := result.Layers[len(result.Layers)-1]
+				Expect(sccacheLayer.Name).To(Equal("rust-sccache"))
+				Expect(sccacheLayer.Cache).To(BeTrue())
+				Expect(sccacheLayer.Launch).To(BeFalse())
+				Expect(sccacheLayer.Metadata).To(Equal(map[string]interface{}{"sccache_version": "sccache 0.2.15"}))
+				Expect(filepath.Join(layersDir, "rust-sccache")).To(BeADirectory())
+			})
+
+			it("warns and builds without sccache when it is not installed", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: BP_CARGO_SCCACHE is set, but sccache could not be found on the PATH, building without it"))
+				Expect(buffer.String()).NotTo(ContainSubstring("sccache statistics"))
+				for _, layer := range result.Layers {
+					Expect(layer.Name).NotTo(Equal("rust-sccache"))
+				}
+			})
+		})
+
+		context("when BP_CARGO_INSTALL_EXAMPLES is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_EXAMPLES", "client")).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_EXAMPLES")).To(Succeed())
+			})
+
+			it("registers a process for each example", func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Examples",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]string{"client"}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					installApp(args)
+					binDir := filepath.Join(args.Get(len(args)-1).(packit.Layer).Path, "bin")
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "client"), []byte("client"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Installing examples [client] alongside the binaries"))
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					{Type: "client", Command: filepath.Join(layersDir, "rust-bin", "bin", "client"), Direct: true},
+				}))
+				Expect(result.Layers[1].Metadata["examples"]).To(Equal([]string{"client"}))
+			})
+
+			it("fails when an example clashes with a binary", func() {
+				mockRunner.On(
+					"Examples",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(nil, errors.New("example app has the same name as a binary"))
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("example app has the same name as a binary"))
+			})
+		})
+
+		context("when the Rust toolchain is missing", func() {
+			it("fails before any layer is created", func() {
+				toolchainErr = errors.New("cargo not found on PATH, ensure that the Rust distribution buildpack ran before this one")
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("cargo not found on PATH, ensure that the Rust distribution buildpack ran before this one"))
+
+				files, err := ioutil.ReadDir(layersDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(files).To(BeEmpty())
+				mockRunner.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when BP_CARGO_VERBOSE is set", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_VERBOSE")).To(Succeed())
+			})
+
+			it("logs the decisions of the buildpack", func() {
+				Expect(os.Setenv("BP_CARGO_VERBOSE", "1")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("BP_CARGO_VERBOSE is set, cargo is run with -v"))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Using %s as CARGO_TARGET_DIR", filepath.Join(layersDir, "rust-target"))))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Using %s as CARGO_HOME", filepath.Join(layersDir, "rust-cargo", "home"))))
+			})
+
+			it("logs nothing extra when it is not set", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("BP_CARGO_VERBOSE"))
+				Expect(buffer.String()).NotTo(ContainSubstring("as CARGO_TARGET_DIR"))
+			})
+		})
+
+		context("when BP_CARGO_STRIP is set", func() {
+			var (
+				toolsDir string
+				path     string
+			)
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_STRIP", "true")).To(Succeed())
+
+				var err error
+				toolsDir, err = ioutil.TempDir("", "tools")
+				Expect(err).NotTo(HaveOccurred())
+
+				path = os.Getenv("PATH")
+				Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_STRIP")).To(Succeed())
+				Expect(os.Setenv("PATH", path)).To(Succeed())
+				Expect(os.RemoveAll(toolsDir)).To(Succeed())
+			})
+
+			it("strips the installed binaries and reports the savings", func() {
+				// empties the binary, which stands in for removing its symbols
+				script := "#!/bin/sh\n: > \"$1\"\n"
+				Expect(ioutil.WriteFile(filepath.Join(toolsDir, "strip"), []byte(script), 0755)).To(Succeed())
+				Expect(os.Setenv("PATH", toolsDir+":/bin:/usr/bin")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Debug symbols are removed with strip once the binaries are installed"))
+				Expect(buffer.String()).To(ContainSubstring("Stripping debug symbols from binaries"))
+				Expect(buffer.String()).To(ContainSubstring("app: 3 bytes -> 0 bytes, saved 3 bytes"))
+				Expect(filepath.Join(layersDir, "rust-bin", "bin", "app")).To(BeAnExistingFile())
+			})
+
+			it("warns and strips with rustc when strip is not installed", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("WARNING: BP_CARGO_STRIP is set, but strip could not be found on the PATH, passing `-C strip=symbols` to rustc instead"))
+				Expect(buffer.String()).NotTo(ContainSubstring("Stripping debug symbols from binaries"))
+			})
+		})
+
+		context("when the sources are unchanged since the last build", func() {
+			// recordBuild writes the layer metadata of a previous build of the project in workingDir
+			recordBuild := func() {
+				sourceChecksum, err := cargo.SourceChecksum(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				configChecksum, err := cargo.ConfigChecksum(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-bin.toml"), []byte(fmt.Sprintf(`launch = true
+[metadata]
+built_at = "yesterday"
+profile = "release"
+rust_version = %q
+source_sha256 = %q
+config_sha256 = %q
+[metadata.binary_sha256]
+app = %q
+`, rustVersion, sourceChecksum, configChecksum, appSHA256)), 0644)).To(Succeed())
+
+				// no build scripts ran in the previous build
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"), []byte(fmt.Sprintf(`cache = true
+[metadata]
+build_script_inputs_sha256 = %q
+`, emptySHA256)), 0644)).To(Succeed())
+			}
+
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+				recordBuild()
+			})
+
+			it("reuses the binaries from the previous image without running cargo", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Reusing cached binaries"))
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+				mockRunner.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything, mock.Anything)
+
+				Expect(result.Layers).To(HaveLen(4))
+				Expect(result.Layers[1].Name).To(Equal("rust-bin"))
+				Expect(result.Layers[1].Launch).To(BeTrue())
+				Expect(result.Layers[1].Metadata["built_at"]).To(Equal("yesterday"))
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{Type: "web", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+					{Type: "app", Command: filepath.Join(layersDir, "rust-bin", "bin", "app"), Direct: true},
+				}))
+			})
+
+			context("when optional layers are written", func() {
+				it.Before(func() {
+					lockfile, err := ioutil.ReadFile(filepath.Join("testdata", "lockfile_internal.toml"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), lockfile, 0644)).To(Succeed())
+					recordBuild()
+
+					Expect(os.Setenv("BP_CARGO_EMIT_OTEL", "true")).To(Succeed())
+					Expect(os.Setenv("BP_CARGO_EMIT_DEPGRAPH", "true")).To(Succeed())
+					Expect(os.Setenv("BP_CARGO_EMIT_PROCESSES", "true")).To(Succeed())
+					Expect(os.Setenv("BP_CARGO_EMIT_SBOM", "true")).To(Succeed())
+
+					mockRunner.On(
+						"DependencyGraph",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return("digraph dependencies {\n}\n", nil)
+
+					mockRunner.On(
+						"TargetPackages",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]string{"app 0.1.0", "internal-tools 0.3.0", "serde 1.0.130"}, nil)
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_CARGO_EMIT_OTEL")).To(Succeed())
+					Expect(os.Unsetenv("BP_CARGO_EMIT_DEPGRAPH")).To(Succeed())
+					Expect(os.Unsetenv("BP_CARGO_EMIT_PROCESSES")).To(Succeed())
+					Expect(os.Unsetenv("BP_CARGO_EMIT_SBOM")).To(Succeed())
+				})
+
+				it("writes them next to the reused binaries", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).To(ContainSubstring("Reusing cached binaries"))
+					mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+
+					var names []string
+					for _, layer := range result.Layers {
+						names = append(names, layer.Name)
+					}
+					Expect(names).To(Equal([]string{"rust-cargo", "rust-bin", "rust-otel", "rust-depgraph", "rust-processes", "rust-sbom", "rust-registry", "rust-target"}))
+
+					Expect(filepath.Join(layersDir, "rust-otel", cargo.OTelAttributesFile)).To(BeAnExistingFile())
+					Expect(filepath.Join(layersDir, "rust-depgraph", cargo.DependencyGraphFile)).To(BeAnExistingFile())
+					Expect(filepath.Join(layersDir, "rust-processes", cargo.ProcessesFile)).To(BeAnExistingFile())
+					Expect(filepath.Join(layersDir, "rust-sbom", cargo.SBOMFile)).To(BeAnExistingFile())
+				})
+			})
+
+			context("when BP_CARGO_MAX_IMAGE_SIZE was set for the last build too", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "1G")).To(Succeed())
+					recordBuild()
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_CARGO_MAX_IMAGE_SIZE")).To(Succeed())
+				})
+
+				it("builds the binaries again, so that they can be measured", func() {
+
+					member, err := url.Parse("file:///workspace")
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"Install",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					_, err = build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached binaries"))
+					Expect(buffer.String()).To(ContainSubstring("Build gates\n    image size: passed\n"))
+				})
+			})
+
+			context("when a source file changes", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}\n"), 0644)).To(Succeed())
+
+					member, err := url.Parse("file:///workspace")
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"Install",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				})
+
+				it("builds again", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached binaries"))
+					Expect(result.Layers[1].Metadata["built_at"]).To(Equal(timestamp))
+				})
+			})
+
+			context("when the configuration changes", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_CARGO_PROFILE", "dev")).To(Succeed())
+
+					member, err := url.Parse("file:///workspace")
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"Install",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_CARGO_PROFILE")).To(Succeed())
+				})
+
+				it("builds again", func() {
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached binaries"))
+				})
+			})
+
+			context("when the inputs of a build script change", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+						[]byte("cache = true\n[metadata]\nbuild_script_inputs_sha256 = \"abc123\"\n"), 0644)).To(Succeed())
+
+					member, err := url.Parse("file:///workspace")
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"Install",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				})
+
+				it("builds again", func() {
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached binaries"))
+				})
+			})
+
+			context("when the git commit changes", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(workingDir, ".git"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, ".git", "HEAD"), []byte("abc1234def5678\n"), 0644)).To(Succeed())
+
+					member, err := url.Parse("file:///workspace")
+					Expect(err).ToNot(HaveOccurred())
+					mockRunner.On(
+						"WorkspaceMembers",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+					mockRunner.On(
+						"Install",
+						workingDir,
+						mock.AnythingOfType("packit.Layer"),
+						mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+					Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				})
+
+				it("builds again and records the new commit", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached binaries"))
+					Expect(result.Layers[1].Metadata["git_sha"]).To(Equal("abc1234def5678"))
+				})
+			})
+		})
+
+		context("when Cargo.lock changes between builds", func() {
+			var extracted string
+
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), []byte{}, 0644)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-registry.toml"),
+					[]byte("cache = true\n[metadata]\ncargo_lock_sha256 = \"abc123\"\n"), 0644)).To(Succeed())
+
+				extracted = filepath.Join(layersDir, "rust-registry", "src", "index.crates.io-6f17d22bba15001f", "serde-1.0.130")
+				Expect(os.MkdirAll(extracted, 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-registry", "cache"), 0755)).To(Succeed())
+			})
+
+			it("removes the extracted crate sources, keeps the downloads and records the new checksum", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Cargo.lock has changed since the last build, removing extracted crate sources from the registry cache"))
+				Expect(extracted).NotTo(BeADirectory())
+				Expect(filepath.Join(layersDir, "rust-registry", "cache")).To(BeADirectory())
+
+				registryLayer := result.Layers[len(result.Layers)-2]
+				Expect(registryLayer.Name).To(Equal("rust-registry"))
+				Expect(registryLayer.Cache).To(BeTrue())
+				Expect(registryLayer.Launch).To(BeFalse())
+				Expect(registryLayer.Metadata).To(Equal(map[string]interface{}{"cargo_lock_sha256": emptySHA256}))
+
+				target, err := os.Readlink(filepath.Join(layersDir, "rust-cargo", "home", "registry"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(target).To(Equal(filepath.Join(layersDir, "rust-registry")))
+			})
+
+			it("treats the git checkouts in CARGO_HOME as stale when the recorded hash differs", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"2021-10-04T00:00:00Z\"\ncargo_lock_sha256 = \"abc123\"\n"), 0644)).To(Succeed())
+				checkout := filepath.Join(layersDir, "rust-cargo", "home", "git", "checkouts", "dep-1234", "abcdef")
+				Expect(os.MkdirAll(checkout, 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo", "home", "git", "db", "dep-1234"), 0755)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Cargo.lock has changed since the last build, removing git dependency checkouts from CARGO_HOME"))
+				Expect(checkout).NotTo(BeADirectory())
+				Expect(filepath.Join(layersDir, "rust-cargo", "home", "git", "db", "dep-1234")).To(BeADirectory())
+
+				Expect(result.Layers[0].Name).To(Equal("rust-cargo"))
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("cargo_lock_sha256", emptySHA256))
+			})
+
+			it("keeps the git checkouts in CARGO_HOME when the recorded hash matches", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte(fmt.Sprintf("cache = true\n[metadata]\nbuilt_at = \"2021-10-04T00:00:00Z\"\ncargo_lock_sha256 = %q\n", emptySHA256)), 0644)).To(Succeed())
+				checkout := filepath.Join(layersDir, "rust-cargo", "home", "git", "checkouts", "dep-1234", "abcdef")
+				Expect(os.MkdirAll(checkout, 0755)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("removing git dependency checkouts"))
+				Expect(checkout).To(BeADirectory())
+			})
+
+			it("keeps the extracted crate sources when Cargo.lock is unchanged", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-registry.toml"),
+					[]byte(fmt.Sprintf("cache = true\n[metadata]\ncargo_lock_sha256 = %q\n", emptySHA256)), 0644)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("Cargo.lock has changed"))
+				Expect(extracted).To(BeADirectory())
+			})
+
+			it("removes the extracted crate sources when there is no Cargo.lock", func() {
+				Expect(os.Remove(filepath.Join(workingDir, "Cargo.lock"))).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(extracted).NotTo(BeADirectory())
+				Expect(result.Layers[len(result.Layers)-2].Metadata).To(Equal(map[string]interface{}{"cargo_lock_sha256": ""}))
+			})
+		})
+
+		context("when the Rust toolchain changes between builds", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-target.toml"),
+					[]byte("cache = true\n[metadata]\nrust_version = \"cargo 1.55.0, rustc 1.55.0\"\n"), 0644)).To(Succeed())
+
+				deps := filepath.Join(layersDir, "rust-target", "release", "deps")
+				Expect(os.MkdirAll(deps, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(deps, "libserde-abc123.rlib"), []byte("stale"), 0644)).To(Succeed())
+			})
+
+			it("empties the target layer and records the new version", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Rust toolchain has changed from cargo 1.55.0, rustc 1.55.0 to %s since the last build, removing the rust-target layer", rustVersion)))
+				Expect(filepath.Join(layersDir, "rust-target", "release", "deps", "libserde-abc123.rlib")).NotTo(BeAnExistingFile())
+
+				targetLayer := result.Layers[len(result.Layers)-1]
+				Expect(targetLayer.Name).To(Equal("rust-target"))
+				Expect(targetLayer.Cache).To(BeTrue())
+				Expect(targetLayer.Launch).To(BeFalse())
+				Expect(targetLayer.Build).To(BeFalse())
+				Expect(targetLayer.Metadata).To(Equal(map[string]interface{}{"rust_version": rustVersion}))
+			})
+
+			it("keeps the target layer when the version can't be read", func() {
+				rustVersion = ""
+				rustVersionErr = errors.New("rustc not found")
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("Rust toolchain has changed"))
+				Expect(filepath.Join(layersDir, "rust-target", "release", "deps", "libserde-abc123.rlib")).To(BeAnExistingFile())
+				Expect(result.Layers[len(result.Layers)-1].Metadata).To(Equal(map[string]interface{}{"rust_version": "cargo 1.55.0, rustc 1.55.0"}))
+			})
+		})
+
+		context("when the pinned toolchain changes between builds", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain"), []byte("1.56.0\n"), 0644)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\ntoolchain_channel = \"1.55.0\"\n"), 0644)).To(Succeed())
+
+				deps := filepath.Join(layersDir, "rust-target", "release", "deps")
+				Expect(os.MkdirAll(deps, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(deps, "libserde-abc123.rlib"), []byte("stale"), 0644)).To(Succeed())
+			})
+
+			it("removes the cached build output and records the new channel", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Pinned toolchain has changed from 1.55.0 to 1.56.0 since the last build, removing cached build output"))
+				Expect(filepath.Join(layersDir, "rust-target", "release", "deps", "libserde-abc123.rlib")).NotTo(BeAnExistingFile())
+
+				Expect(result.Layers[0].Name).To(Equal("rust-cargo"))
+				Expect(result.Layers[0].Metadata["toolchain_channel"]).To(Equal("1.56.0"))
+			})
+
+			it("keeps the cached build output when the channel is unchanged", func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"),
+					[]byte("cache = true\n[metadata]\nbuilt_at = \"yesterday\"\ntoolchain_channel = \"1.56.0\"\n"), 0644)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).NotTo(ContainSubstring("Pinned toolchain has changed"))
+				Expect(filepath.Join(layersDir, "rust-target", "release", "deps", "libserde-abc123.rlib")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when BP_CARGO_PREFETCH is set", func() {
+			var componentsEnsured chan struct{}
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_PREFETCH", "true")).To(Succeed())
+
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"),
+					[]byte("[toolchain]\ncomponents = [\"clippy\"]\n"), 0644)).To(Succeed())
+
+				componentsEnsured = make(chan struct{})
+				mockRunner.On("EnsureComponents", workingDir, []string{"clippy"}).Run(func(mock.Arguments) {
+					close(componentsEnsured)
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_PREFETCH")).To(Succeed())
+			})
+
+			it("fetches dependencies once the toolchain is set up", func() {
+				mockRunner.On(
+					"Fetch",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(func(string, packit.Layer, packit.Layer) error {
+					select {
+					case <-componentsEnsured:
+						return nil
+					default:
+						return errors.New("fetch started before the toolchain components were installed")
+					}
+				})
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				_, err = build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Fetching dependencies while the build is prepared"))
+			})
+
+			it("fails before compiling when the fetch fails", func() {
+				mockRunner.On(
+					"Fetch",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(errors.New("fetch failed: network unreachable"))
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("unable to prefetch dependencies\nfetch failed: network unreachable")))
+				mockRunner.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything, mock.Anything)
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			it("waits for the fetch when the build fails while it runs", func() {
+				Expect(os.Setenv("BP_CARGO_MEMBER_TIMEOUT", "soon")).To(Succeed())
+				defer os.Unsetenv("BP_CARGO_MEMBER_TIMEOUT")
+
+				var fetched int32
+				mockRunner.On(
+					"Fetch",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(func(string, packit.Layer, packit.Layer) error {
+					time.Sleep(100 * time.Millisecond)
+					atomic.StoreInt32(&fetched, 1)
+					return nil
+				})
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid value for BP_CARGO_MEMBER_TIMEOUT")))
+				Expect(atomic.LoadInt32(&fetched)).To(Equal(int32(1)))
+			})
+		})
+
+		context("when BP_CARGO_OFFLINE is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_OFFLINE", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_PREFETCH", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_OFFLINE")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_PREFETCH")).To(Succeed())
+			})
+
+			it("skips the prefetch and warns when nothing is vendored", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				mockRunner.AssertNotCalled(t, "Fetch", mock.Anything, mock.Anything, mock.Anything)
+				Expect(buffer.String()).To(ContainSubstring("Skipping BP_CARGO_PREFETCH because BP_CARGO_OFFLINE is set"))
+				Expect(buffer.String()).To(ContainSubstring("WARNING: BP_CARGO_OFFLINE is set but there is no vendor directory or .cargo/config.toml"))
+			})
+
+			it("does not warn when dependencies are vendored", func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "serde"), 0755)).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).NotTo(ContainSubstring("WARNING: BP_CARGO_OFFLINE is set"))
+			})
+		})
+
+		context("when BP_CARGO_MAX_IMAGE_SIZE is set", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_MAX_IMAGE_SIZE")).To(Succeed())
+			})
+
+			it("reports the launch layers when they are within the budget", func() {
+				Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "1K")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("rust-bin: 3 B"))
+			})
+
+			it("fails when the launch layers exceed the budget", func() {
+				Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "2")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("launch layers use 3 B, which exceeds BP_CARGO_MAX_IMAGE_SIZE of 2 B"))
+			})
+		})
+
+		context("when BP_CARGO_INCLUDE_FILES is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INCLUDE_FILES", "static")).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(workingDir, "static"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "static", "logo.png"), []byte("logo"), 0644)).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte(time.Now().String()), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INCLUDE_FILES")).To(Succeed())
+			})
+
+			it("ships the assets in their own launch layer with independent metadata", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers).To(HaveLen(5))
+
+				binaryLayer, assetsLayer := result.Layers[1], result.Layers[2]
+				Expect(binaryLayer.Name).To(Equal("rust-bin"))
+				Expect(assetsLayer.Name).To(Equal("rust-assets"))
+				Expect(assetsLayer.Launch).To(BeTrue())
+				Expect(assetsLayer.Metadata).To(HaveKey("assets_sha256"))
+				Expect(assetsLayer.Metadata).ToNot(HaveKey("binary_sha256"))
+				Expect(binaryLayer.Metadata).ToNot(HaveKey("assets_sha256"))
+				Expect(filepath.Join(layersDir, "rust-assets", "static", "logo.png")).To(BeARegularFile())
+				Expect(filepath.Join(layersDir, "rust-bin", "static")).ToNot(BeAnExistingFile())
+
+				// a rebuild with new binaries but the same assets keeps the assets metadata
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-assets.toml"),
+					[]byte(fmt.Sprintf("launch = true\n[metadata]\nassets_sha256 = %q\n", assetsLayer.Metadata["assets_sha256"])), 0644)).To(Succeed())
+
+				rebuild, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rebuild.Layers[1].Metadata["binary_sha256"]).ToNot(Equal(binaryLayer.Metadata["binary_sha256"]))
+				Expect(rebuild.Layers[2].Metadata).To(Equal(assetsLayer.Metadata))
+				Expect(buffer.String()).To(ContainSubstring("Reusing assets layer"))
+			})
+		})
+
+		context("when BP_CARGO_VALIDATE_CMD is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_VALIDATE_CMD", "app config check")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("#!/bin/sh\n[ \"$*\" = \"config check\" ]\n"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_VALIDATE_CMD")).To(Succeed())
+			})
+
+			it("runs the validation command", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Validation passed"))
+			})
+
+			it("fails the build when validation fails", func() {
+				Expect(os.Setenv("BP_CARGO_VALIDATE_CMD", "app config lint")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("validation command app failed")))
+			})
+
+			it("summarizes the enabled gates", func() {
+				Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "1M")).To(Succeed())
+				defer os.Unsetenv("BP_CARGO_MAX_IMAGE_SIZE")
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Build gates\n    validation command: passed\n    image size: passed\n"))
+				Expect(buffer.String()).NotTo(ContainSubstring("reproducible sources"))
+			})
+
+			it("summarizes the gates up to the one that failed", func() {
+				Expect(os.Setenv("BP_CARGO_VALIDATE_CMD", "app config lint")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "1M")).To(Succeed())
+				defer os.Unsetenv("BP_CARGO_MAX_IMAGE_SIZE")
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Build gates\n    validation command: failed\n"))
+				Expect(buffer.String()).NotTo(ContainSubstring("image size:"))
+			})
+		})
+
+		context("when BP_CARGO_EMIT_DEPGRAPH is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_DEPGRAPH", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				mockRunner.On(
+					"DependencyGraph",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return("digraph dependencies {\n}\n", nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_EMIT_DEPGRAPH")).To(Succeed())
+			})
+
+			it("writes the graph to a build-time only layer", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers).To(HaveLen(5))
+				Expect(result.Layers[2].Name).To(Equal("rust-depgraph"))
+				Expect(result.Layers[2].Build).To(BeTrue())
+				Expect(result.Layers[2].Launch).To(BeFalse())
+				Expect(result.Layers[2].Cache).To(BeFalse())
+				Expect(ioutil.ReadFile(filepath.Join(layersDir, "rust-depgraph", cargo.DependencyGraphFile))).To(Equal([]byte("digraph dependencies {\n}\n")))
+			})
+		})
+
+		context("when BP_CARGO_EMIT_OTEL is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_OTEL", "true")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_EMIT_OTEL")).To(Succeed())
+			})
+
+			it("writes the build attributes to a build-time only layer", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers).To(HaveLen(5))
+				Expect(result.Layers[2].Name).To(Equal("rust-otel"))
+				Expect(result.Layers[2].Build).To(BeTrue())
+				Expect(result.Layers[2].Launch).To(BeFalse())
+				Expect(result.Layers[2].Cache).To(BeFalse())
+				Expect(filepath.Join(layersDir, "rust-otel", cargo.OTelAttributesFile)).To(BeAnExistingFile())
+			})
+		})
+
+		context("when BP_CARGO_STREAM_MEMBERS is set", func() {
+			var (
+				mockStreamer mocks.MemberStreamer
+				members      []url.URL
+				installed    []string
+				firstBuilt   chan struct{}
+			)
+
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_STREAM_MEMBERS", "true")).To(Succeed())
+
+				members = nil
+				for i := 0; i < 200; i++ {
+					member, err := url.Parse(fmt.Sprintf("file:///workspace/member-%03d", i))
+					Expect(err).ToNot(HaveOccurred())
+					members = append(members, *member)
+				}
+
+				installed = nil
+				firstBuilt = make(chan struct{})
+
+				mockStreamer = mocks.MemberStreamer{}
+				mockStreamer.On(
+					"StreamWorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer"),
+					mock.Anything).Run(func(args mock.Arguments) {
+					stream := args.Get(3).(chan<- url.URL)
+					defer close(stream)
+
+					stream <- members[0]
+					stream <- members[1]
+
+					// the rest are only resolved once building has started
+					select {
+					case <-firstBuilt:
+					case <-time.After(5 * time.Second):
+						return
+					}
+
+					for _, member := range members[2:] {
+						stream <- member
+					}
+				}).Return(nil)
+
+				mockRunner.On(
+					"InstallMember",
+					mock.Anything,
+					mock.AnythingOfType("string"),
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					installed = append(installed, args.String(1))
+					if len(installed) == 1 {
+						close(firstBuilt)
+					}
+					installApp(args)
+				}).Return(nil)
+
+				build = cargo.Build(struct {
+					*mocks.Runner
+					*mocks.MemberStreamer
+				}{&mockRunner, &mockStreamer}, &mockUPX, clock, scribe.NewEmitter(buffer))
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_STREAM_MEMBERS")).To(Succeed())
+				mockStreamer.AssertExpectations(t)
+			})
+
+			it("starts building members before they have all been resolved", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(installed).To(HaveLen(len(members)))
+				for i, member := range members {
+					Expect(installed[i]).To(Equal(member.Path))
+				}
+				mockRunner.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when BP_CARGO_GIT_SHA is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_GIT_SHA", "1a2b3c4")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_GIT_SHA")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_VERSION_FILE")).To(Succeed())
+			})
+
+			it("records the commit in the rust-bin layer metadata", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[1].Metadata["git_sha"]).To(Equal("1a2b3c4"))
+				Expect(filepath.Join(layersDir, "rust-bin", cargo.VersionFile)).ToNot(BeAnExistingFile())
+			})
+
+			it("writes version.txt when BP_CARGO_VERSION_FILE is set", func() {
+				Expect(os.Setenv("BP_CARGO_VERSION_FILE", "true")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-bin", cargo.VersionFile))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("1a2b3c4\n"))
+			})
+		})
+
+		context("when there is no git commit", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_VERSION_FILE")).To(Succeed())
+			})
+
+			it("does not mention it", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).NotTo(ContainSubstring("No git commit found"))
+			})
+
+			it("logs that version.txt is not written when BP_CARGO_VERSION_FILE is set", func() {
+				Expect(os.Setenv("BP_CARGO_VERSION_FILE", "true")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("No git commit found in BP_CARGO_GIT_SHA or a .git directory, version.txt will not be written"))
+				Expect(filepath.Join(layersDir, "rust-bin", cargo.VersionFile)).ToNot(BeAnExistingFile())
+			})
+		})
+
+		context("when a registry token is set in the environment", func() {
+			it.Before(func() {
+				Expect(os.Setenv("CARGO_REGISTRIES_INTERNAL_TOKEN", "s3cr3t-internal")).To(Succeed())
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("CARGO_REGISTRIES_INTERNAL_TOKEN")).To(Succeed())
+			})
+
+			it("logs which tokens are used but never their values", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Passing registry tokens from CARGO_REGISTRIES_INTERNAL_TOKEN to cargo"))
+				Expect(buffer.String()).NotTo(ContainSubstring("s3cr3t-internal"))
+				Expect(fmt.Sprintf("%v", result)).NotTo(ContainSubstring("s3cr3t-internal"))
+			})
+		})
+
+		context("when the build produces no binaries", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it("fails instead of shipping an empty launch layer", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("no binaries were produced, check BP_CARGO_FEATURES and the binary targets of the packages that were built"))
+			})
+		})
+
+		context("when the build phases take time", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_RUN_TESTS", "true")).To(Succeed())
+
+				now := time.Date(2021, 8, 1, 10, 30, 0, 0, time.UTC)
+				clock = chronos.NewClock(func() time.Time { return now })
+				build = cargo.Build(&mockRunner, &mockUPX, clock, scribe.NewEmitter(buffer))
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(mock.Arguments) {
+					now = now.Add(1200 * time.Millisecond)
+				}).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					installApp(args)
+					now = now.Add(42 * time.Second)
+				}).Return(nil)
+
+				mockRunner.On(
+					"Test",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(mock.Arguments) {
+					now = now.Add(3500 * time.Millisecond)
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_RUN_TESTS")).To(Succeed())
+			})
+
+			it("logs how long each phase and the whole build took", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("Fetched workspace members (1.2s)"))
+				Expect(buffer.String()).To(ContainSubstring("Installed binaries (42s)"))
+				Expect(buffer.String()).To(ContainSubstring("Ran tests (3.5s)"))
+				Expect(buffer.String()).To(ContainSubstring("Completed (46.7s)"))
+			})
+		})
+
+		context("when the clock is not in UTC", func() {
+			it.Before(func() {
+				now := time.Date(2021, 8, 1, 10, 30, 0, 5, time.FixedZone("UTC-7", -7*60*60))
+				clock = chronos.NewClock(func() time.Time { return now })
+				build = cargo.Build(&mockRunner, &mockUPX, clock, scribe.NewEmitter(buffer))
+
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(installApp).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_TIMESTAMP_FORMAT")).To(Succeed())
+			})
+
+			it("normalizes built_at to UTC", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[0].Metadata["built_at"]).To(Equal("2021-08-01T17:30:00.000000005Z"))
+				Expect(result.Layers[1].Metadata["built_at"]).To(Equal("2021-08-01T17:30:00.000000005Z"))
+			})
+
+			it("uses the configured format", func() {
+				Expect(os.Setenv("BP_CARGO_TIMESTAMP_FORMAT", "RFC3339")).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[0].Metadata["built_at"]).To(Equal("2021-08-01T17:30:00Z"))
+			})
+
+			it("rejects an unknown format", func() {
+				Expect(os.Setenv("BP_CARGO_TIMESTAMP_FORMAT", "iso")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("invalid BP_CARGO_TIMESTAMP_FORMAT \"iso\", must be one of `RFC3339Nano`, `RFC3339` or `unix`"))
+			})
+		})
+
+		context("when binaries are installed", func() {
+			it.Before(func() {
+				member, err := url.Parse("file:///workspace")
+				Expect(err).ToNot(HaveOccurred())
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*member}, nil)
+
+				mockRunner.On(
+					"Install",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Run(func(args mock.Arguments) {
+					binDir := filepath.Join(args.Get(2).(packit.Layer).Path, "bin")
+					Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("some-binary"), 0755)).To(Succeed())
+				}).Return(nil)
+
+				Expect(os.MkdirAll(filepath.Join(layersDir, "rust-cargo"), 0755)).ToNot(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_EMIT_CHECKSUMS")).To(Succeed())
+			})
+
+			it("records the checksum of each binary", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layersDir, "rust-bin", "bin", "app"))
+				Expect(err).NotTo(HaveOccurred())
+				sum := sha256.Sum256(content)
+
+				Expect(result.Layers[1].Metadata["binary_sha256"]).To(Equal(map[string]string{
+					"app": hex.EncodeToString(sum[:]),
+				}))
+				Expect(filepath.Join(layersDir, "rust-bin", "checksums.txt")).ToNot(BeAnExistingFile())
+			})
+
+			it("writes checksums.txt when requested", func() {
+				Expect(os.Setenv("BP_CARGO_EMIT_CHECKSUMS", "true")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				sum := sha256.Sum256([]byte("some-binary"))
+				Expect(ioutil.ReadFile(filepath.Join(layersDir, "rust-bin", "checksums.txt"))).To(Equal(
+					[]byte(fmt.Sprintf("%s  bin/app\n", hex.EncodeToString(sum[:])))))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the rust layer cannot be retrieved", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(layersDir, "rust-cargo.toml"), nil, 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "rust"},
+						},
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when BP_CARGO_BIN_LAYER_NAME is not a valid layer name", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_BIN_LAYER_NAME")).To(Succeed())
+			})
+
+			it("rejects names that aren't safe to use as a file name", func() {
+				Expect(os.Setenv("BP_CARGO_BIN_LAYER_NAME", "../bin")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`invalid value for BP_CARGO_BIN_LAYER_NAME "../bin", it must start with a letter or digit and only contain letters, digits, '.', '_' and '-'`))
+			})
+
+			it("rejects names of the other layers", func() {
+				Expect(os.Setenv("BP_CARGO_BIN_LAYER_NAME", "rust-cargo")).To(Succeed())
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`BP_CARGO_BIN_LAYER_NAME "rust-cargo" is already used by the buildpack or the lifecycle, choose another name`))
+			})
+		})
+
+		context("when BP_CARGO_DISABLE_CACHE and BP_CARGO_CACHE_ONLY are both set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_DISABLE_CACHE", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_CACHE_ONLY", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_DISABLE_CACHE")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_CACHE_ONLY")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("BP_CARGO_DISABLE_CACHE and BP_CARGO_CACHE_ONLY cannot both be set"))
+			})
+		})
+
+		context("when BP_CARGO_INSTALL_BINS lists a binary the project doesn't define", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_BINS", "app,migrate")).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_BINS")).To(Succeed())
+			})
+
+			it("fails before anything is built", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("binaries [migrate] in BP_CARGO_INSTALL_BINS are not defined by the project, the available binaries are [app]"))
+				mockRunner.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when a workspace member has no Cargo.toml", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"api\"]\n"), 0644)).To(Succeed())
+			})
+
+			it("fails before the members are resolved", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("have no Cargo.toml: api")))
+				mockRunner.AssertNotCalled(t, "WorkspaceMembers", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		context("when a member exceeds BP_CARGO_MEMBER_TIMEOUT", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_MEMBER_TIMEOUT", "10ms")).To(Succeed())
+
+				mockRunner := mocks.Runner{}
+				mockRunner.On("Version", workingDir, mock.Anything, mock.Anything).Return(rustVersion, nil).Maybe()
+				mockRunner.On("CheckToolchain").Return(nil).Maybe()
+				fast, err := url.Parse("file:///workspace/fast")
+				Expect(err).ToNot(HaveOccurred())
+				stuck, err := url.Parse("file:///workspace/stuck")
+				Expect(err).ToNot(HaveOccurred())
+
+				mockRunner.On(
+					"WorkspaceMembers",
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return([]url.URL{*fast, *stuck}, nil)
+
+				mockRunner.On(
+					"InstallMember",
+					mock.Anything,
+					fast.Path,
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(nil)
+
+				mockRunner.On(
+					"InstallMember",
+					mock.Anything,
+					stuck.Path,
+					workingDir,
+					mock.AnythingOfType("packit.Layer"),
+					mock.AnythingOfType("packit.Layer")).Return(func(ctx gocontext.Context, _ string, _ string, _ packit.Layer, _ packit.Layer) error {
+					<-ctx.Done()
+					return ctx.Err()
+				})
+
+				build = cargo.Build(&mockRunner, &mockUPX, clock, scribe.NewEmitter(buffer))
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_MEMBER_TIMEOUT")).To(Succeed())
+			})
+
+			it("returns an error naming the member", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("member /workspace/stuck did not finish building within BP_CARGO_MEMBER_TIMEOUT (10ms)")))
+				Expect(errors.Is(err, gocontext.DeadlineExceeded)).To(BeTrue())
+			})
+		})
+
+		context("when BP_CARGO_MEMBER_TIMEOUT is invalid", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_MEMBER_TIMEOUT", "forever")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_MEMBER_TIMEOUT")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid value for BP_CARGO_MEMBER_TIMEOUT")))
+			})
+		})
+
+		context("when BP_CARGO_STRICT_CONFIG is set and a variable is misspelled", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_STRICT_CONFIG", "true")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_INSTAL_ARGS", "--locked")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_STRICT_CONFIG")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_INSTAL_ARGS")).To(Succeed())
+			})
+
+			it("returns an error with a suggestion", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("BP_CARGO_INSTAL_ARGS (did you mean BP_CARGO_INSTALL_ARGS?)")))
 			})
 		})
 
 		context("cargo build fails", func() {
 			it.Before(func() {
 				mockRunner := mocks.Runner{}
+				mockRunner.On("Version", workingDir, mock.Anything, mock.Anything).Return(rustVersion, nil).Maybe()
+				mockRunner.On("CheckToolchain").Return(nil).Maybe()
 				mockRunner.On(
 					"Install",
 					workingDir,
@@ -303,7 +3852,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 				logger := scribe.NewEmitter(buffer)
 
-				build = cargo.Build(&mockRunner, clock, logger)
+				build = cargo.Build(&mockRunner, &mockUPX, clock, logger)
 			})
 
 			it("returns an error", func() {
@@ -325,6 +3874,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		context("cargo cannot fetch members", func() {
 			it.Before(func() {
 				mockRunner := mocks.Runner{}
+				mockRunner.On("Version", workingDir, mock.Anything, mock.Anything).Return(rustVersion, nil).Maybe()
+				mockRunner.On("CheckToolchain").Return(nil).Maybe()
 
 				mockRunner.On(
 					"WorkspaceMembers",
@@ -334,7 +3885,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 				logger := scribe.NewEmitter(buffer)
 
-				build = cargo.Build(&mockRunner, clock, logger)
+				build = cargo.Build(&mockRunner, &mockUPX, clock, logger)
 			})
 
 			it.After(func() {
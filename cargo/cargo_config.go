@@ -0,0 +1,200 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// CargoConfigFile is the name of the Cargo configuration file the buildpack manages in the Cargo home
+const CargoConfigFile = "config.toml"
+
+// HTTPConfig holds the `[http]` settings written to the Cargo configuration
+type HTTPConfig struct {
+	Multiplexing *bool `toml:"multiplexing,omitempty"`
+	Timeout      int   `toml:"timeout,omitzero"`
+}
+
+// CargoConfig is the Cargo configuration managed by the buildpack
+type CargoConfig struct {
+	HTTP *HTTPConfig `toml:"http,omitempty"`
+}
+
+// Environ returns the settings as the CARGO_HTTP_* variables that cargo reads, for when the configuration file can't
+// be written
+func (c HTTPConfig) Environ() []string {
+	var env []string
+	if c.Multiplexing != nil {
+		env = append(env, fmt.Sprintf("CARGO_HTTP_MULTIPLEXING=%t", *c.Multiplexing))
+	}
+	if c.Timeout > 0 {
+		env = append(env, fmt.Sprintf("CARGO_HTTP_TIMEOUT=%d", c.Timeout))
+	}
+	return env
+}
+
+// LoadHTTPConfig reads the `[http]` settings from BP_CARGO_HTTP_MULTIPLEXING and BP_CARGO_HTTP_TIMEOUT, returning
+// nil if neither is set
+func LoadHTTPConfig() (*HTTPConfig, error) {
+	var config HTTPConfig
+
+	if value := os.Getenv("BP_CARGO_HTTP_MULTIPLEXING"); value != "" {
+		multiplexing, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for BP_CARGO_HTTP_MULTIPLEXING: %w", err)
+		}
+		config.Multiplexing = &multiplexing
+	}
+
+	if value := os.Getenv("BP_CARGO_HTTP_TIMEOUT"); value != "" {
+		timeout, err := strconv.Atoi(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid value for BP_CARGO_HTTP_TIMEOUT %q, must be a positive number of seconds", value)
+		}
+		config.Timeout = timeout
+	}
+
+	if config.Multiplexing == nil && config.Timeout == 0 {
+		return nil, nil
+	}
+
+	return &config, nil
+}
+
+// WriteCargoConfig writes config to the Cargo home, removing a previously written file when there is nothing to
+// configure so that settings do not linger in the cached layer
+func WriteCargoConfig(cargoHome string, config CargoConfig) error {
+	path := filepath.Join(cargoHome, CargoConfigFile)
+
+	if config.HTTP == nil {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove %s\n%w", path, err)
+		}
+		return nil
+	}
+
+	err := os.MkdirAll(cargoHome, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	err = toml.NewEncoder(file).Encode(config)
+	if err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+type projectConfig struct {
+	Build struct {
+		Target    interface{} `toml:"target"`
+		RustFlags interface{} `toml:"rustflags"`
+	} `toml:"build"`
+}
+
+// findProjectConfig looks for the project's `.cargo/config.toml`, or the older `.cargo/config`, in srcDir and then
+// each of its parents like Cargo does. It returns the path of the first configuration that found is true for, or an
+// empty path if there is none.
+func findProjectConfig(srcDir string, found func(config projectConfig) bool) (string, projectConfig, error) {
+	dir, err := filepath.Abs(srcDir)
+	if err != nil {
+		return "", projectConfig{}, fmt.Errorf("unable to resolve %s\n%w", srcDir, err)
+	}
+
+	for {
+		for _, name := range []string{"config.toml", "config"} {
+			path := filepath.Join(dir, ".cargo", name)
+
+			var config projectConfig
+			_, err := toml.DecodeFile(path, &config)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return "", projectConfig{}, fmt.Errorf("unable to parse %s\n%w", path, err)
+			}
+
+			if found(config) {
+				return path, config, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", projectConfig{}, nil
+		}
+		dir = parent
+	}
+}
+
+// EnvironmentTarget returns the target set with BP_CARGO_TARGET, falling back to CARGO_BUILD_TARGET. It fails if both
+// are set to different targets.
+func EnvironmentTarget() (string, error) {
+	target := os.Getenv("BP_CARGO_TARGET")
+	cargoTarget := os.Getenv("CARGO_BUILD_TARGET")
+
+	if target != "" && cargoTarget != "" && target != cargoTarget {
+		return "", fmt.Errorf("BP_CARGO_TARGET is %q but CARGO_BUILD_TARGET is %q, unset one of them or set both to the same target", target, cargoTarget)
+	}
+
+	if target != "" {
+		return target, nil
+	}
+	return cargoTarget, nil
+}
+
+// BuildTarget returns the target binaries are built for. A target from the environment, see EnvironmentTarget, takes
+// precedence like it does for Cargo. Otherwise it is the `build.target` set in the project's `.cargo/config.toml`,
+// looking in srcDir and then each of its parents like Cargo does. An empty string means binaries are built for the
+// host.
+func BuildTarget(srcDir string) (string, error) {
+	target, err := EnvironmentTarget()
+	if err != nil {
+		return "", err
+	}
+
+	if target != "" {
+		return target, nil
+	}
+
+	path, config, err := findProjectConfig(srcDir, func(config projectConfig) bool {
+		return config.Build.Target != nil
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	switch target := config.Build.Target.(type) {
+	case string:
+		return target, nil
+	case []interface{}:
+		if len(target) == 1 {
+			if t, ok := target[0].(string); ok {
+				return t, nil
+			}
+		}
+		return "", fmt.Errorf("build.target in %s lists %d targets, only a single target is supported", path, len(target))
+	default:
+		return "", fmt.Errorf("build.target in %s must be a string", path)
+	}
+}
+
+// describeTarget names a build target for log messages, an empty target means the host
+func describeTarget(target string) string {
+	if target == "" {
+		return "the host"
+	}
+	return target
+}
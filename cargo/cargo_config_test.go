@@ -0,0 +1,182 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCargoConfig(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cargoHome string
+	)
+
+	it.Before(func() {
+		var err error
+		cargoHome, err = ioutil.TempDir("", "cargo-home")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cargoHome)).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_HTTP_MULTIPLEXING")).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_HTTP_TIMEOUT")).To(Succeed())
+	})
+
+	it("writes the [http] config with the configured values", func() {
+		Expect(os.Setenv("BP_CARGO_HTTP_MULTIPLEXING", "false")).To(Succeed())
+		Expect(os.Setenv("BP_CARGO_HTTP_TIMEOUT", "120")).To(Succeed())
+
+		config, err := cargo.LoadHTTPConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cargo.WriteCargoConfig(cargoHome, cargo.CargoConfig{HTTP: config})).To(Succeed())
+
+		Expect(ioutil.ReadFile(filepath.Join(cargoHome, "config.toml"))).To(Equal([]byte("[http]\n  multiplexing = false\n  timeout = 120\n")))
+	})
+
+	it("only writes the values that are set", func() {
+		Expect(os.Setenv("BP_CARGO_HTTP_MULTIPLEXING", "true")).To(Succeed())
+
+		config, err := cargo.LoadHTTPConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cargo.WriteCargoConfig(cargoHome, cargo.CargoConfig{HTTP: config})).To(Succeed())
+
+		Expect(ioutil.ReadFile(filepath.Join(cargoHome, "config.toml"))).To(Equal([]byte("[http]\n  multiplexing = true\n")))
+	})
+
+	it("removes a previously written config when nothing is set", func() {
+		Expect(ioutil.WriteFile(filepath.Join(cargoHome, "config.toml"), []byte("[http]\n"), 0644)).To(Succeed())
+
+		config, err := cargo.LoadHTTPConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(BeNil())
+		Expect(cargo.WriteCargoConfig(cargoHome, cargo.CargoConfig{HTTP: config})).To(Succeed())
+
+		Expect(filepath.Join(cargoHome, "config.toml")).ToNot(BeAnExistingFile())
+	})
+
+	it("rejects invalid values", func() {
+		Expect(os.Setenv("BP_CARGO_HTTP_TIMEOUT", "soon")).To(Succeed())
+
+		_, err := cargo.LoadHTTPConfig()
+		Expect(err).To(MatchError("invalid value for BP_CARGO_HTTP_TIMEOUT \"soon\", must be a positive number of seconds"))
+	})
+
+	context("reading build.target", func() {
+		it("reads the target from the project config", func() {
+			Expect(cargo.BuildTarget("testdata/build_target")).To(Equal("x86_64-unknown-linux-musl"))
+		})
+
+		it("looks in parent directories and the legacy config file", func() {
+			Expect(os.MkdirAll(filepath.Join(cargoHome, ".cargo"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(cargoHome, "project"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cargoHome, ".cargo", "config"), []byte("[build]\ntarget = [\"aarch64-unknown-linux-gnu\"]\n"), 0644)).To(Succeed())
+
+			Expect(cargo.BuildTarget(filepath.Join(cargoHome, "project"))).To(Equal("aarch64-unknown-linux-gnu"))
+		})
+
+		it("returns an empty target when none is configured", func() {
+			Expect(cargo.BuildTarget(cargoHome)).To(BeEmpty())
+		})
+
+		it("rejects multiple targets", func() {
+			Expect(os.MkdirAll(filepath.Join(cargoHome, ".cargo"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cargoHome, ".cargo", "config.toml"), []byte("[build]\ntarget = [\"a\", \"b\"]\n"), 0644)).To(Succeed())
+
+			_, err := cargo.BuildTarget(cargoHome)
+			Expect(err).To(MatchError(ContainSubstring("lists 2 targets, only a single target is supported")))
+		})
+
+		context("reading build.rustflags", func() {
+			it("splits a string of flags", func() {
+				Expect(os.MkdirAll(filepath.Join(cargoHome, ".cargo"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(cargoHome, ".cargo", "config.toml"), []byte("[build]\nrustflags = \"-C target-cpu=native  -C opt-level=2\"\n"), 0644)).To(Succeed())
+
+				flags, path, err := cargo.ProjectRustFlags(cargoHome)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flags).To(Equal([]string{"-C", "target-cpu=native", "-C", "opt-level=2"}))
+				Expect(path).To(Equal(filepath.Join(cargoHome, ".cargo", "config.toml")))
+			})
+
+			it("reads a list of flags from a parent directory", func() {
+				Expect(os.MkdirAll(filepath.Join(cargoHome, ".cargo"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(cargoHome, "project"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(cargoHome, ".cargo", "config"), []byte("[build]\nrustflags = [\"-Ctarget-cpu=native\"]\n"), 0644)).To(Succeed())
+
+				flags, _, err := cargo.ProjectRustFlags(filepath.Join(cargoHome, "project"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flags).To(Equal([]string{"-Ctarget-cpu=native"}))
+			})
+
+			it("returns no flags when none are configured", func() {
+				flags, path, err := cargo.ProjectRustFlags(cargoHome)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flags).To(BeEmpty())
+				Expect(path).To(BeEmpty())
+			})
+
+			it("rejects flags that aren't strings", func() {
+				Expect(os.MkdirAll(filepath.Join(cargoHome, ".cargo"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(cargoHome, ".cargo", "config.toml"), []byte("[build]\nrustflags = [1]\n"), 0644)).To(Succeed())
+
+				_, _, err := cargo.ProjectRustFlags(cargoHome)
+				Expect(err).To(MatchError(ContainSubstring("must be a string or a list of strings")))
+			})
+
+			it("finds codegen options that are set to different values", func() {
+				project := []string{"-C", "target-cpu=native", "-Copt-level=2", "-C", "debuginfo=1"}
+				flags := []string{"--codegen=opt-level=3", "-C", "target-cpu=native", "-C", "lto"}
+
+				Expect(cargo.RustFlagConflicts(project, flags)).To(Equal([]string{"opt-level"}))
+				Expect(cargo.RustFlagConflicts(project, []string{"-C", "debuginfo=2", "-C", "target-cpu=x86-64"})).To(Equal([]string{"debuginfo", "target-cpu"}))
+				Expect(cargo.RustFlagConflicts(nil, flags)).To(BeEmpty())
+			})
+		})
+	})
+
+	context("when the target is set in the environment", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			Expect(os.Unsetenv("CARGO_BUILD_TARGET")).To(Succeed())
+		})
+
+		it("falls back to CARGO_BUILD_TARGET", func() {
+			Expect(os.Setenv("CARGO_BUILD_TARGET", "aarch64-unknown-linux-gnu")).To(Succeed())
+
+			Expect(cargo.EnvironmentTarget()).To(Equal("aarch64-unknown-linux-gnu"))
+			Expect(cargo.BuildTarget("testdata/build_target")).To(Equal("aarch64-unknown-linux-gnu"))
+		})
+
+		it("prefers BP_CARGO_TARGET", func() {
+			Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-gnu")).To(Succeed())
+
+			Expect(cargo.BuildTarget("testdata/build_target")).To(Equal("x86_64-unknown-linux-gnu"))
+		})
+
+		it("accepts both when they agree", func() {
+			Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-gnu")).To(Succeed())
+			Expect(os.Setenv("CARGO_BUILD_TARGET", "x86_64-unknown-linux-gnu")).To(Succeed())
+
+			Expect(cargo.EnvironmentTarget()).To(Equal("x86_64-unknown-linux-gnu"))
+		})
+
+		it("fails when they conflict", func() {
+			Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-gnu")).To(Succeed())
+			Expect(os.Setenv("CARGO_BUILD_TARGET", "aarch64-unknown-linux-gnu")).To(Succeed())
+
+			_, err := cargo.EnvironmentTarget()
+			Expect(err).To(MatchError(`BP_CARGO_TARGET is "x86_64-unknown-linux-gnu" but CARGO_BUILD_TARGET is "aarch64-unknown-linux-gnu", unset one of them or set both to the same target`))
+
+			_, err = cargo.BuildTarget("testdata/build_target")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
@@ -2,16 +2,21 @@ package cargo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
-	"path"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/mattn/go-shellwords"
 	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/fs"
 	"github.com/paketo-buildpacks/packit/pexec"
 	"github.com/paketo-buildpacks/packit/scribe"
 )
@@ -26,6 +31,8 @@ type Executable interface {
 // CLIRunner can execute cargo via CLI
 type CLIRunner struct {
 	exec   Executable
+	rustup Executable
+	rustc  Executable
 	logger scribe.Emitter
 }
 
@@ -33,105 +40,1185 @@ type CLIRunner struct {
 func NewCLIRunner(exec Executable, logger scribe.Emitter) CLIRunner {
 	return CLIRunner{
 		exec:   exec,
+		rustup: NewCommandExecutable("rustup"),
+		rustc:  NewCommandExecutable("rustc"),
 		logger: logger,
 	}
 }
 
-func createEnviron(workLayer packit.Layer, destLayer packit.Layer) []string {
+// WithRustup returns a copy of the runner that uses rustup to manage toolchain components
+func (c CLIRunner) WithRustup(rustup Executable) CLIRunner {
+	c.rustup = rustup
+	return c
+}
+
+// WithRustc returns a copy of the runner that uses rustc to read the compiler version
+func (c CLIRunner) WithRustc(rustc Executable) CLIRunner {
+	c.rustc = rustc
+	return c
+}
+
+// CheckToolchain fails when cargo or rustc can't be found on the PATH, which happens when the buildpack that
+// provides Rust did not run
+func (c CLIRunner) CheckToolchain() error {
+	for _, tool := range []string{"cargo", "rustc"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s not found on PATH, ensure that the Rust distribution buildpack ran before this one\n%w", tool, err)
+		}
+	}
+
+	return nil
+}
+
+// Version returns the output of `cargo --version` and `rustc --version` for the toolchain used in workingDir,
+// like `cargo 1.56.0 (4ed5d137b 2021-10-04), rustc 1.56.0 (09c42c458 2021-10-18)`
+func (c CLIRunner) Version(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) (string, error) {
+	env, err := createEnviron(workingDir, cargoLayer, binLayer)
+	if err != nil {
+		return "", err
+	}
+
+	var versions []string
+	for _, tool := range []struct {
+		name string
+		exec Executable
+	}{{"cargo", c.exec}, {"rustc", c.rustc}} {
+		stdout := bytes.Buffer{}
+		err := tool.exec.Execute(pexec.Execution{
+			Dir:    workingDir,
+			Stdout: &stdout,
+			Stderr: &stdout,
+			Env:    env,
+			Args:   []string{"--version"},
+		})
+		if err != nil {
+			return "", fmt.Errorf("%s --version failed: %w", tool.name, err)
+		}
+
+		versions = append(versions, strings.TrimSpace(stdout.String()))
+	}
+
+	return strings.Join(versions, ", "), nil
+}
+
+// TargetInstalled checks if the standard library for target is installed in the toolchain used in srcDir, by
+// looking for the directory that `rustc --print target-libdir` reports
+func (c CLIRunner) TargetInstalled(srcDir string, target string) (bool, error) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	err := c.rustc.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"--print", "target-libdir", "--target", target},
+	})
+	if err != nil {
+		return false, fmt.Errorf("rustc --print target-libdir failed: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	libDir := strings.TrimSpace(stdout.String())
+	info, err := os.Stat(libDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to stat %s\n%w", libDir, err)
+	}
+
+	return info.IsDir(), nil
+}
+
+// EnsureComponents installs any of the rustup components that are missing from the toolchain used in srcDir. If
+// rustup is not available, the components cannot be checked and a warning is logged.
+func (c CLIRunner) EnsureComponents(srcDir string, components []string) error {
+	stdout := bytes.Buffer{}
+	err := c.rustup.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: &stdout,
+		Stderr: scribe.NewWriter(os.Stderr, scribe.WithIndent(5)),
+		Args:   []string{"component", "list", "--installed"},
+	})
+	if errors.Is(err, exec.ErrNotFound) {
+		c.logger.Subprocess("WARNING: rustup not found on PATH, unable to check toolchain components [%s]", strings.Join(components, ", "))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list rustup components: %w", err)
+	}
+
+	installed := strings.Fields(stdout.String())
+
+	var missing []string
+	for _, component := range components {
+		found := false
+		for _, i := range installed {
+			// installed components are suffixed with the target triple, like `clippy-x86_64-unknown-linux-gnu`
+			if i == component || strings.HasPrefix(i, component+"-") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, component)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	offline, err := ParseBoolEnv("BP_CARGO_OFFLINE")
+	if err != nil {
+		return err
+	}
+
+	if offline {
+		c.logger.Subprocess("WARNING: toolchain components [%s] are missing and are not installed because BP_CARGO_OFFLINE is set", strings.Join(missing, ", "))
+		return nil
+	}
+
+	args := append([]string{"component", "add"}, missing...)
+	c.logger.Subprocess("Installing toolchain components [%s]", strings.Join(missing, ", "))
+	c.logger.Detail("rustup %s", strings.Join(args, " "))
+	err = c.rustup.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: scribe.NewWriter(os.Stdout, scribe.WithIndent(5)),
+		Stderr: scribe.NewWriter(os.Stderr, scribe.WithIndent(5)),
+		Args:   args,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to install toolchain components [%s] listed in rust-toolchain.toml, use a Rust toolchain that includes them or remove them from the components list\n%w",
+			strings.Join(missing, ", "), err)
+	}
+
+	return nil
+}
+
+// CleanEnvironAllowlist is the set of host environment variables passed to cargo when BP_CARGO_CLEAN_ENV is set
+var CleanEnvironAllowlist = []string{
+	"PATH",
+	"HOME",
+	"CARGO_HOME",
+	"RUSTUP_HOME",
+	"SSL_CERT_FILE",
+	"SSL_CERT_DIR",
+}
+
+// baseEnviron is the environment of the build, reduced to the allowed variables when BP_CARGO_CLEAN_ENV is set
+func baseEnviron() ([]string, error) {
 	env := os.Environ()
-	env = append(env, fmt.Sprintf("CARGO_TARGET_DIR=%s", path.Join(workLayer.Path, "target")))
-	env = append(env, fmt.Sprintf("CARGO_HOME=%s", path.Join(workLayer.Path, "home")))
 
-	for i := 0; i < len(env); i++ {
-		if strings.HasPrefix(env[i], "PATH=") {
-			env[i] = fmt.Sprintf("%s%c%s", env[i], os.PathListSeparator, filepath.Join(destLayer.Path, "bin"))
+	clean, err := ParseBoolEnv("BP_CARGO_CLEAN_ENV")
+	if err != nil {
+		return nil, err
+	}
+
+	if clean {
+		var allowed []string
+		for _, e := range env {
+			name := strings.SplitN(e, "=", 2)[0]
+			// registry tokens are kept so that cargo can authenticate without a file based binding
+			if contains(CleanEnvironAllowlist, name) || registryToken.MatchString(name) {
+				allowed = append(allowed, e)
+			}
+		}
+		env = allowed
+	}
+
+	return env, nil
+}
+
+func createEnviron(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	env, err := baseEnviron()
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := EnvironmentTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	// BP_CARGO_TARGET is passed on to cargo, and CARGO_BUILD_TARGET is kept even when the environment is cleaned
+	if target != "" && !containsEnv(env, "CARGO_BUILD_TARGET") {
+		env = append(env, fmt.Sprintf("CARGO_BUILD_TARGET=%s", target))
+	}
+
+	// the target may also come from the project's .cargo/config.toml
+	buildTarget, err := BuildTarget(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	env = muslEnviron(env, buildTarget)
+
+	color, err := ColorMode()
+	if err != nil {
+		return nil, err
+	}
+
+	offline, err := ParseBoolEnv("BP_CARGO_OFFLINE")
+	if err != nil {
+		return nil, err
+	}
+
+	// keeps commands that are not passed --offline, like `cargo metadata`, off the network too
+	if offline && !containsEnv(env, "CARGO_NET_OFFLINE") {
+		env = append(env, "CARGO_NET_OFFLINE=true")
+	}
+
+	// the color mode also applies to cargo commands that don't take --color, like `cargo metadata`
+	env = append(env, fmt.Sprintf("CARGO_TERM_COLOR=%s", color))
+	env = append(env, fmt.Sprintf("CARGO_TARGET_DIR=%s", targetDir(workLayer)))
+	env = append(env, fmt.Sprintf("CARGO_HOME=%s", CargoHome(workLayer)))
+
+	// the configuration file is only written to the cargo home that the buildpack manages
+	if os.Getenv("BP_CARGO_HOME") != "" {
+		httpConfig, err := LoadHTTPConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		if httpConfig != nil {
+			env = append(env, httpConfig.Environ()...)
+		}
+	}
+
+	env, err = ensureWritableHome(env, workLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	sccache, err := SccacheEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	if sccache {
+		env = append(env, "RUSTC_WRAPPER=sccache", fmt.Sprintf("SCCACHE_DIR=%s", sccacheDir(workLayer)))
+	}
+
+	for i := 0; i < len(env); i++ {
+		if strings.HasPrefix(env[i], "PATH=") {
+			env[i] = fmt.Sprintf("%s%c%s", env[i], os.PathListSeparator, filepath.Join(destLayer.Path, "bin"))
+		}
+	}
+
+	useJobserver, err := ParseBoolEnv("BP_CARGO_USE_JOBSERVER")
+	if err != nil {
+		return nil, err
+	}
+
+	if flags := JobserverFlags(); useJobserver && flags != "" {
+		env = append(env, fmt.Sprintf("CARGO_MAKEFLAGS=%s", flags))
+	}
+
+	return applyRustFlags(srcDir, env)
+}
+
+// containsEnv reports whether the variable name is set in env
+func containsEnv(env []string, name string) bool {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// appendRustFlags adds flags to the end of RUSTFLAGS in env, setting it if it is not present
+func appendRustFlags(env []string, flags string) []string {
+	for i, e := range env {
+		if strings.HasPrefix(e, "RUSTFLAGS=") {
+			if existing := strings.TrimPrefix(e, "RUSTFLAGS="); existing != "" {
+				env[i] = fmt.Sprintf("RUSTFLAGS=%s %s", existing, flags)
+			} else {
+				env[i] = fmt.Sprintf("RUSTFLAGS=%s", flags)
+			}
+			return env
+		}
+	}
+	return append(env, fmt.Sprintf("RUSTFLAGS=%s", flags))
+}
+
+// Install will build and install the project using `cargo install`
+func (c CLIRunner) Install(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	return c.InstallMember(context.Background(), ".", srcDir, workLayer, destLayer)
+}
+
+// Examples returns the examples in srcDir that BP_CARGO_INSTALL_EXAMPLES selects. It fails when a listed example
+// is not in any package, or when an example has the same name as a binary.
+func (c CLIRunner) Examples(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	requested := RequestedExamples()
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	m, err := c.metadata(srcDir, workLayer, destLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	// no package has an empty manifest path, so every package is searched
+	available := m.Examples("")
+	for _, name := range requested {
+		if name != AllExamples && !contains(available, name) {
+			return nil, fmt.Errorf("example %s in BP_CARGO_INSTALL_EXAMPLES is not an example of any package", name)
+		}
+	}
+
+	return SelectExamples(requested, available, m.Binaries(""))
+}
+
+// memberExamples returns the examples of the package with manifestPath that BP_CARGO_INSTALL_EXAMPLES selects,
+// examples of other workspace members are left to their own install
+func (c CLIRunner) memberExamples(srcDir string, manifestPath string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	requested := RequestedExamples()
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	m, err := c.metadata(srcDir, workLayer, destLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath = filepath.Clean(manifestPath)
+	return SelectExamples(requested, m.Examples(manifestPath), m.Binaries(manifestPath))
+}
+
+// memberBins picks the binaries that BP_CARGO_INSTALL_BINS selects out of the package of manifestPath. Asking cargo
+// for a binary that a package doesn't have fails, so each workspace member is only given its own binaries.
+func memberBins(manifestPath string) ([]string, error) {
+	requested := RequestedBins()
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	available, err := PackageBinaries(filepath.Dir(manifestPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return SelectBins(requested, available), nil
+}
+
+// Fetch downloads the project's dependencies into cargo home with `cargo fetch`. It can run while other work is
+// going on, so its output is kept and only shown when it fails.
+func (c CLIRunner) Fetch(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	env, err := createEnviron(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	output := bytes.Buffer{}
+	err = c.exec.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: &output,
+		Stderr: &output,
+		Env:    env,
+		Args:   append([]string{"fetch", "--color=never"}, manifestPathArgs(srcDir)...),
+	})
+	if err != nil {
+		return fmt.Errorf("fetch failed: %w\n%s", err, Redact(output.String(), env))
+	}
+
+	return nil
+}
+
+// Test runs `cargo test` in srcDir with the profile and features that the binaries are built with, streaming the
+// output so that failing tests are visible in the build log
+func (c CLIRunner) Test(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	args, err := c.TestArgs(srcDir)
+	if err != nil {
+		return err
+	}
+
+	env, err := createEnviron(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Process("Running tests")
+	c.logger.Subprocess("cargo %s", Redact(strings.Join(args, " "), env))
+	stdout := redactTokens(scribe.NewWriter(os.Stdout, scribe.WithIndent(5)), env)
+	stderr := redactTokens(scribe.NewWriter(os.Stderr, scribe.WithIndent(5)), env)
+	err = c.exec.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: stdout,
+		Stderr: stderr,
+		Env:    env,
+		Args:   args,
+	})
+	flushRedacted(stdout, stderr)
+	c.logger.Break()
+	if err != nil {
+		return fmt.Errorf("tests failed: %w", err)
+	}
+
+	return c.CleanCargoHomeCache(workLayer)
+}
+
+// Clippy runs `cargo clippy` in srcDir with the profile and features that the binaries are built with, streaming the
+// output. Warnings only fail it when BP_CARGO_CLIPPY_DENY_WARNINGS is set.
+func (c CLIRunner) Clippy(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	args, err := c.ClippyArgs(srcDir)
+	if err != nil {
+		return err
+	}
+
+	env, err := createEnviron(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Process("Running clippy")
+
+	// without the clippy component, cargo only fails with `no such command`
+	output := bytes.Buffer{}
+	err = c.exec.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: &output,
+		Stderr: &output,
+		Env:    env,
+		Args:   []string{"clippy", "--version"},
+	})
+	if err != nil {
+		c.logger.Break()
+		return fmt.Errorf("clippy is not installed, add `clippy` to `components` in rust-toolchain.toml\n%w", err)
+	}
+
+	c.logger.Subprocess("cargo %s", Redact(strings.Join(args, " "), env))
+	stdout := redactTokens(scribe.NewWriter(os.Stdout, scribe.WithIndent(5)), env)
+	stderr := redactTokens(scribe.NewWriter(os.Stderr, scribe.WithIndent(5)), env)
+	err = c.exec.Execute(pexec.Execution{
+		Dir:    srcDir,
+		Stdout: stdout,
+		Stderr: stderr,
+		Env:    env,
+		Args:   args,
+	})
+	flushRedacted(stdout, stderr)
+	c.logger.Break()
+	if err != nil {
+		return fmt.Errorf("clippy failed: %w", err)
+	}
+
+	return nil
+}
+
+// Clean runs `cargo clean` on the rust-target layer, which is the target directory that cargo builds into. Nothing is
+// run when the layer is empty.
+func (c CLIRunner) Clean(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error {
+	dir := targetDir(cargoLayer)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) || err == nil && len(entries) == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	env, err := createEnviron(workingDir, cargoLayer, binLayer)
+	if err != nil {
+		return err
+	}
+
+	output := bytes.Buffer{}
+	err = c.exec.Execute(pexec.Execution{
+		Dir:    workingDir,
+		Stdout: &output,
+		Stderr: &output,
+		Env:    env,
+		Args:   append([]string{"clean", fmt.Sprintf("--target-dir=%s", dir), "--color=never"}, manifestPathArgs(workingDir)...),
+	})
+	if err != nil {
+		return fmt.Errorf("clean failed: %w\n%s", err, Redact(output.String(), env))
+	}
+
+	return nil
+}
+
+// InstallMethod returns the configured way of installing binaries, either `install` (the default) or `build`
+func InstallMethod() (string, error) {
+	method := os.Getenv("BP_CARGO_INSTALL_METHOD")
+	switch method {
+	case "", "install":
+		return "install", nil
+	case "build":
+		return "build", nil
+	default:
+		return "", fmt.Errorf("invalid BP_CARGO_INSTALL_METHOD %q, must be `install` or `build`", method)
+	}
+}
+
+// InstallMember will build and install a specific workspace member using `cargo install`, or `cargo build`
+// if BP_CARGO_INSTALL_METHOD is `build`. The build is abandoned with ctx's error if ctx is done first.
+func (c CLIRunner) InstallMember(ctx context.Context, memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	method, err := InstallMethod()
+	if err != nil {
+		return err
+	}
+
+	if method == "build" {
+		return c.BuildMember(ctx, memberPath, srcDir, workLayer, destLayer)
+	}
+
+	args, err := c.installCommand(memberPath, srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	if args == nil {
+		c.logger.Detail("Skipping %s, it has none of the binaries in BP_CARGO_INSTALL_BINS", memberPath)
+		return nil
+	}
+
+	env, err := createEnviron(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Detail("cargo %s", Redact(strings.Join(args, " "), env))
+	stdout := redactTokens(scribe.NewWriter(os.Stdout, scribe.WithIndent(5)), env)
+	stderr := redactTokens(scribe.NewWriter(os.Stderr, scribe.WithIndent(5)), env)
+	err = c.executeWithRetry(ctx, pexec.Execution{
+		Dir:    srcDir,
+		Stdout: stdout,
+		Stderr: stderr,
+		Env:    env,
+		Args:   args,
+	})
+	flushRedacted(stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	err = c.CleanCargoHomeCache(workLayer)
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+	return nil
+}
+
+// InstallCommand returns the arguments that InstallMember passes to cargo for memberPath, without running cargo. No
+// arguments are returned for a member that would be skipped.
+func (c CLIRunner) InstallCommand(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	method, err := InstallMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "build" {
+		args, _, _, _, err := c.compileCommand(memberPath, srcDir, workLayer, destLayer)
+		return args, err
+	}
+
+	return c.installCommand(memberPath, srcDir, workLayer, destLayer)
+}
+
+// installCommand resolves the arguments of `cargo install` for memberPath, or none if the member has none of the
+// binaries in BP_CARGO_INSTALL_BINS
+func (c CLIRunner) installCommand(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	args, err := c.BuildArgs(destLayer, memberPath)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := featureArgs(memberPath, srcDir)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, features...)
+
+	manifestPath := filepath.Join(memberPath, "Cargo.toml")
+	if !filepath.IsAbs(manifestPath) {
+		manifestPath = filepath.Join(srcDir, manifestPath)
+	}
+
+	bins, err := memberBins(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(RequestedBins()) > 0 && len(bins) == 0 {
+		return nil, nil
+	}
+	args = append(args, BinArgs(bins)...)
+
+	examples, err := c.memberExamples(srcDir, manifestPath, workLayer, destLayer)
+	if err != nil {
+		return nil, err
+	}
+	return append(args, ExampleArgs(args, examples)...), nil
+}
+
+// compileCommand resolves the arguments of `cargo build` for memberPath, along with the manifest it builds and the
+// binaries and examples that were selected. No arguments are returned if the member has none of the binaries in
+// BP_CARGO_INSTALL_BINS.
+func (c CLIRunner) compileCommand(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, string, []string, []string, error) {
+	args, manifestPath, err := c.CompileArgs(memberPath)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	features, err := featureArgs(memberPath, srcDir)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	args = append(args, features...)
+
+	if !filepath.IsAbs(manifestPath) {
+		manifestPath = filepath.Join(srcDir, manifestPath)
+	}
+
+	bins, err := memberBins(manifestPath)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	if len(RequestedBins()) > 0 && len(bins) == 0 {
+		return nil, manifestPath, nil, nil, nil
+	}
+	args = append(args, BinArgs(bins)...)
+
+	examples, err := c.memberExamples(srcDir, manifestPath, workLayer, destLayer)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	return append(args, ExampleArgs(args, examples)...), manifestPath, bins, examples, nil
+}
+
+// BuildMember will build a specific workspace member using `cargo build` and copy the resulting binaries into
+// the bin directory of destLayer
+func (c CLIRunner) BuildMember(ctx context.Context, memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	args, manifestPath, bins, examples, err := c.compileCommand(memberPath, srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	if args == nil {
+		c.logger.Detail("Skipping %s, it has none of the binaries in BP_CARGO_INSTALL_BINS", memberPath)
+		return nil
+	}
+
+	env, err := createEnviron(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Detail("cargo %s", Redact(strings.Join(args, " "), env))
+	stdout := redactTokens(scribe.NewWriter(os.Stdout, scribe.WithIndent(5)), env)
+	stderr := redactTokens(scribe.NewWriter(os.Stderr, scribe.WithIndent(5)), env)
+	err = c.executeWithRetry(ctx, pexec.Execution{
+		Dir:    srcDir,
+		Stdout: stdout,
+		Stderr: stderr,
+		Env:    env,
+		Args:   args,
+	})
+	flushRedacted(stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	m, err := c.metadata(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	buildTarget, err := BuildTarget(srcDir)
+	if err != nil {
+		return err
+	}
+
+	// with a default target set, cargo puts binaries in a directory named after the target triple
+	releaseDir := filepath.Join(m.TargetDirectory, buildTarget, ProfileDir(ProfileName()))
+
+	binDir := filepath.Join(destLayer.Path, "bin")
+	err = os.MkdirAll(binDir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	built := m.Binaries(filepath.Clean(manifestPath))
+	if len(bins) > 0 {
+		built = SelectBins(bins, built)
+	}
+
+	for _, name := range built {
+		binPath := filepath.Join(releaseDir, name)
+		if _, err := os.Stat(binPath); os.IsNotExist(err) {
+			c.logger.Detail("Binary %s was not built, skipping", name)
+			continue
+		}
+
+		c.logger.Detail("Copying %s to %s", binPath, binDir)
+		err = fs.Copy(binPath, filepath.Join(binDir, name))
+		if err != nil {
+			return fmt.Errorf("unable to copy binary %s\n%w", name, err)
+		}
+	}
+
+	for _, name := range examples {
+		examplePath := filepath.Join(releaseDir, "examples", name)
+		c.logger.Detail("Copying %s to %s", examplePath, binDir)
+		err = fs.Copy(examplePath, filepath.Join(binDir, name))
+		if err != nil {
+			return fmt.Errorf("unable to copy example %s\n%w", name, err)
+		}
+	}
+
+	err = c.CleanCargoHomeCache(workLayer)
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+	return nil
+}
+
+// execute runs the execution, stopping it and returning ctx's error if ctx is done before it completes. Executables
+// that can't be stopped, which are only test doubles, are left to finish in the background.
+func execute(ctx context.Context, exec Executable, execution pexec.Execution) error {
+	if ctx.Done() == nil {
+		return exec.Execute(execution)
+	}
+
+	if stoppable, ok := exec.(ContextExecutable); ok {
+		return stoppable.ExecuteContext(ctx, execution)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Execute(execution)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// registryFailure matches the errors Cargo reports when it is unable to reach or update a registry index. Errors
+// like `failed to get` or `failed to load` are left out, cargo reports problems with manifests and path
+// dependencies with them too.
+var registryFailure = regexp.MustCompile(`failed to update registry|failed to query replaced source registry|download of \S+ failed|` +
+	`failed to download from|index\.crates\.io|github\.com/rust-lang/crates\.io-index`)
+
+// RegistryProtocol returns the protocol Cargo will use for crates.io given env, `sparse` unless
+// CARGO_REGISTRIES_CRATES_IO_PROTOCOL says otherwise
+func RegistryProtocol(env []string) string {
+	for _, e := range env {
+		if strings.HasPrefix(e, "CARGO_REGISTRIES_CRATES_IO_PROTOCOL=") {
+			return strings.TrimPrefix(e, "CARGO_REGISTRIES_CRATES_IO_PROTOCOL=")
+		}
+	}
+	return "sparse"
+}
+
+// executeWithFallback runs cargo and, if BP_CARGO_REGISTRY_PROTOCOL_FALLBACK is enabled and cargo failed to
+// resolve dependencies from crates.io, retries once using the other registry protocol
+func (c CLIRunner) executeWithFallback(ctx context.Context, execution pexec.Execution) error {
+	fallback, err := ParseBoolEnv("BP_CARGO_REGISTRY_PROTOCOL_FALLBACK")
+	if err != nil {
+		return err
+	}
+
+	if !fallback {
+		return execute(ctx, c.exec, execution)
+	}
+
+	stderr := execution.Stderr
+	output := bytes.Buffer{}
+	execution.Stderr = io.MultiWriter(stderr, &output)
+
+	err = execute(ctx, c.exec, execution)
+	if err == nil || ctx.Err() != nil || !registryFailure.Match(output.Bytes()) {
+		return err
+	}
+
+	primary := RegistryProtocol(execution.Env)
+	other := "sparse"
+	if primary == "sparse" {
+		other = "git"
+	}
+
+	c.logger.Subprocess("Resolving dependencies with the %s registry protocol failed, retrying with the %s protocol", primary, other)
+
+	var env []string
+	for _, e := range execution.Env {
+		if !strings.HasPrefix(e, "CARGO_REGISTRIES_CRATES_IO_PROTOCOL=") {
+			env = append(env, e)
+		}
+	}
+	execution.Env = append(env, fmt.Sprintf("CARGO_REGISTRIES_CRATES_IO_PROTOCOL=%s", other))
+	execution.Stderr = stderr
+
+	return execute(ctx, c.exec, execution)
+}
+
+// RetryPatterns returns the regular expressions listed, comma delimited, in BP_CARGO_RETRY_PATTERNS
+func RetryPatterns() ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, expr := range ParseListEnv("BP_CARGO_RETRY_PATTERNS") {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in BP_CARGO_RETRY_PATTERNS\n%w", expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// executeWithRetry runs cargo like executeWithFallback and, if it fails with output that matches one of
+// BP_CARGO_RETRY_PATTERNS, runs it once more. Everything cargo built before failing is kept for the retry.
+func (c CLIRunner) executeWithRetry(ctx context.Context, execution pexec.Execution) error {
+	patterns, err := RetryPatterns()
+	if err != nil {
+		return err
+	}
+
+	if len(patterns) == 0 {
+		return c.executeWithFallback(ctx, execution)
+	}
+
+	stdout, stderr := execution.Stdout, execution.Stderr
+	output := bytes.Buffer{}
+	execution.Stdout = io.MultiWriter(stdout, &output)
+	execution.Stderr = io.MultiWriter(stderr, &output)
+
+	err = c.executeWithFallback(ctx, execution)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	for _, pattern := range patterns {
+		if pattern.Match(output.Bytes()) {
+			c.logger.Subprocess("Build failed with output matching %q from BP_CARGO_RETRY_PATTERNS, retrying once", pattern.String())
+			execution.Stdout, execution.Stderr = stdout, stderr
+			return c.executeWithFallback(ctx, execution)
 		}
 	}
 
-	return env
+	return err
 }
 
-// Install will build and install the project using `cargo install`
-func (c CLIRunner) Install(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
-	return c.InstallMember(".", srcDir, workLayer, destLayer)
+type target struct {
+	Kind []string `json:"kind"`
+	Name string   `json:"name"`
 }
 
-// InstallMember will build and install a specific workspace member using `cargo install`
-func (c CLIRunner) InstallMember(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
-	args, err := c.BuildArgs(destLayer, memberPath)
+type metadataDependency struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Req    string `json:"req"`
+}
+
+type metadataPackage struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	Version      string               `json:"version"`
+	ManifestPath string               `json:"manifest_path"`
+	Targets      []target             `json:"targets"`
+	Dependencies []metadataDependency `json:"dependencies"`
+	Publish      *[]string            `json:"publish"`
+}
+
+// unpublished is true when the package sets `publish = false`, which cargo metadata reports as an empty list of
+// registries
+func (p metadataPackage) unpublished() bool {
+	return p.Publish != nil && len(*p.Publish) == 0
+}
+
+type metadata struct {
+	Packages         []metadataPackage `json:"packages"`
+	WorkspaceMembers []string          `json:"workspace_members"`
+	TargetDirectory  string            `json:"target_directory"`
+	Resolve          *resolve          `json:"resolve"`
+}
+
+// Binaries lists the binary targets for the package with the given manifest, or all packages if the manifest
+// does not belong to a package (i.e. it is a virtual workspace manifest)
+func (m metadata) Binaries(manifestPath string) []string {
+	return m.targetNames(manifestPath, "bin")
+}
+
+// Examples lists the example targets in the same way as Binaries
+func (m metadata) Examples(manifestPath string) []string {
+	return m.targetNames(manifestPath, "example")
+}
+
+func (m metadata) targetNames(manifestPath string, targetKind string) []string {
+	packages := m.Packages
+	for _, pkg := range m.Packages {
+		if pkg.ManifestPath == manifestPath {
+			packages = []metadataPackage{pkg}
+			break
+		}
+	}
+
+	var names []string
+	for _, pkg := range packages {
+		for _, t := range pkg.Targets {
+			for _, kind := range t.Kind {
+				if kind == targetKind {
+					names = append(names, t.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func (c CLIRunner) metadata(srcDir string, workLayer packit.Layer, destLayer packit.Layer) (metadata, error) {
+	return c.readMetadata(srcDir, workLayer, destLayer, "--no-deps")
+}
+
+func (c CLIRunner) readMetadata(srcDir string, workLayer packit.Layer, destLayer packit.Layer, extraArgs ...string) (metadata, error) {
+	env, err := createEnviron(srcDir, workLayer, destLayer)
 	if err != nil {
-		return err
+		return metadata{}, err
 	}
 
-	c.logger.Detail("cargo %s", strings.Join(args, " "))
+	stdout := bytes.Buffer{}
 	err = c.exec.Execute(pexec.Execution{
 		Dir:    srcDir,
-		Stdout: scribe.NewWriter(os.Stdout, scribe.WithIndent(5)),
-		Stderr: scribe.NewWriter(os.Stderr, scribe.WithIndent(5)),
-		Env:    createEnviron(workLayer, destLayer),
-		Args:   args,
+		Stdout: &stdout,
+		Env:    env,
+		Args:   append(append([]string{"metadata", "--format-version=1"}, manifestPathArgs(srcDir)...), extraArgs...),
 	})
 	if err != nil {
-		return fmt.Errorf("build failed: %w", err)
+		return metadata{}, fmt.Errorf("build failed: %w", err)
 	}
 
-	err = c.CleanCargoHomeCache(workLayer)
+	var m metadata
+	err = json.Unmarshal(stdout.Bytes(), &m)
 	if err != nil {
-		return fmt.Errorf("cleanup failed: %w", err)
+		return metadata{}, fmt.Errorf("unable to parse Cargo metadata: %w", err)
 	}
-	return nil
+
+	return m, nil
 }
 
-type metadata struct {
-	WorkspaceMembers []string `json:"workspace_members"`
+// DependencyGraph renders the project's resolved dependencies as a Graphviz DOT graph
+func (c CLIRunner) DependencyGraph(srcDir string, workLayer packit.Layer, destLayer packit.Layer) (string, error) {
+	m, err := c.readMetadata(srcDir, workLayer, destLayer)
+	if err != nil {
+		return "", err
+	}
+
+	return m.DependencyGraph()
 }
 
-// WorkspaceMembers loads the members from the project workspace
-func (c CLIRunner) WorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]url.URL, error) {
-	stdout := bytes.Buffer{}
+// TargetPackages lists the packages, as "name version", that are compiled for the build target. Dependencies in
+// `[target.'cfg(...)'.dependencies]` tables that do not apply to the target are left out.
+func (c CLIRunner) TargetPackages(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	target, err := BuildTarget(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		target, err = c.hostTarget(srcDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := c.readMetadata(srcDir, workLayer, destLayer, fmt.Sprintf("--filter-platform=%s", target))
+	if err != nil {
+		return nil, err
+	}
 
+	return m.ResolvedPackages()
+}
+
+// hostTarget returns the target triple of the host from `cargo -vV`
+func (c CLIRunner) hostTarget(srcDir string) (string, error) {
+	stdout := bytes.Buffer{}
 	err := c.exec.Execute(pexec.Execution{
 		Dir:    srcDir,
 		Stdout: &stdout,
-		Env:    createEnviron(workLayer, destLayer),
-		Args:   []string{"metadata", "--format-version=1", "--no-deps"},
+		Args:   []string{"-vV"},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("build failed: %w", err)
+		return "", fmt.Errorf("unable to read the host target: %w", err)
 	}
 
-	var m metadata
-	err = json.Unmarshal(stdout.Bytes(), &m)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if host := strings.TrimPrefix(line, "host: "); host != line {
+			return strings.TrimSpace(host), nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to read the host target, `cargo -vV` did not print it")
+}
+
+// WorkspaceMembers loads the members from the project workspace
+func (c CLIRunner) WorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]url.URL, error) {
+	stream := make(chan url.URL)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.StreamWorkspaceMembers(srcDir, workLayer, destLayer, stream)
+	}()
+
+	var paths []url.URL
+	for path := range stream {
+		paths = append(paths, path)
+	}
+
+	err := <-done
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// StreamWorkspaceMembers sends each member of the project workspace to members as soon as it is resolved, and
+// closes members when it returns
+func (c CLIRunner) StreamWorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer, members chan<- url.URL) error {
+	defer close(members)
+
+	m, err := c.metadata(srcDir, workLayer, destLayer)
+	if err != nil {
+		return err
+	}
+
+	err = m.CheckSiblingDependencies(c.logger)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse Cargo metadata: %w", err)
+		return err
 	}
 
 	filterStr, filter := os.LookupEnv("BP_CARGO_WORKSPACE_MEMBERS")
-	filterList := make(map[string]bool)
-	if filter {
-		for _, f := range strings.Split(filterStr, ",") {
-			filterList[strings.TrimSpace(f)] = true
+	memberFilter := NewMemberFilter(filterStr)
+
+	pkg := strings.TrimSpace(os.Getenv("BP_CARGO_PACKAGE"))
+	if pkg != "" {
+		if filter {
+			return fmt.Errorf("BP_CARGO_PACKAGE and BP_CARGO_WORKSPACE_MEMBERS may not be used together")
 		}
+		filter = true
 	}
 
-	var paths []url.URL
+	skipUnpublished, err := ParseBoolEnv("BP_CARGO_SKIP_UNPUBLISHED")
+	if err != nil {
+		return err
+	}
+
+	unpublished := make(map[string]bool)
+	for _, p := range m.Packages {
+		unpublished[p.ID] = p.unpublished()
+	}
+
+	// cargo metadata should already leave excluded directories out, checking again makes sure they are never built
+	var rootWorkspace ManifestWorkspace
+	root, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if root.Workspace != nil {
+		rootWorkspace = *root.Workspace
+	}
+
+	type member struct {
+		name string
+		id   string
+		path url.URL
+	}
+
+	// like cargo, only the default members are built unless members are picked explicitly
+	useDefaults := !filter && len(rootWorkspace.DefaultMembers) > 0
+
+	var names []string
+	var defaults []string
+	var selected []member
 	for _, workspace := range m.WorkspaceMembers {
-		// This is OK because the workspace member format is `package-name package-version (url)` and
-		//   none of name, version or URL may contain a space & be valid
+		// The workspace member format is `package-name package-version (url)`. Neither the name nor the version may
+		//   contain a space, so everything after the second space is the URL, even when the path has spaces in it.
+		//   cargo percent-encodes special characters in the URL, which url.Parse decodes into Path.
 		parts := strings.SplitN(workspace, " ", 3)
-		if filter && filterList[strings.TrimSpace(parts[0])] || !filter {
-			path, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(parts[2], "("), ")"))
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse URL %s: %w", workspace, err)
+		if len(parts) != 3 {
+			return fmt.Errorf("unable to parse workspace member %q", workspace)
+		}
+		names = append(names, parts[0])
+
+		path, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(parts[2], "("), ")"))
+		if err != nil {
+			return fmt.Errorf("unable to parse URL %s: %w", workspace, err)
+		}
+
+		relPath, err := filepath.Rel(srcDir, path.Path)
+		if err != nil {
+			relPath = path.Path
+		}
+
+		// a package is picked by its name, like `cargo build -p`, never by the directory of the member
+		if pkg != "" {
+			if parts[0] == pkg {
+				selected = append(selected, member{name: parts[0], id: workspace, path: *path})
+			}
+			continue
+		}
+
+		if useDefaults {
+			if rootWorkspace.IsDefaultMember(srcDir, path.Path) {
+				defaults = append(defaults, parts[0])
+				selected = append(selected, member{name: parts[0], id: workspace, path: *path})
 			}
-			paths = append(paths, *path)
+			continue
+		}
+
+		if !filter || memberFilter.Match(strings.TrimSpace(parts[0]), relPath) {
+			selected = append(selected, member{name: parts[0], id: workspace, path: *path})
 		}
 	}
 
-	return paths, nil
+	if useDefaults {
+		c.logger.Subprocess("Building the default-members of the workspace [%s], set BP_CARGO_WORKSPACE_MEMBERS to build others", strings.Join(defaults, ", "))
+	}
+
+	// every requested member is checked before anything is built, so a typo doesn't fail the build half way through
+	if unmatched := memberFilter.Unmatched(); pkg == "" && filter && len(unmatched) > 0 {
+		return fmt.Errorf("BP_CARGO_WORKSPACE_MEMBERS lists [%s], which did not match any workspace member, available members are [%s]",
+			strings.Join(unmatched, ", "), strings.Join(names, ", "))
+	}
+
+	var skipped []string
+	sent := 0
+	for _, selection := range selected {
+		if skipUnpublished && unpublished[selection.id] {
+			c.logger.Subprocess("Skipping %s because it sets `publish = false`", selection.name)
+			skipped = append(skipped, selection.name)
+			continue
+		}
+
+		if rootWorkspace.Excludes(srcDir, selection.path.Path) {
+			c.logger.Subprocess("Skipping %s because it is listed in `[workspace].exclude`", selection.name)
+			continue
+		}
+
+		members <- selection.path
+		sent++
+	}
+
+	if pkg != "" && sent == 0 {
+		return fmt.Errorf("package %s not found in the workspace, available packages are [%s]", pkg, strings.Join(names, ", "))
+	}
+
+	if len(skipped) > 0 && sent == 0 {
+		return fmt.Errorf("BP_CARGO_SKIP_UNPUBLISHED excludes every member [%s], nothing is left to build", strings.Join(skipped, ", "))
+	}
+
+	return nil
 }
 
 func (c CLIRunner) CleanCargoHomeCache(workLayer packit.Layer) error {
+	// a cargo home from BP_CARGO_HOME is not part of the image and may be shared, so it is left as it is
+	if os.Getenv("BP_CARGO_HOME") != "" {
+		return nil
+	}
+
 	homeDir := filepath.Join(workLayer.Path, "home")
 	files, err := os.ReadDir(homeDir)
 	if err != nil {
@@ -143,8 +1230,9 @@ func (c CLIRunner) CleanCargoHomeCache(workLayer packit.Layer) error {
 
 	for _, file := range files {
 		if file.IsDir() && file.Name() == "bin" ||
-			file.IsDir() && file.Name() == "registry" ||
-			file.IsDir() && file.Name() == "git" {
+			file.Name() == "registry" ||
+			file.IsDir() && file.Name() == "git" ||
+			!file.IsDir() && file.Name() == CargoConfigFile {
 			continue
 		}
 		err := os.RemoveAll(filepath.Join(homeDir, file.Name()))
@@ -196,14 +1284,246 @@ func (c CLIRunner) BuildArgs(destLayer packit.Layer, defaultMemberPath string) (
 		return nil, fmt.Errorf("filter failed: %w", err)
 	}
 
+	jobsArgs, err := JobsArgs(envArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	profileArgs, err := ProfileConfigArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	color, err := ColorMode()
+	if err != nil {
+		return nil, err
+	}
+
+	offlineArgs, err := OfflineArgs(envArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	lockedArgs, err := LockedArgs(envArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	verboseArgs, err := VerboseArgs(envArgs)
+	if err != nil {
+		return nil, err
+	}
+
 	args := []string{"install"}
 	args = append(args, envArgs...)
-	args = append(args, "--color=never", fmt.Sprintf("--root=%s", destLayer.Path))
+	args = append(args, offlineArgs...)
+	args = append(args, lockedArgs...)
+	args = append(args, verboseArgs...)
+	// cargo install builds with the release profile unless told otherwise
+	if profile := ProfileName(); profile != DefaultProfile {
+		args = append(args, fmt.Sprintf("--profile=%s", profile))
+	}
+	args = append(args, jobsArgs...)
+	args = append(args, profileArgs...)
+	args = append(args, fmt.Sprintf("--color=%s", color), fmt.Sprintf("--root=%s", destLayer.Path))
 	args = AddDefaultPath(args, defaultMemberPath)
 
 	return args, nil
 }
 
+// TestArgs will build the list of arguments to pass `cargo test`, using the same profile, features and cargo
+// settings as the build
+func (c CLIRunner) TestArgs(srcDir string) ([]string, error) {
+	return c.checkArgs("test", srcDir)
+}
+
+// ClippyArgs will build the list of arguments to pass `cargo clippy`, using the same profile, features and cargo
+// settings as the build. Warnings are denied when BP_CARGO_CLIPPY_DENY_WARNINGS is set.
+func (c CLIRunner) ClippyArgs(srcDir string) ([]string, error) {
+	args, err := c.checkArgs("clippy", srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := ParseBoolEnv("BP_CARGO_CLIPPY_DENY_WARNINGS")
+	if err != nil {
+		return nil, err
+	}
+
+	if deny {
+		args = append(args, "--", "-D", "warnings")
+	}
+
+	return args, nil
+}
+
+// checkArgs builds the arguments for a cargo command that checks the project, like `cargo test`
+func (c CLIRunner) checkArgs(command string, srcDir string) ([]string, error) {
+	args := []string{command, "--release"}
+	if profile := ProfileName(); profile != DefaultProfile {
+		args = []string{command, fmt.Sprintf("--profile=%s", profile)}
+	}
+
+	offlineArgs, err := OfflineArgs(nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, offlineArgs...)
+
+	lockedArgs, err := LockedArgs(nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, lockedArgs...)
+
+	verboseArgs, err := VerboseArgs(nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, verboseArgs...)
+
+	jobsArgs, err := JobsArgs(nil)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, jobsArgs...)
+
+	profileArgs, err := ProfileConfigArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, profileArgs...)
+
+	features, err := featureArgs(".", srcDir)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, features...)
+
+	color, err := ColorMode()
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, fmt.Sprintf("--color=%s", color))
+	return append(args, manifestPathArgs(srcDir)...), nil
+}
+
+// manifestPathArgs returns `--manifest-path` for the Cargo.toml in srcDir when BP_CARGO_MANIFEST_PATH is set, which
+// makes ProjectDir the directory of that manifest. `cargo install` and `cargo build` are passed their own paths.
+func manifestPathArgs(srcDir string) []string {
+	if os.Getenv("BP_CARGO_MANIFEST_PATH") == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("--manifest-path=%s", filepath.Join(srcDir, "Cargo.toml"))}
+}
+
+// CompileArgs will build the list of arguments to pass `cargo build`, along with the manifest being built. A
+// `--path` set in BP_CARGO_INSTALL_ARGS takes precedence over defaultMemberPath, like it does for `cargo install`.
+func (c CLIRunner) CompileArgs(defaultMemberPath string) ([]string, string, error) {
+	envArgs, err := FilterInstallArgs(os.Getenv("BP_CARGO_INSTALL_ARGS"))
+	if err != nil {
+		return nil, "", fmt.Errorf("filter failed: %w", err)
+	}
+
+	memberPath := defaultMemberPath
+	args := []string{"build", "--release"}
+	if profile := ProfileName(); profile != DefaultProfile {
+		args = []string{"build", fmt.Sprintf("--profile=%s", profile)}
+	}
+	for i := 0; i < len(envArgs); i++ {
+		if envArgs[i] == "--path" && i+1 < len(envArgs) {
+			memberPath = envArgs[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(envArgs[i], "--path=") {
+			memberPath = strings.TrimPrefix(envArgs[i], "--path=")
+			continue
+		}
+		args = append(args, envArgs[i])
+	}
+
+	offlineArgs, err := OfflineArgs(args)
+	if err != nil {
+		return nil, "", err
+	}
+	args = append(args, offlineArgs...)
+
+	lockedArgs, err := LockedArgs(args)
+	if err != nil {
+		return nil, "", err
+	}
+	args = append(args, lockedArgs...)
+
+	verboseArgs, err := VerboseArgs(args)
+	if err != nil {
+		return nil, "", err
+	}
+	args = append(args, verboseArgs...)
+
+	jobsArgs, err := JobsArgs(args)
+	if err != nil {
+		return nil, "", err
+	}
+	args = append(args, jobsArgs...)
+
+	profileArgs, err := ProfileConfigArgs()
+	if err != nil {
+		return nil, "", err
+	}
+	args = append(args, profileArgs...)
+
+	color, err := ColorMode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	manifestPath := filepath.Join(memberPath, "Cargo.toml")
+	args = append(args, fmt.Sprintf("--color=%s", color), fmt.Sprintf("--manifest-path=%s", manifestPath))
+
+	return args, manifestPath, nil
+}
+
+// featureArgs returns the `--features` argument for the features in BP_CARGO_FEATURES that the member defines
+func featureArgs(memberPath string, srcDir string) ([]string, error) {
+	selection, err := LoadFeatureSelection()
+	if err != nil {
+		return nil, err
+	}
+
+	// --all-features takes precedence over the other settings
+	if selection.All {
+		return []string{"--all-features"}, nil
+	}
+
+	var args []string
+	if selection.NoDefault {
+		args = append(args, "--no-default-features")
+	}
+
+	requested := selection.Requested()
+	if len(requested) == 0 {
+		return args, nil
+	}
+
+	memberDir := memberPath
+	if !filepath.IsAbs(memberDir) {
+		memberDir = filepath.Join(srcDir, memberDir)
+	}
+
+	features, err := MemberFeatures(memberDir, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(features) == 0 {
+		return args, nil
+	}
+
+	return append(args, fmt.Sprintf("--features=%s", strings.Join(features, ","))), nil
+}
+
 // FilterInstallArgs provides a clean list of allowed arguments
 func FilterInstallArgs(args string) ([]string, error) {
 	argwords, err := shellwords.Parse(args)
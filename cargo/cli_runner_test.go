@@ -2,20 +2,25 @@ package cargo_test
 
 import (
 	"bytes"
+	gocontext "context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
 	"github.com/dmikusa/rust-cargo-cnb/cargo"
 	"github.com/dmikusa/rust-cargo-cnb/cargo/mocks"
 	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/fs"
 	"github.com/paketo-buildpacks/packit/pexec"
 	"github.com/paketo-buildpacks/packit/scribe"
 	"github.com/sclevine/spec"
@@ -39,6 +44,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(args).To(Equal([]string{
 				"install",
+				"--locked",
 				"--color=never",
 				"--root=/some/location/2",
 				"--path=foo",
@@ -65,11 +71,123 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 					"--foo=bar",
 					"--foo",
 					"baz",
+					"--locked",
 					"--color=never",
 					"--root=/some/location/2",
 				}))
 			})
 		})
+
+		context("with BP_CARGO_LOCKED", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_LOCKED")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+			})
+
+			it("leaves out --locked when it is turned off", func() {
+				Expect(os.Setenv("BP_CARGO_LOCKED", "false")).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).NotTo(ContainElement("--locked"))
+
+				args, _, err = cargo.CLIRunner{}.CompileArgs(".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).NotTo(ContainElement("--locked"))
+			})
+
+			it("doesn't add --locked when the install args already pass --frozen", func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--frozen")).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--frozen", "--color=never", "--root=/some/location/2", "--path=."}))
+			})
+		})
+
+		context("with BP_CARGO_VERBOSE", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_VERBOSE")).To(Succeed())
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+			})
+
+			it("makes cargo verbose when building and testing", func() {
+				Expect(os.Setenv("BP_CARGO_VERBOSE", "2")).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--locked", "-vv", "--color=never", "--root=/some/location/2", "--path=."}))
+
+				args, _, err = cargo.CLIRunner{}.CompileArgs(".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(ContainElement("-vv"))
+
+				args, err = cargo.CLIRunner{}.TestArgs(".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(ContainElement("-vv"))
+			})
+
+			it("doesn't add -v when the install args already set the verbosity", func() {
+				Expect(os.Setenv("BP_CARGO_VERBOSE", "1")).To(Succeed())
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--quiet")).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{"install", "--quiet", "--locked", "--color=never", "--root=/some/location/2", "--path=."}))
+			})
+
+			it("rejects other values", func() {
+				Expect(os.Setenv("BP_CARGO_VERBOSE", "true")).To(Succeed())
+
+				_, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).To(MatchError(`invalid value for BP_CARGO_VERBOSE "true", must be 1 or 2`))
+			})
+		})
+
+		context("with quoted args", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+			})
+
+			it("passes a quoted value with spaces as a single argument", func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", `--locked --features "tls metrics" -j 4`)).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{
+					"install",
+					"--locked",
+					"--features",
+					"tls metrics",
+					"-j",
+					"4",
+					"--color=never",
+					"--root=/some/location/2",
+					"--path=.",
+				}))
+			})
+
+			it("builds the default arguments when the value is empty", func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "")).To(Succeed())
+
+				args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(args).To(Equal([]string{
+					"install",
+					"--locked",
+					"--color=never",
+					"--root=/some/location/2",
+					"--path=.",
+				}))
+			})
+
+			it("fails on an unterminated quote", func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", `--features "tls`)).To(Succeed())
+
+				_, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+				Expect(err).To(MatchError(ContainSubstring("parse args failed")))
+			})
+		})
 	})
 
 	context("BP_CARGO_INSTALL_ARGS filters --color and --root", func() {
@@ -113,7 +231,8 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 			logger := scribe.NewEmitter(&logBuf)
 
 			env := os.Environ()
-			env = append(env, `CARGO_TARGET_DIR=/some/location/1/target`)
+			env = append(env, `CARGO_TERM_COLOR=never`)
+			env = append(env, `CARGO_TARGET_DIR=/some/location/rust-target`)
 			env = append(env, `CARGO_HOME=/some/location/1/home`)
 
 			for i := 0; i < len(env); i++ {
@@ -129,6 +248,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 				Stderr: os.Stderr,
 				Args: []string{
 					"install",
+					"--locked",
 					"--color=never",
 					"--root=/some/location/2",
 					"--path=.",
@@ -161,7 +281,8 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 				logger := scribe.NewEmitter(&logBuf)
 
 				env := os.Environ()
-				env = append(env, `CARGO_TARGET_DIR=/some/location/1/target`)
+				env = append(env, `CARGO_TERM_COLOR=never`)
+				env = append(env, `CARGO_TARGET_DIR=/some/location/rust-target`)
 				env = append(env, `CARGO_HOME=/some/location/1/home`)
 
 				for i := 0; i < len(env); i++ {
@@ -180,6 +301,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 						"--path=./todo",
 						"--foo=baz",
 						"bar",
+						"--locked",
 						"--color=never",
 						"--root=/some/location/2",
 					},
@@ -233,13 +355,59 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when workspace member paths have spaces or special characters", func() {
+		var mockExe mocks.Executable
+
+		it.Before(func() {
+			metadata := `{
+				"packages": [],
+				"workspace_members": [
+					"member-one 0.1.0 (path+file:///work space/member one)",
+					"member-two 0.1.0 (path+file:///work%20space/member%20two%2Bextra)"
+				],
+				"target_directory": "/work space/target"
+			}`
+
+			mockExe = mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+		})
+
+		it("decodes the paths", func() {
+			urls, err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).WorkspaceMembers("/work space", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls).To(HaveLen(2))
+			Expect(urls[0].Path).To(Equal("/work space/member one"))
+			Expect(urls[1].Path).To(Equal("/work space/member two+extra"))
+		})
+
+		it("passes the path to cargo as a single argument", func() {
+			urls, err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).WorkspaceMembers("/work space", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "install"
+			})).Run(func(args mock.Arguments) {
+				Expect(args.Get(0).(pexec.Execution).Args).To(ContainElement("--path=/work space/member one"))
+			}).Return(nil)
+
+			runner := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+			Expect(runner.InstallMember(gocontext.Background(), urls[0].Path, "/work space", workLayer, destLayer)).To(Succeed())
+		})
+	})
+
 	context("failure cases", func() {
 		it("bubbles up failures", func() {
 			logBuf := bytes.Buffer{}
 			logger := scribe.NewEmitter(&logBuf)
 
 			env := os.Environ()
-			env = append(env, `CARGO_TARGET_DIR=/some/location/1/target`)
+			env = append(env, `CARGO_TERM_COLOR=never`)
+			env = append(env, `CARGO_TARGET_DIR=/some/location/rust-target`)
 			env = append(env, `CARGO_HOME=/some/location/1/home`)
 
 			for i := 0; i < len(env); i++ {
@@ -255,6 +423,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 				Stderr: os.Stderr,
 				Args: []string{
 					"install",
+					"--locked",
 					"--color=never",
 					"--root=/some/location/2",
 					"--path=.",
@@ -275,6 +444,70 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when BP_CARGO_INSTALL_EXAMPLES is set", func() {
+		var exe mocks.Executable
+
+		it.Before(func() {
+			metadata := `{
+				"packages": [
+					{"name": "app", "manifest_path": "/workspace/app/Cargo.toml", "targets": [
+						{"kind": ["bin"], "name": "app"},
+						{"kind": ["example"], "name": "client"}
+					]},
+					{"name": "tool", "manifest_path": "/workspace/tool/Cargo.toml", "targets": [
+						{"kind": ["bin"], "name": "tool"},
+						{"kind": ["example"], "name": "server"}
+					]}
+				],
+				"workspace_members": [],
+				"target_directory": "/workspace/target"
+			}`
+
+			exe = mocks.Executable{}
+			exe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_INSTALL_EXAMPLES")).To(Succeed())
+		})
+
+		it("returns the examples of every package", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_EXAMPLES", "*")).To(Succeed())
+
+			examples, err := cargo.NewCLIRunner(&exe, scribe.NewEmitter(&bytes.Buffer{})).Examples("/workspace", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(examples).To(Equal([]string{"client", "server"}))
+		})
+
+		it("fails on an example that no package has", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_EXAMPLES", "client,missing")).To(Succeed())
+
+			_, err := cargo.NewCLIRunner(&exe, scribe.NewEmitter(&bytes.Buffer{})).Examples("/workspace", workLayer, destLayer)
+			Expect(err).To(MatchError("example missing in BP_CARGO_INSTALL_EXAMPLES is not an example of any package"))
+		})
+
+		it("only passes the examples of the member being installed", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_EXAMPLES", "client,server")).To(Succeed())
+
+			exe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "install"
+			})).Run(func(args mock.Arguments) {
+				Expect(args.Get(0).(pexec.Execution).Args).To(Equal([]string{
+					"install", "--locked", "--color=never", "--root=/some/location/2", "--path=/workspace/tool",
+					"--bins", "--example", "server",
+				}))
+			}).Return(nil)
+
+			runner := cargo.NewCLIRunner(&exe, scribe.NewEmitter(&bytes.Buffer{}))
+			Expect(runner.InstallMember(gocontext.Background(), "/workspace/tool", "/workspace", workLayer, destLayer)).To(Succeed())
+		})
+	})
+
 	context("when cargo home has files", func() {
 		it("is cleaned up", func() {
 			logBuf := bytes.Buffer{}
@@ -288,6 +521,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 			Expect(os.MkdirAll(filepath.Join(workingDir, "home", "registry", "index"), 0755)).ToNot(HaveOccurred())
 			Expect(os.MkdirAll(filepath.Join(workingDir, "home", "registry", "cache"), 0755)).ToNot(HaveOccurred())
 			Expect(os.MkdirAll(filepath.Join(workingDir, "home", "git", "db"), 0755)).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "home", "config.toml"), nil, 0644)).ToNot(HaveOccurred())
 
 			// To destroy
 			Expect(os.MkdirAll(filepath.Join(workingDir, "home", "registry", "foo"), 0755)).ToNot(HaveOccurred())
@@ -300,6 +534,7 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 			Expect(filepath.Join(workingDir, "home", "registry", "index")).To(BeADirectory())
 			Expect(filepath.Join(workingDir, "home", "registry", "cache")).To(BeADirectory())
 			Expect(filepath.Join(workingDir, "home", "git", "db")).To(BeADirectory())
+			Expect(filepath.Join(workingDir, "home", "config.toml")).To(BeARegularFile())
 			Expect(filepath.Join(workingDir, "home", "registry", "foo")).ToNot(BeADirectory())
 			Expect(filepath.Join(workingDir, "home", "git", "bar")).ToNot(BeADirectory())
 			Expect(filepath.Join(workingDir, "home", "baz")).ToNot(BeADirectory())
@@ -325,53 +560,1734 @@ func testCLIRunner(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
-	context("when specifying a subset of workspace members", func() {
+	context("when BP_CARGO_INSTALL_METHOD is build", func() {
+		var (
+			srcDir    string
+			workLayer packit.Layer
+			destLayer packit.Layer
+		)
+
 		it.Before(func() {
-			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "cookie-auth,protobuf-example, async_data_factory,hello-world")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_INSTALL_METHOD", "build")).To(Succeed())
+
+			var err error
+			srcDir, err = ioutil.TempDir("", "src-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			workDir, err := ioutil.TempDir("", "work-layer")
+			Expect(err).NotTo(HaveOccurred())
+			workLayer = packit.Layer{Name: "work-layer", Path: workDir}
+
+			destDir, err := ioutil.TempDir("", "dest-layer")
+			Expect(err).NotTo(HaveOccurred())
+			destLayer = packit.Layer{Name: "dest-layer", Path: destDir}
 		})
 
 		it.After(func() {
-			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_INSTALL_METHOD")).To(Succeed())
+			Expect(os.RemoveAll(srcDir)).To(Succeed())
+			Expect(os.RemoveAll(workLayer.Path)).To(Succeed())
+			Expect(os.RemoveAll(destLayer.Path)).To(Succeed())
 		})
 
-		it("filters workspace members", func() {
+		it("builds default compile arguments", func() {
+			args, manifestPath, err := cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifestPath).To(Equal("Cargo.toml"))
+			Expect(args).To(Equal([]string{"build", "--release", "--locked", "--color=never", "--manifest-path=Cargo.toml"}))
+		})
+
+		context("with custom args", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--path ./todo --locked --root=/nope")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+			})
+
+			it("translates --path to --manifest-path", func() {
+				args, manifestPath, err := cargo.CLIRunner{}.CompileArgs(".")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifestPath).To(Equal("todo/Cargo.toml"))
+				Expect(args).To(Equal([]string{"build", "--release", "--locked", "--color=never", "--manifest-path=todo/Cargo.toml"}))
+			})
+		})
+
+		it("copies binaries from the directory of the configured build target", func() {
+			Expect(fs.Copy(filepath.Join("testdata", "build_target", ".cargo"), filepath.Join(srcDir, ".cargo"))).To(Succeed())
+
+			metadata := fmt.Sprintf(`{
+				"packages": [
+					{"name": "app", "manifest_path": %q, "targets": [{"kind": ["bin"], "name": "app"}]}
+				],
+				"workspace_members": [],
+				"target_directory": %q
+			}`, filepath.Join(srcDir, "Cargo.toml"), filepath.Join(workLayer.Path, "target"))
+
+			buildExe := mocks.Executable{}
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "build"
+			})).Return(func(ex pexec.Execution) error {
+				releaseDir := filepath.Join(workLayer.Path, "target", "x86_64-unknown-linux-musl", "release")
+				Expect(os.MkdirAll(releaseDir, 0755)).To(Succeed())
+				return ioutil.WriteFile(filepath.Join(releaseDir, "app"), []byte("some-binary"), 0755)
+			})
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+
+			Expect(cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(&bytes.Buffer{})).Install(srcDir, workLayer, destLayer)).To(Succeed())
+			Expect(cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))).To(Equal([]string{"app"}))
+		})
+
+		context("when BP_CARGO_INSTALL_EXAMPLES is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_EXAMPLES", "client")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_EXAMPLES")).To(Succeed())
+			})
+
+			it("copies the examples next to the binaries", func() {
+				metadata := fmt.Sprintf(`{
+					"packages": [
+						{"name": "app", "manifest_path": %q, "targets": [
+							{"kind": ["bin"], "name": "app"},
+							{"kind": ["example"], "name": "client"}
+						]}
+					],
+					"workspace_members": [],
+					"target_directory": %q
+				}`, filepath.Join(srcDir, "Cargo.toml"), filepath.Join(workLayer.Path, "target"))
+
+				releaseDir := filepath.Join(workLayer.Path, "target", "release")
+				buildExe := mocks.Executable{}
+				buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+					return ex.Args[0] == "build"
+				})).Return(func(ex pexec.Execution) error {
+					Expect(ex.Args).To(ContainElements("--bins", "--example", "client"))
+					Expect(os.MkdirAll(filepath.Join(releaseDir, "examples"), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(releaseDir, "app"), []byte("some-binary"), 0755)).To(Succeed())
+					return ioutil.WriteFile(filepath.Join(releaseDir, "examples", "client"), []byte("some-example"), 0755)
+				})
+				buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+					return ex.Args[0] == "metadata"
+				})).Return(func(ex pexec.Execution) error {
+					_, err := ex.Stdout.Write([]byte(metadata))
+					return err
+				})
+
+				Expect(cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(&bytes.Buffer{})).Install(srcDir, workLayer, destLayer)).To(Succeed())
+				Expect(cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))).To(Equal([]string{"app", "client"}))
+			})
+		})
+
+		context("when BP_CARGO_INSTALL_BINS is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_BINS", "worker")).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(srcDir, "src", "bin"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(srcDir, "src", "main.rs"), []byte("fn main() {}"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(srcDir, "src", "bin", "worker.rs"), []byte("fn main() {}"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_INSTALL_BINS")).To(Succeed())
+			})
+
+			it("builds and copies only the selected binaries", func() {
+				metadata := fmt.Sprintf(`{
+					"packages": [
+						{"name": "app", "manifest_path": %q, "targets": [
+							{"kind": ["bin"], "name": "app"},
+							{"kind": ["bin"], "name": "worker"}
+						]}
+					],
+					"workspace_members": [],
+					"target_directory": %q
+				}`, filepath.Join(srcDir, "Cargo.toml"), filepath.Join(workLayer.Path, "target"))
+
+				releaseDir := filepath.Join(workLayer.Path, "target", "release")
+				buildExe := mocks.Executable{}
+				buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+					return ex.Args[0] == "build"
+				})).Return(func(ex pexec.Execution) error {
+					Expect(ex.Args).To(ContainElements("--bin", "worker"))
+					Expect(ex.Args).NotTo(ContainElement("app"))
+					Expect(os.MkdirAll(releaseDir, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(releaseDir, "app"), []byte("stale-binary"), 0755)).To(Succeed())
+					return ioutil.WriteFile(filepath.Join(releaseDir, "worker"), []byte("some-binary"), 0755)
+				})
+				buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+					return ex.Args[0] == "metadata"
+				})).Return(func(ex pexec.Execution) error {
+					_, err := ex.Stdout.Write([]byte(metadata))
+					return err
+				})
+
+				Expect(cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(&bytes.Buffer{})).Install(srcDir, workLayer, destLayer)).To(Succeed())
+				Expect(cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))).To(Equal([]string{"worker"}))
+			})
+
+			it("skips a member that has none of the selected binaries", func() {
+				Expect(os.Setenv("BP_CARGO_INSTALL_BINS", "other")).To(Succeed())
+
+				buf := &bytes.Buffer{}
+				buildExe := mocks.Executable{}
+
+				Expect(cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(buf)).InstallMember(gocontext.Background(), ".", srcDir, workLayer, destLayer)).To(Succeed())
+				Expect(buf.String()).To(ContainSubstring("Skipping ., it has none of the binaries in BP_CARGO_INSTALL_BINS"))
+				buildExe.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+
+			it("resolves the command without running cargo", func() {
+				buildExe := mocks.Executable{}
+				runner := cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(&bytes.Buffer{}))
+
+				args, err := runner.InstallCommand(".", srcDir, workLayer, destLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(args).To(Equal([]string{"build", "--release", "--locked", "--color=never", "--manifest-path=Cargo.toml", "--bin", "worker"}))
+
+				Expect(os.Setenv("BP_CARGO_INSTALL_BINS", "other")).To(Succeed())
+				args, err = runner.InstallCommand(".", srcDir, workLayer, destLayer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(args).To(BeNil())
+
+				buildExe.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+		})
+
+		it("installs only the binary of a crate that is also a library", func() {
+			Expect(fs.Copy(filepath.Join("testdata", "lib_bin"), srcDir)).To(Succeed())
+
+			metadata := fmt.Sprintf(`{
+				"packages": [
+					{"name": "app", "manifest_path": %q, "targets": [
+						{"kind": ["lib"], "name": "app"},
+						{"kind": ["bin"], "name": "app"}
+					]}
+				],
+				"workspace_members": [],
+				"target_directory": %q
+			}`, filepath.Join(srcDir, "Cargo.toml"), filepath.Join(workLayer.Path, "target"))
+
+			releaseDir := filepath.Join(workLayer.Path, "target", "release")
+			buildExe := mocks.Executable{}
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "build"
+			})).Return(func(ex pexec.Execution) error {
+				Expect(os.MkdirAll(filepath.Join(releaseDir, "deps"), 0755)).To(Succeed())
+				for _, name := range []string{"libapp.rlib", "libapp.d", "app.d", filepath.Join("deps", "libapp-0123abcd.rlib"), filepath.Join("deps", "libapp-0123abcd.rmeta")} {
+					Expect(ioutil.WriteFile(filepath.Join(releaseDir, name), []byte("library"), 0644)).To(Succeed())
+				}
+				return ioutil.WriteFile(filepath.Join(releaseDir, "app"), []byte("some-binary"), 0755)
+			})
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+
+			Expect(cargo.NewCLIRunner(&buildExe, scribe.NewEmitter(&bytes.Buffer{})).Install(srcDir, workLayer, destLayer)).To(Succeed())
+			Expect(cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))).To(Equal([]string{"app"}))
+
+			var launched []string
+			Expect(filepath.Walk(destLayer.Path, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					launched = append(launched, filepath.Base(path))
+				}
+				return err
+			})).To(Succeed())
+			Expect(launched).To(Equal([]string{"app"}))
+
+			// the library stays in the cached target directory, so the next build can reuse it
+			Expect(filepath.Join(releaseDir, "libapp.rlib")).To(BeAnExistingFile())
+			Expect(filepath.Join(releaseDir, "deps", "libapp-0123abcd.rlib")).To(BeAnExistingFile())
+		})
+
+		it("produces the same launch layer as install", func() {
 			logBuf := bytes.Buffer{}
 			logger := scribe.NewEmitter(&logBuf)
 
-			metadata, err := ioutil.ReadFile("testdata/metadata.json")
-			Expect(err).ToNot(HaveOccurred())
+			metadata := fmt.Sprintf(`{
+				"packages": [
+					{"name": "app", "manifest_path": %q, "targets": [
+						{"kind": ["lib"], "name": "app"},
+						{"kind": ["bin"], "name": "app"},
+						{"kind": ["bin"], "name": "not-built"}
+					]},
+					{"name": "other", "manifest_path": "/somewhere/else/Cargo.toml", "targets": [
+						{"kind": ["bin"], "name": "other"}
+					]}
+				],
+				"workspace_members": [],
+				"target_directory": %q
+			}`, filepath.Join(srcDir, "Cargo.toml"), filepath.Join(workLayer.Path, "target"))
 
-			mockExe := mocks.Executable{}
-			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
-				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
-				return true
+			buildExe := mocks.Executable{}
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "build"
 			})).Return(func(ex pexec.Execution) error {
-				_, err := ex.Stdout.Write(metadata)
-				Expect(err).ToNot(HaveOccurred())
-				return nil
+				releaseDir := filepath.Join(workLayer.Path, "target", "release")
+				Expect(os.MkdirAll(releaseDir, 0755)).To(Succeed())
+				return ioutil.WriteFile(filepath.Join(releaseDir, "app"), []byte("some-binary"), 0755)
+			})
+			buildExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "metadata"
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
 			})
 
-			runner := cargo.NewCLIRunner(&mockExe, logger)
-			urls, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(cargo.NewCLIRunner(&buildExe, logger).Install(srcDir, workLayer, destLayer)).To(Succeed())
+			buildExe.AssertExpectations(t)
+			built, err := cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))
 			Expect(err).ToNot(HaveOccurred())
+			Expect(logBuf.String()).To(ContainSubstring("Binary not-built was not built, skipping"))
 
-			Expect(urls).To(HaveLen(4))
+			Expect(os.Setenv("BP_CARGO_INSTALL_METHOD", "install")).To(Succeed())
+			Expect(os.RemoveAll(filepath.Join(destLayer.Path, "bin"))).To(Succeed())
 
-			url, err := url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/basics/hello%20world")
-			Expect(err).ToNot(HaveOccurred())
-			Expect(urls[0]).To(Equal(*url))
+			installExe := mocks.Executable{}
+			installExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[0] == "install"
+			})).Return(func(ex pexec.Execution) error {
+				binDir := filepath.Join(destLayer.Path, "bin")
+				Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+				return ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("some-binary"), 0755)
+			})
 
-			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/other/data_factory")
+			Expect(cargo.NewCLIRunner(&installExe, logger).Install(srcDir, workLayer, destLayer)).To(Succeed())
+			installExe.AssertExpectations(t)
+			installed, err := cargo.ListBinaries(filepath.Join(destLayer.Path, "bin"))
 			Expect(err).ToNot(HaveOccurred())
-			Expect(urls[1]).To(Equal(*url))
 
-			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/other/protobuf")
-			Expect(err).ToNot(HaveOccurred())
-			Expect(urls[2]).To(Equal(*url))
+			Expect(built).To(Equal([]string{"app"}))
+			Expect(built).To(Equal(installed))
+		})
 
-			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/session/cookie-auth")
-			Expect(err).ToNot(HaveOccurred())
-			Expect(urls[3]).To(Equal(*url))
+		it("rejects an unknown method", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_METHOD", "copy")).To(Succeed())
+			err := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{})).Install(srcDir, workLayer, destLayer)
+			Expect(err).To(MatchError("invalid BP_CARGO_INSTALL_METHOD \"copy\", must be `install` or `build`"))
+		})
+	})
+
+	context("when the context is done before cargo finishes", func() {
+		it("returns the context's error", func() {
+			release := make(chan struct{})
+			defer close(release)
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				<-release
+			}).Return(nil)
+
+			ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).InstallMember(ctx, "/workspace/stuck", workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError(gocontext.DeadlineExceeded))
+		})
+	})
+
+	context("when a registry token is set in the environment", func() {
+		it.Before(func() {
+			Expect(os.Setenv("CARGO_REGISTRIES_INTERNAL_TOKEN", "s3cr3t-internal")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_CLEAN_ENV", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("CARGO_REGISTRIES_INTERNAL_TOKEN")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_CLEAN_ENV")).To(Succeed())
+		})
+
+		it("passes the token to cargo, even with a clean environment, and keeps it out of the logs", func() {
+			logBuf := bytes.Buffer{}
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				for _, e := range ex.Env {
+					if e == "CARGO_REGISTRIES_INTERNAL_TOKEN=s3cr3t-internal" {
+						return true
+					}
+				}
+				return false
+			})).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			mockExe.AssertExpectations(t)
+			Expect(logBuf.String()).NotTo(ContainSubstring("s3cr3t-internal"))
+		})
+	})
+
+	context("when BP_CARGO_TARGET is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-musl")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+		})
+
+		it("passes the target to cargo as CARGO_BUILD_TARGET", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				for _, e := range ex.Env {
+					if e == "CARGO_BUILD_TARGET=x86_64-unknown-linux-musl" {
+						return true
+					}
+				}
+				return false
+			})).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			mockExe.AssertExpectations(t)
+		})
+	})
+
+	context("when fetching dependencies", func() {
+		it("runs cargo fetch and keeps its output", func() {
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+				_, _ = execution.Stderr.Write([]byte("Downloaded serde v1.0.130\n"))
+			}).Return(nil)
+
+			logBuf := bytes.Buffer{}
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Fetch(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Dir).To(Equal(workingDir))
+			Expect(execution.Args).To(Equal([]string{"fetch", "--color=never"}))
+			Expect(execution.Env).To(ContainElement("CARGO_HOME=/some/location/1/home"))
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+
+		it("includes the output when cargo fetch fails", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, _ = ex.Stderr.Write([]byte("error: failed to download serde\n"))
+				return errors.New("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Fetch(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("fetch failed: exit status 101\nerror: failed to download serde\n"))
+		})
+	})
+
+	context("when reading the toolchain version", func() {
+		it("returns the cargo and rustc versions", func() {
+			cargoExe := mocks.Executable{}
+			cargoExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				Expect(ex.Args).To(Equal([]string{"--version"}))
+				Expect(ex.Dir).To(Equal(workingDir))
+				_, err := ex.Stdout.Write([]byte("cargo 1.56.0 (4ed5d137b 2021-10-04)\n"))
+				return err
+			})
+
+			rustc := mocks.Executable{}
+			rustc.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte("rustc 1.56.0 (09c42c458 2021-10-18)\n"))
+				return err
+			})
+
+			runner := cargo.NewCLIRunner(&cargoExe, scribe.NewEmitter(&bytes.Buffer{})).WithRustc(&rustc)
+			Expect(runner.Version(workingDir, workLayer, destLayer)).To(Equal("cargo 1.56.0 (4ed5d137b 2021-10-04), rustc 1.56.0 (09c42c458 2021-10-18)"))
+		})
+
+		it("names the command that failed", func() {
+			cargoExe := mocks.Executable{}
+			cargoExe.On("Execute", mock.Anything).Return(nil)
+
+			rustc := mocks.Executable{}
+			rustc.On("Execute", mock.Anything).Return(errors.New("exit status 1"))
+
+			runner := cargo.NewCLIRunner(&cargoExe, scribe.NewEmitter(&bytes.Buffer{})).WithRustc(&rustc)
+			_, err := runner.Version(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("rustc --version failed: exit status 1"))
+		})
+	})
+
+	context("TargetInstalled", func() {
+		var libDir string
+
+		it.Before(func() {
+			var err error
+			libDir, err = ioutil.TempDir("", "target-libdir")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(libDir)).To(Succeed())
+		})
+
+		it("checks the library directory that rustc reports for the target", func() {
+			mockRustc := mocks.Executable{}
+			mockRustc.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{"--print", "target-libdir", "--target", "aarch64-unknown-linux-gnu"})
+			})).Return(func(ex pexec.Execution) error {
+				_, err := fmt.Fprintln(ex.Stdout, libDir)
+				return err
+			})
+
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{})).WithRustc(&mockRustc)
+			installed, err := runner.TargetInstalled(workingDir, "aarch64-unknown-linux-gnu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeTrue())
+
+			Expect(os.RemoveAll(libDir)).To(Succeed())
+			installed, err = runner.TargetInstalled(workingDir, "aarch64-unknown-linux-gnu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installed).To(BeFalse())
+		})
+
+		it("fails when rustc fails", func() {
+			mockRustc := mocks.Executable{}
+			mockRustc.On("Execute", mock.Anything).Return(errors.New("exit status 1"))
+
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{})).WithRustc(&mockRustc)
+			_, err := runner.TargetInstalled(workingDir, "aarch64-unknown-linux-gnu")
+			Expect(err).To(MatchError(ContainSubstring("rustc --print target-libdir failed: exit status 1")))
+		})
+	})
+
+	context("when building for a musl target", func() {
+		var (
+			toolsDir string
+			path     string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-musl")).To(Succeed())
+
+			var err error
+			toolsDir, err = ioutil.TempDir("", "tools")
+			Expect(err).NotTo(HaveOccurred())
+
+			path = os.Getenv("PATH")
+			Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			Expect(os.Unsetenv("CC_x86_64_unknown_linux_musl")).To(Succeed())
+			Expect(os.Setenv("PATH", path)).To(Succeed())
+			Expect(os.RemoveAll(toolsDir)).To(Succeed())
+		})
+
+		install := func() []string {
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)).To(Succeed())
+			return execution.Env
+		}
+
+		it("compiles C code with musl-gcc when it is installed", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "musl-gcc"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+			env := install()
+			Expect(env).To(ContainElement("CARGO_BUILD_TARGET=x86_64-unknown-linux-musl"))
+			Expect(env).To(ContainElement("CC_x86_64_unknown_linux_musl=musl-gcc"))
+		})
+
+		it("keeps a compiler that is already set", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "musl-gcc"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			Expect(os.Setenv("CC_x86_64_unknown_linux_musl", "clang")).To(Succeed())
+
+			env := install()
+			Expect(env).To(ContainElement("CC_x86_64_unknown_linux_musl=clang"))
+			Expect(env).NotTo(ContainElement("CC_x86_64_unknown_linux_musl=musl-gcc"))
+		})
+
+		it("uses the default compiler without musl-gcc", func() {
+			for _, e := range install() {
+				Expect(e).NotTo(HavePrefix("CC_x86_64_unknown_linux_musl="))
+			}
+		})
+
+		it("recognizes musl targets", func() {
+			Expect(cargo.IsMuslTarget("x86_64-unknown-linux-musl")).To(BeTrue())
+			Expect(cargo.IsMuslTarget("armv7-unknown-linux-musleabihf")).To(BeTrue())
+			Expect(cargo.IsMuslTarget("x86_64-unknown-linux-gnu")).To(BeFalse())
+			Expect(cargo.IsMuslTarget("")).To(BeFalse())
+		})
+	})
+
+	context("when BP_CARGO_SCCACHE is set", func() {
+		var (
+			toolsDir string
+			path     string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_SCCACHE", "true")).To(Succeed())
+
+			var err error
+			toolsDir, err = ioutil.TempDir("", "tools")
+			Expect(err).NotTo(HaveOccurred())
+
+			path = os.Getenv("PATH")
+			Expect(os.Setenv("PATH", toolsDir+string(os.PathListSeparator)+path)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_SCCACHE")).To(Succeed())
+			Expect(os.Setenv("PATH", path)).To(Succeed())
+			Expect(os.RemoveAll(toolsDir)).To(Succeed())
+		})
+
+		it("wraps rustc with sccache and points it at the rust-sccache layer", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "sccache"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Env).To(ContainElement("RUSTC_WRAPPER=sccache"))
+			Expect(execution.Env).To(ContainElement(fmt.Sprintf("SCCACHE_DIR=%s", filepath.Join(filepath.Dir(workLayer.Path), "rust-sccache"))))
+		})
+
+		it("leaves rustc alone when sccache is not installed", func() {
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Env).NotTo(ContainElement(HavePrefix("RUSTC_WRAPPER=")))
+		})
+	})
+
+	context("when BP_CARGO_OFFLINE is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_OFFLINE", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_OFFLINE")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+		})
+
+		it("keeps every cargo command off the network", func() {
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Args).To(Equal([]string{
+				"install",
+				"--offline",
+				"--locked",
+				"--color=never",
+				"--root=/some/location/2",
+				"--path=.",
+			}))
+			Expect(execution.Env).To(ContainElement("CARGO_NET_OFFLINE=true"))
+
+			args, _, err := cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"build", "--release", "--offline", "--locked", "--color=never", "--manifest-path=Cargo.toml"}))
+		})
+
+		it("does not add --offline when BP_CARGO_INSTALL_ARGS already has --frozen", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "--frozen")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).NotTo(ContainElement("--offline"))
+			Expect(args).To(ContainElement("--frozen"))
+		})
+
+		it("does not install missing toolchain components", func() {
+			rustup := mocks.Executable{}
+			rustup.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, _ = args.Get(0).(pexec.Execution).Stdout.Write([]byte("rustc-x86_64-unknown-linux-gnu\n"))
+			}).Return(nil)
+
+			logBuf := bytes.Buffer{}
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&logBuf)).WithRustup(&rustup)
+			Expect(runner.EnsureComponents(workingDir, []string{"clippy"})).To(Succeed())
+			rustup.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(logBuf.String()).To(ContainSubstring("WARNING: toolchain components [clippy] are missing and are not installed because BP_CARGO_OFFLINE is set"))
+		})
+	})
+
+	context("when BP_CARGO_COLOR is set", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_COLOR")).To(Succeed())
+		})
+
+		it("passes the color mode to cargo as --color and CARGO_TERM_COLOR", func() {
+			Expect(os.Setenv("BP_CARGO_COLOR", "always")).To(Succeed())
+
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Args).To(ContainElement("--color=always"))
+			Expect(execution.Args).NotTo(ContainElement("--color=never"))
+			Expect(execution.Env).To(ContainElement("CARGO_TERM_COLOR=always"))
+
+			args, _, err := cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--color=always"))
+		})
+
+		it("defaults to never", func() {
+			Expect(cargo.ColorMode()).To(Equal("never"))
+		})
+
+		it("rejects other values", func() {
+			Expect(os.Setenv("BP_CARGO_COLOR", "rainbow")).To(Succeed())
+
+			_, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+			Expect(err).To(MatchError(`invalid value for BP_CARGO_COLOR "rainbow", must be one of auto, always, never`))
+		})
+	})
+
+	context("when BP_CARGO_FEATURES is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_FEATURES", "metrics,json")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+		})
+
+		it("passes only the features the member defines", func() {
+			memberPath, err := filepath.Abs(filepath.Join("testdata", "workspace_features", "cli"))
+			Expect(err).NotTo(HaveOccurred())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{
+					"install",
+					"--locked",
+					"--color=never",
+					"--root=/some/location/2",
+					fmt.Sprintf("--path=%s", memberPath),
+					"--features=json",
+				})
+			})).Return(nil)
+
+			err = cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).InstallMember(gocontext.Background(), memberPath, workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			mockExe.AssertExpectations(t)
+		})
+		it("also disables the default features with BP_CARGO_NO_DEFAULT_FEATURES", func() {
+			Expect(os.Setenv("BP_CARGO_NO_DEFAULT_FEATURES", "true")).To(Succeed())
+			defer os.Unsetenv("BP_CARGO_NO_DEFAULT_FEATURES")
+
+			memberPath, err := filepath.Abs(filepath.Join("testdata", "workspace_features", "cli"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err = cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).InstallMember(gocontext.Background(), memberPath, workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Args[len(execution.Args)-2:]).To(Equal([]string{"--no-default-features", "--features=json"}))
+		})
+
+		it("passes only --all-features with BP_CARGO_ALL_FEATURES", func() {
+			Expect(os.Setenv("BP_CARGO_ALL_FEATURES", "true")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_NO_DEFAULT_FEATURES", "true")).To(Succeed())
+			defer os.Unsetenv("BP_CARGO_ALL_FEATURES")
+			defer os.Unsetenv("BP_CARGO_NO_DEFAULT_FEATURES")
+
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).InstallMember(gocontext.Background(), ".", workingDir, workLayer, destLayer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(execution.Args).To(ContainElement("--all-features"))
+			Expect(execution.Args).NotTo(ContainElement("--no-default-features"))
+			Expect(execution.Args).NotTo(ContainElement(HavePrefix("--features")))
+		})
+	})
+
+	context("when rust-toolchain.toml lists components", func() {
+		it("installs only the missing components", func() {
+			rustup := mocks.Executable{}
+			rustup.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return strings.Join(ex.Args, " ") == "component list --installed"
+			})).Run(func(args mock.Arguments) {
+				fmt.Fprintln(args.Get(0).(pexec.Execution).Stdout, "cargo-x86_64-unknown-linux-gnu\nclippy-x86_64-unknown-linux-gnu\nrustc-x86_64-unknown-linux-gnu")
+			}).Return(nil)
+			rustup.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return strings.Join(ex.Args, " ") == "component add rustfmt"
+			})).Return(nil)
+
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{})).WithRustup(&rustup)
+			Expect(runner.EnsureComponents(workingDir, []string{"clippy", "rustfmt"})).To(Succeed())
+			rustup.AssertExpectations(t)
+		})
+
+		it("fails with guidance when a component cannot be installed", func() {
+			rustup := mocks.Executable{}
+			rustup.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[1] == "list"
+			})).Return(nil)
+			rustup.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Args[1] == "add"
+			})).Return(fmt.Errorf("expected"))
+
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{})).WithRustup(&rustup)
+			err := runner.EnsureComponents(workingDir, []string{"rustfmt"})
+			Expect(err).To(MatchError(ContainSubstring("unable to install toolchain components [rustfmt] listed in rust-toolchain.toml")))
+		})
+
+		it("skips the check when rustup is not installed", func() {
+			rustup := mocks.Executable{}
+			rustup.On("Execute", mock.Anything).Return(&osexec.Error{Name: "rustup", Err: osexec.ErrNotFound})
+
+			buf := bytes.Buffer{}
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&buf)).WithRustup(&rustup)
+			Expect(runner.EnsureComponents(workingDir, []string{"rustfmt"})).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("rustup not found on PATH"))
+		})
+	})
+
+	context("when BP_CARGO_REGISTRY_PROTOCOL_FALLBACK is set", func() {
+		var (
+			logBuf    bytes.Buffer
+			protocols []string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_REGISTRY_PROTOCOL_FALLBACK", "true")).To(Succeed())
+			logBuf = bytes.Buffer{}
+			protocols = nil
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_REGISTRY_PROTOCOL_FALLBACK")).To(Succeed())
+		})
+
+		it("retries once with the git protocol when sparse resolution fails", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				protocol := cargo.RegistryProtocol(ex.Env)
+				protocols = append(protocols, protocol)
+				if protocol == "sparse" {
+					_, _ = ex.Stderr.Write([]byte("error: failed to get `serde` as a dependency of package `app`\n\n" +
+						"Caused by:\n  failed to query replaced source registry `crates-io`\n\n" +
+						"Caused by:\n  download of config.json failed\n"))
+					return fmt.Errorf("exit status 101")
+				}
+				return nil
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(protocols).To(Equal([]string{"sparse", "git"}))
+			Expect(logBuf.String()).To(ContainSubstring("Resolving dependencies with the sparse registry protocol failed, retrying with the git protocol"))
+		})
+
+		it("fails when the other protocol fails too", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				protocols = append(protocols, cargo.RegistryProtocol(ex.Env))
+				_, _ = ex.Stderr.Write([]byte("error: failed to update registry `crates-io`\n"))
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("build failed: exit status 101"))
+			Expect(protocols).To(Equal([]string{"sparse", "git"}))
+		})
+
+		it("does not retry when a manifest is broken", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				protocols = append(protocols, cargo.RegistryProtocol(ex.Env))
+				_, _ = ex.Stderr.Write([]byte("error: failed to get `shared` as a dependency of package `app`\n\n" +
+					"Caused by:\n  failed to load source for dependency `shared`\n\n" +
+					"Caused by:\n  failed to load manifest for dependency `shared`\n"))
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).To(HaveOccurred())
+			Expect(protocols).To(Equal([]string{"sparse"}))
+			Expect(logBuf.String()).ToNot(ContainSubstring("retrying"))
+		})
+
+		it("does not retry when the build fails for another reason", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				protocols = append(protocols, cargo.RegistryProtocol(ex.Env))
+				_, _ = ex.Stderr.Write([]byte("error[E0308]: mismatched types\n"))
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).To(HaveOccurred())
+			Expect(protocols).To(Equal([]string{"sparse"}))
+			Expect(logBuf.String()).ToNot(ContainSubstring("retrying"))
+		})
+	})
+
+	context("when BP_CARGO_RETRY_PATTERNS is set", func() {
+		var (
+			logBuf bytes.Buffer
+			calls  int
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_RETRY_PATTERNS", "failed to get 200 response, proc-macro .* panicked")).To(Succeed())
+			logBuf = bytes.Buffer{}
+			calls = 0
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_RETRY_PATTERNS")).To(Succeed())
+		})
+
+		it("retries the build once when the output matches a pattern", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				calls++
+				if calls == 1 {
+					_, _ = ex.Stderr.Write([]byte("error: failed to get 200 response from `https://index.crates.io/se/rd/serde`\n"))
+					return fmt.Errorf("exit status 101")
+				}
+				return nil
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal(2))
+			Expect(logBuf.String()).To(ContainSubstring(`Build failed with output matching "failed to get 200 response" from BP_CARGO_RETRY_PATTERNS, retrying once`))
+		})
+
+		it("fails when the retry fails too", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				calls++
+				_, _ = ex.Stderr.Write([]byte("error: proc-macro derive panicked\n"))
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("build failed: exit status 101"))
+			Expect(calls).To(Equal(2))
+		})
+
+		it("fails immediately when the output matches no pattern", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				calls++
+				_, _ = ex.Stderr.Write([]byte("error[E0308]: mismatched types\n"))
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Install(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("build failed: exit status 101"))
+			Expect(calls).To(Equal(1))
+			Expect(logBuf.String()).ToNot(ContainSubstring("retrying"))
+		})
+
+		it("rejects an invalid pattern", func() {
+			Expect(os.Setenv("BP_CARGO_RETRY_PATTERNS", "failed to (get")).To(Succeed())
+
+			_, err := cargo.RetryPatterns()
+			Expect(err).To(MatchError(ContainSubstring(`invalid pattern "failed to (get" in BP_CARGO_RETRY_PATTERNS`)))
+		})
+	})
+
+	context("when BP_CARGO_COVERAGE is set", func() {
+		var env []string
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_COVERAGE", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_COVERAGE")).To(Succeed())
+			Expect(os.Unsetenv("RUSTFLAGS")).To(Succeed())
+		})
+
+		install := func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)).To(Succeed())
+		}
+
+		it("adds the coverage flag to RUSTFLAGS", func() {
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C instrument-coverage"))
+		})
+
+		it("keeps the RUSTFLAGS that are already set", func() {
+			Expect(os.Setenv("RUSTFLAGS", "-C target-cpu=native")).To(Succeed())
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C target-cpu=native -C instrument-coverage"))
+			Expect(env).ToNot(ContainElement("RUSTFLAGS=-C target-cpu=native"))
+		})
+	})
+
+	context("when BP_CARGO_RUSTFLAGS is set", func() {
+		var (
+			env        []string
+			projectDir string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_RUSTFLAGS", "-C opt-level=3")).To(Succeed())
+
+			var err error
+			projectDir, err = ioutil.TempDir("", "project")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_RUSTFLAGS")).To(Succeed())
+			Expect(os.Unsetenv("RUSTFLAGS")).To(Succeed())
+			Expect(os.RemoveAll(projectDir)).To(Succeed())
+		})
+
+		install := func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(projectDir, workLayer, destLayer)).To(Succeed())
+		}
+
+		it("passes the flags as RUSTFLAGS", func() {
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C opt-level=3"))
+		})
+
+		it("keeps the build.rustflags of the project ahead of them", func() {
+			Expect(os.MkdirAll(filepath.Join(projectDir, ".cargo"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(projectDir, ".cargo", "config.toml"), []byte("[build]\nrustflags = [\"-C\", \"target-cpu=native\"]\n"), 0644)).To(Succeed())
+
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C target-cpu=native -C opt-level=3"))
+			Expect(cargo.EffectiveRustFlags(projectDir)).To(Equal("-C target-cpu=native -C opt-level=3"))
+		})
+
+		it("adds them to RUSTFLAGS from the environment, which cargo prefers over build.rustflags", func() {
+			Expect(os.MkdirAll(filepath.Join(projectDir, ".cargo"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(projectDir, ".cargo", "config.toml"), []byte("[build]\nrustflags = [\"-C\", \"target-cpu=native\"]\n"), 0644)).To(Succeed())
+			Expect(os.Setenv("RUSTFLAGS", "-C debuginfo=1")).To(Succeed())
+
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C debuginfo=1 -C opt-level=3"))
+		})
+	})
+
+	context("CheckToolchain", func() {
+		var (
+			path     string
+			toolsDir string
+		)
+
+		it.Before(func() {
+			path = os.Getenv("PATH")
+
+			var err error
+			toolsDir, err = ioutil.TempDir("", "tools")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Setenv("PATH", path)).To(Succeed())
+			Expect(os.RemoveAll(toolsDir)).To(Succeed())
+		})
+
+		it("succeeds when cargo and rustc are on the PATH", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "cargo"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "rustc"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+			Expect(cargo.CLIRunner{}.CheckToolchain()).To(Succeed())
+		})
+
+		it("names the tool that is missing", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "cargo"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+			err := cargo.CLIRunner{}.CheckToolchain()
+			Expect(err).To(MatchError(ContainSubstring("rustc not found on PATH, ensure that the Rust distribution buildpack ran before this one")))
+		})
+	})
+
+	context("when BP_CARGO_STRIP is set", func() {
+		var (
+			env      []string
+			path     string
+			toolsDir string
+		)
+
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_STRIP", "true")).To(Succeed())
+			path = os.Getenv("PATH")
+
+			var err error
+			toolsDir, err = ioutil.TempDir("", "tools")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_STRIP")).To(Succeed())
+			Expect(os.Setenv("PATH", path)).To(Succeed())
+			Expect(os.RemoveAll(toolsDir)).To(Succeed())
+		})
+
+		install := func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)).To(Succeed())
+		}
+
+		it("has rustc strip the symbols when strip is not on the PATH", func() {
+			install()
+			Expect(env).To(ContainElement("RUSTFLAGS=-C strip=symbols"))
+		})
+
+		it("leaves RUSTFLAGS alone when strip is on the PATH", func() {
+			Expect(ioutil.WriteFile(filepath.Join(toolsDir, "strip"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+			install()
+			for _, e := range env {
+				Expect(e).ToNot(HavePrefix("RUSTFLAGS="))
+			}
+		})
+	})
+
+	context("when HOME is not usable", func() {
+		var (
+			originalHome string
+			homeLayer    packit.Layer
+			env          []string
+		)
+
+		it.Before(func() {
+			originalHome = os.Getenv("HOME")
+
+			workDir, err := ioutil.TempDir("", "work-layer")
+			Expect(err).NotTo(HaveOccurred())
+			homeLayer = packit.Layer{Name: "work-layer", Path: workDir}
+		})
+
+		it.After(func() {
+			Expect(os.Setenv("HOME", originalHome)).To(Succeed())
+			Expect(os.RemoveAll(homeLayer.Path)).To(Succeed())
+		})
+
+		install := func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, homeLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		it("provides a writable HOME in the work layer when HOME is unset", func() {
+			Expect(os.Unsetenv("HOME")).To(Succeed())
+
+			install()
+			buildHome := filepath.Join(homeLayer.Path, cargo.BuildHomeDir)
+			Expect(env).To(ContainElement(fmt.Sprintf("HOME=%s", buildHome)))
+			Expect(buildHome).To(BeADirectory())
+			Expect(env).To(ContainElement(fmt.Sprintf("CARGO_HOME=%s", filepath.Join(homeLayer.Path, "home"))))
+		})
+
+		it("replaces a read-only HOME and keeps rustup's toolchains", func() {
+			if os.Geteuid() == 0 {
+				t.Skip("root can write to read-only directories")
+			}
+
+			readOnly := filepath.Join(homeLayer.Path, "read-only")
+			Expect(os.MkdirAll(filepath.Join(readOnly, ".rustup"), 0755)).To(Succeed())
+			Expect(os.Chmod(readOnly, 0555)).To(Succeed())
+			defer func() { Expect(os.Chmod(readOnly, 0755)).To(Succeed()) }()
+			Expect(os.Setenv("HOME", readOnly)).To(Succeed())
+
+			install()
+			Expect(env).To(ContainElement(fmt.Sprintf("HOME=%s", filepath.Join(homeLayer.Path, cargo.BuildHomeDir))))
+			Expect(env).NotTo(ContainElement(fmt.Sprintf("HOME=%s", readOnly)))
+			Expect(env).To(ContainElement(fmt.Sprintf("RUSTUP_HOME=%s", filepath.Join(readOnly, ".rustup"))))
+		})
+
+		it("leaves a writable HOME alone", func() {
+			Expect(os.Setenv("HOME", homeLayer.Path)).To(Succeed())
+
+			install()
+			Expect(env).To(ContainElement(fmt.Sprintf("HOME=%s", homeLayer.Path)))
+			Expect(filepath.Join(homeLayer.Path, cargo.BuildHomeDir)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("when BP_CARGO_CLEAN_ENV is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_CLEAN_ENV", "true")).To(Succeed())
+			Expect(os.Setenv("SOME_UNRELATED_VAR", "leaked")).To(Succeed())
+			Expect(os.Setenv("RUSTUP_HOME", "/some/rustup")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_CLEAN_ENV")).To(Succeed())
+			Expect(os.Unsetenv("SOME_UNRELATED_VAR")).To(Succeed())
+			Expect(os.Unsetenv("RUSTUP_HOME")).To(Succeed())
+		})
+
+		it("only passes allowlisted variables to cargo", func() {
+			logBuf := bytes.Buffer{}
+			logger := scribe.NewEmitter(&logBuf)
+
+			var env []string
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, logger).Install(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(env).ToNot(ContainElement("SOME_UNRELATED_VAR=leaked"))
+			Expect(env).ToNot(ContainElement("BP_CARGO_CLEAN_ENV=true"))
+			Expect(env).To(ContainElement("RUSTUP_HOME=/some/rustup"))
+			Expect(env).To(ContainElement("CARGO_TARGET_DIR=/some/location/rust-target"))
+			Expect(env).To(ContainElement("CARGO_HOME=/some/location/1/home"))
+			Expect(env).To(ContainElement(fmt.Sprintf("PATH=%s%c%s", os.Getenv("PATH"), os.PathListSeparator, "/some/location/2/bin")))
+		})
+	})
+
+	context("when BP_CARGO_MANIFEST_PATH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_MANIFEST_PATH", "app/Cargo.toml")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_MANIFEST_PATH")).To(Succeed())
+		})
+
+		it("passes the manifest to cargo commands that don't take their own path", func() {
+			srcDir := filepath.Join(workingDir, "app")
+
+			var executions []pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				executions = append(executions, args.Get(0).(pexec.Execution))
+			}).Return(nil)
+
+			runner := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+			Expect(runner.Fetch(srcDir, workLayer, destLayer)).To(Succeed())
+			Expect(runner.Test(srcDir, workLayer, destLayer)).To(Succeed())
+
+			Expect(executions).To(HaveLen(2))
+			Expect(executions[0].Args).To(Equal([]string{"fetch", "--color=never", "--manifest-path=/does/not/matter/app/Cargo.toml"}))
+			Expect(executions[1].Args).To(ContainElement("--manifest-path=/does/not/matter/app/Cargo.toml"))
+		})
+	})
+
+	context("when cleaning the build output", func() {
+		var (
+			srcDir     string
+			layersDir  string
+			cargoLayer packit.Layer
+			binLayer   packit.Layer
+		)
+
+		it.Before(func() {
+			var err error
+			srcDir, err = ioutil.TempDir("", "src")
+			Expect(err).NotTo(HaveOccurred())
+
+			layersDir, err = ioutil.TempDir("", "layers")
+			Expect(err).NotTo(HaveOccurred())
+
+			cargoLayer = packit.Layer{Name: "rust-cargo", Path: filepath.Join(layersDir, "rust-cargo")}
+			binLayer = packit.Layer{Name: "rust-bin", Path: filepath.Join(layersDir, "rust-bin")}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(srcDir)).To(Succeed())
+			Expect(os.RemoveAll(layersDir)).To(Succeed())
+		})
+
+		it("runs cargo clean on the rust-target layer", func() {
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-target", "release"), 0755)).To(Succeed())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return ex.Dir == srcDir && reflect.DeepEqual(ex.Args, []string{
+					"clean",
+					fmt.Sprintf("--target-dir=%s", filepath.Join(layersDir, "rust-target")),
+					"--color=never",
+				})
+			})).Return(nil)
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Clean(srcDir, cargoLayer, binLayer)).To(Succeed())
+			mockExe.AssertExpectations(t)
+		})
+
+		it("leaves a target directory in the project alone", func() {
+			Expect(os.MkdirAll(filepath.Join(srcDir, "target", "release"), 0755)).To(Succeed())
+
+			mockExe := mocks.Executable{}
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Clean(srcDir, cargoLayer, binLayer)).To(Succeed())
+			mockExe.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("does nothing when the rust-target layer is empty", func() {
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-target"), 0755)).To(Succeed())
+
+			mockExe := mocks.Executable{}
+
+			Expect(cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Clean(srcDir, cargoLayer, binLayer)).To(Succeed())
+			mockExe.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("fails when cargo clean fails", func() {
+			Expect(os.MkdirAll(filepath.Join(layersDir, "rust-target", "release"), 0755)).To(Succeed())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stderr.Write([]byte("error: could not find `Cargo.toml`"))
+				Expect(err).NotTo(HaveOccurred())
+				return fmt.Errorf("exit status 101")
+			})
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Clean(srcDir, cargoLayer, binLayer)
+			Expect(err).To(MatchError("clean failed: exit status 101\nerror: could not find `Cargo.toml`"))
+		})
+	})
+
+	context("when running the tests", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_PROFILE")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+		})
+
+		it("builds the test arguments with the release profile by default", func() {
+			args, err := cargo.CLIRunner{}.TestArgs(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"test", "--release", "--locked", "--color=never"}))
+		})
+
+		it("uses the profile and features of the build", func() {
+			srcDir, err := ioutil.TempDir("", "src")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(srcDir)
+
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, "Cargo.toml"),
+				[]byte("[package]\nname = \"app\"\n[features]\njson = []\n"), 0644)).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_PROFILE", "dev")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_FEATURES", "json")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.TestArgs(srcDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"test", "--profile=dev", "--locked", "--features=json", "--color=never"}))
+		})
+
+		it("runs cargo test in the work layer and fails when a test fails", func() {
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(fmt.Errorf("exit status 101"))
+
+			logBuf := bytes.Buffer{}
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Test(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("tests failed: exit status 101"))
+
+			Expect(execution.Dir).To(Equal(workingDir))
+			Expect(execution.Args).To(Equal([]string{"test", "--release", "--locked", "--color=never"}))
+			Expect(execution.Env).To(ContainElement("CARGO_TARGET_DIR=/some/location/rust-target"))
+			Expect(logBuf.String()).To(ContainSubstring("Running tests"))
+			Expect(logBuf.String()).To(ContainSubstring("cargo test --release --locked --color=never"))
+		})
+	})
+
+	context("when running clippy", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_CLIPPY_DENY_WARNINGS")).To(Succeed())
+		})
+
+		it("builds the clippy arguments like the test arguments", func() {
+			args, err := cargo.CLIRunner{}.ClippyArgs(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"clippy", "--release", "--locked", "--color=never"}))
+		})
+
+		it("denies warnings when BP_CARGO_CLIPPY_DENY_WARNINGS is set", func() {
+			Expect(os.Setenv("BP_CARGO_CLIPPY_DENY_WARNINGS", "true")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.ClippyArgs(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"clippy", "--release", "--locked", "--color=never", "--", "-D", "warnings"}))
+		})
+
+		it("runs cargo clippy in the work layer and fails when clippy fails", func() {
+			var executions []pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(e pexec.Execution) bool { return e.Args[1] == "--version" })).Return(nil)
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				executions = append(executions, args.Get(0).(pexec.Execution))
+			}).Return(fmt.Errorf("exit status 101"))
+
+			logBuf := bytes.Buffer{}
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf)).Clippy(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("clippy failed: exit status 101"))
+
+			Expect(executions).To(HaveLen(1))
+			Expect(executions[0].Dir).To(Equal(workingDir))
+			Expect(executions[0].Args).To(Equal([]string{"clippy", "--release", "--locked", "--color=never"}))
+			Expect(executions[0].Env).To(ContainElement("CARGO_TARGET_DIR=/some/location/rust-target"))
+			Expect(logBuf.String()).To(ContainSubstring("Running clippy"))
+			Expect(logBuf.String()).To(ContainSubstring("cargo clippy --release --locked --color=never"))
+		})
+
+		it("suggests adding the component when clippy isn't installed", func() {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(fmt.Errorf("exit status 101"))
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Clippy(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("clippy is not installed, add `clippy` to `components` in rust-toolchain.toml\nexit status 101"))
+			mockExe.AssertNumberOfCalls(t, "Execute", 1)
+		})
+	})
+
+	context("when BP_CARGO_HOME is set", func() {
+		var home string
+
+		it.Before(func() {
+			var err error
+			home, err = ioutil.TempDir("", "cargo-home")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.Setenv("BP_CARGO_HOME", home)).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_HTTP_TIMEOUT", "90")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_HOME")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_HTTP_TIMEOUT")).To(Succeed())
+			Expect(os.RemoveAll(home)).To(Succeed())
+		})
+
+		it("runs cargo with it as CARGO_HOME and passes the http settings through the environment", func() {
+			var env []string
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				env = args.Get(0).(pexec.Execution).Env
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(env).To(ContainElement(fmt.Sprintf("CARGO_HOME=%s", home)))
+			Expect(env).NotTo(ContainElement("CARGO_HOME=/some/location/1/home"))
+			Expect(env).To(ContainElement("CARGO_HTTP_TIMEOUT=90"))
+		})
+
+		it("leaves the cargo home alone when cleaning", func() {
+			Expect(os.MkdirAll(filepath.Join(home, "registry", "src"), 0755)).To(Succeed())
+
+			runner := cargo.NewCLIRunner(&mocks.Executable{}, scribe.NewEmitter(&bytes.Buffer{}))
+			Expect(runner.CleanCargoHomeCache(packit.Layer{Path: home})).To(Succeed())
+			Expect(filepath.Join(home, "registry", "src")).To(BeADirectory())
+		})
+	})
+
+	context("when selecting a package by name", func() {
+		var runner cargo.CLIRunner
+
+		it.Before(func() {
+			metadata, err := ioutil.ReadFile("testdata/metadata.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write(metadata)
+				return err
+			})
+
+			runner = cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_PACKAGE")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+		})
+
+		it("selects the member for a valid package", func() {
+			Expect(os.Setenv("BP_CARGO_PACKAGE", "cookie-auth")).To(Succeed())
+
+			urls, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			member, err := url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/session/cookie-auth")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls).To(Equal([]url.URL{*member}))
+		})
+
+		it("lists the available packages for an invalid package", func() {
+			Expect(os.Setenv("BP_CARGO_PACKAGE", "cookie-monster")).To(Succeed())
+
+			_, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("package cookie-monster not found in the workspace, available packages are [basics, ")))
+			Expect(err).To(MatchError(ContainSubstring("cookie-auth")))
+		})
+
+		it("matches the package name rather than the directory of the member", func() {
+			metadata := fmt.Sprintf(`{
+				"packages": [],
+				"workspace_members": [
+					"api-server 0.1.0 (path+file://%[1]s/api)",
+					"api 0.1.0 (path+file://%[1]s/server)"
+				]
+			}`, workingDir)
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+
+			Expect(os.Setenv("BP_CARGO_PACKAGE", "api")).To(Succeed())
+
+			urls, err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls).To(HaveLen(1))
+			Expect(urls[0].Path).To(Equal(filepath.Join(workingDir, "server")))
+		})
+
+		it("may not be combined with BP_CARGO_WORKSPACE_MEMBERS", func() {
+			Expect(os.Setenv("BP_CARGO_PACKAGE", "cookie-auth")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "basics")).To(Succeed())
+
+			_, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError("BP_CARGO_PACKAGE and BP_CARGO_WORKSPACE_MEMBERS may not be used together"))
+		})
+	})
+
+	context("when the workspace excludes a directory", func() {
+		it("never returns members inside it", func() {
+			logBuf := bytes.Buffer{}
+			logger := scribe.NewEmitter(&logBuf)
+
+			srcDir, err := filepath.Abs("testdata/workspace_exclude")
+			Expect(err).ToNot(HaveOccurred())
+
+			metadata := fmt.Sprintf(`{"packages": [], "workspace_members": [
+				"app 0.1.0 (path+file://%[1]s/crates/app)",
+				"legacy 0.1.0 (path+file://%[1]s/crates/legacy)"
+			]}`, srcDir)
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+
+			urls, err := cargo.NewCLIRunner(&mockExe, logger).WorkspaceMembers(srcDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls).To(HaveLen(1))
+			Expect(urls[0].Path).To(Equal(filepath.Join(srcDir, "crates", "app")))
+			Expect(logBuf.String()).To(ContainSubstring("Skipping legacy because it is listed in `[workspace].exclude`"))
+		})
+	})
+
+	context("when the workspace has default-members", func() {
+		var (
+			srcDir   string
+			metadata string
+		)
+
+		it.Before(func() {
+			var err error
+			srcDir, err = filepath.Abs("testdata/workspace_default_members")
+			Expect(err).ToNot(HaveOccurred())
+
+			metadata = fmt.Sprintf(`{"packages": [], "workspace_members": [
+				"api 0.1.0 (path+file://%[1]s/crates/api)",
+				"tools 0.1.0 (path+file://%[1]s/crates/tools)",
+				"worker 0.1.0 (path+file://%[1]s/crates/worker)"
+			]}`, srcDir)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+		})
+
+		members := func(logger scribe.Emitter) []string {
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write([]byte(metadata))
+				return err
+			})
+
+			urls, err := cargo.NewCLIRunner(&mockExe, logger).WorkspaceMembers(srcDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			var paths []string
+			for _, u := range urls {
+				paths = append(paths, u.Path)
+			}
+			return paths
+		}
+
+		it("only returns the default members", func() {
+			logBuf := bytes.Buffer{}
+
+			Expect(members(scribe.NewEmitter(&logBuf))).To(Equal([]string{
+				filepath.Join(srcDir, "crates", "api"),
+				filepath.Join(srcDir, "crates", "worker"),
+			}))
+			Expect(logBuf.String()).To(ContainSubstring("Building the default-members of the workspace [api, worker], set BP_CARGO_WORKSPACE_MEMBERS to build others"))
+		})
+
+		it("returns the members from BP_CARGO_WORKSPACE_MEMBERS instead", func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "tools")).To(Succeed())
+			logBuf := bytes.Buffer{}
+
+			Expect(members(scribe.NewEmitter(&logBuf))).To(Equal([]string{filepath.Join(srcDir, "crates", "tools")}))
+			Expect(logBuf.String()).NotTo(ContainSubstring("default-members"))
+		})
+	})
+
+	context("when specifying a subset of workspace members", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "cookie-auth,protobuf-example, async_data_factory,hello-world")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+		})
+
+		it("filters workspace members", func() {
+			logBuf := bytes.Buffer{}
+			logger := scribe.NewEmitter(&logBuf)
+
+			metadata, err := ioutil.ReadFile("testdata/metadata.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				Expect(ex.Args).To(Equal([]string{"metadata", "--format-version=1", "--no-deps"}))
+				return true
+			})).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write(metadata)
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			runner := cargo.NewCLIRunner(&mockExe, logger)
+			urls, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(urls).To(HaveLen(4))
+
+			url, err := url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/basics/hello%20world")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls[0]).To(Equal(*url))
+
+			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/other/data_factory")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls[1]).To(Equal(*url))
+
+			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/other/protobuf")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls[2]).To(Equal(*url))
+
+			url, err = url.Parse("path+file:///Users/dmikusa/Code/Rust/actix-examples/session/cookie-auth")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(urls[3]).To(Equal(*url))
+		})
+
+		it("matches globs against member names and paths", func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "session/*, template-t*")).To(Succeed())
+
+			metadata, err := ioutil.ReadFile("testdata/metadata.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write(metadata)
+				return err
+			})
+
+			runner := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+			urls, err := runner.WorkspaceMembers("/Users/dmikusa/Code/Rust/actix-examples", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			var paths []string
+			for _, u := range urls {
+				paths = append(paths, u.Path)
+			}
+			Expect(paths).To(Equal([]string{
+				"/Users/dmikusa/Code/Rust/actix-examples/session/cookie-auth",
+				"/Users/dmikusa/Code/Rust/actix-examples/session/cookie-session",
+				"/Users/dmikusa/Code/Rust/actix-examples/session/redis-session",
+				"/Users/dmikusa/Code/Rust/actix-examples/template_engines/tera",
+				"/Users/dmikusa/Code/Rust/actix-examples/template_engines/tinytemplate",
+			}))
+		})
+
+		it("fails before building anything when a requested member does not exist", func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "cookie-auth,missing-member")).To(Succeed())
+
+			metadata, err := ioutil.ReadFile("testdata/metadata.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				_, err := ex.Stdout.Write(metadata)
+				return err
+			})
+
+			runner := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+			urls, err := runner.WorkspaceMembers(workingDir, workLayer, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("BP_CARGO_WORKSPACE_MEMBERS lists [missing-member], which did not match any workspace member, available members are [basics, docker_sample,")))
+			Expect(urls).To(BeEmpty())
 		})
 	})
 }
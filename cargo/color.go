@@ -0,0 +1,27 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ColorModes are the values accepted by BP_CARGO_COLOR
+var ColorModes = []string{"auto", "always", "never"}
+
+// DefaultColorMode keeps ANSI codes out of build logs
+const DefaultColorMode = "never"
+
+// ColorMode returns the cargo color mode from BP_CARGO_COLOR, which defaults to DefaultColorMode
+func ColorMode() (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("BP_CARGO_COLOR")))
+	if mode == "" {
+		return DefaultColorMode, nil
+	}
+
+	if !contains(ColorModes, mode) {
+		return "", fmt.Errorf("invalid value for BP_CARGO_COLOR %q, must be one of %s", mode, strings.Join(ColorModes, ", "))
+	}
+
+	return mode, nil
+}
@@ -0,0 +1,106 @@
+package cargo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/pexec"
+)
+
+// ContextExecutable is implemented by executables that stop the process they started when ctx is done, rather than
+// leaving it running in the background
+type ContextExecutable interface {
+	ExecuteContext(ctx context.Context, execution pexec.Execution) error
+}
+
+// CommandExecutable runs an executable like pexec.Executable does, but it looks the executable up on the PATH of the
+// execution without changing the PATH of the buildpack process, and it can be stopped with a context
+type CommandExecutable struct {
+	name string
+}
+
+// NewCommandExecutable returns a CommandExecutable for name, which is looked up on the PATH unless it is a path
+func NewCommandExecutable(name string) CommandExecutable {
+	return CommandExecutable{name: name}
+}
+
+// Execute runs the executable and waits for it to exit
+func (e CommandExecutable) Execute(execution pexec.Execution) error {
+	return e.ExecuteContext(context.Background(), execution)
+}
+
+// ExecuteContext runs the executable and waits for it to exit. When ctx is done first, the process and everything it
+// started are killed, and ctx's error is returned once they are gone.
+func (e CommandExecutable) ExecuteContext(ctx context.Context, execution pexec.Execution) error {
+	path := os.Getenv("PATH")
+	if execution.Env != nil {
+		if value := lookupEnv(execution.Env, "PATH"); value != "" {
+			path = value
+		}
+	}
+
+	executable, err := lookPath(e.name, path)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, execution.Args...)
+	cmd.Dir = execution.Dir
+	if len(execution.Env) > 0 {
+		cmd.Env = execution.Env
+	}
+	cmd.Stdout = execution.Stdout
+	cmd.Stderr = execution.Stderr
+	startProcessGroup(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// lookPath finds name in the directories of path, like exec.LookPath does with the PATH environment variable
+func lookPath(name string, path string) (string, error) {
+	if strings.Contains(name, string(filepath.Separator)) {
+		if isExecutable(name) {
+			return name, nil
+		}
+		return "", fmt.Errorf("exec: %q: %w", name, exec.ErrNotFound)
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+
+		candidate := filepath.Join(dir, name)
+		if isExecutable(candidate) {
+			return candidate, nil
+		}
+	}
+
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0111 != 0
+}
@@ -0,0 +1,16 @@
+package cargo
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup runs cmd in a process group of its own, so that the compilers cargo starts can be killed with it
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills cmd and every process in its process group
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package cargo
+
+import "os/exec"
+
+func startProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,72 @@
+package cargo_test
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCommand(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("runs the executable found on the PATH of the execution", func() {
+		binDir := filepath.Join(workingDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "greet"), []byte("#!/bin/sh\necho hello $1\n"), 0755)).To(Succeed())
+
+		path := os.Getenv("PATH")
+		stdout := bytes.Buffer{}
+		err := cargo.NewCommandExecutable("greet").Execute(pexec.Execution{
+			Args:   []string{"world"},
+			Env:    []string{"PATH=" + binDir + ":/usr/bin:/bin"},
+			Stdout: &stdout,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout.String()).To(Equal("hello world\n"))
+		Expect(os.Getenv("PATH")).To(Equal(path))
+	})
+
+	it("fails when the executable is not on the PATH", func() {
+		err := cargo.NewCommandExecutable("does-not-exist").Execute(pexec.Execution{Env: []string{"PATH=" + workingDir}})
+		Expect(err).To(MatchError(ContainSubstring("executable file not found")))
+	})
+
+	it("kills the process and the processes it started when the context is done", func() {
+		marker := filepath.Join(workingDir, "marker")
+
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := cargo.NewCommandExecutable("/bin/sh").ExecuteContext(ctx, pexec.Execution{
+			Args: []string{"-c", "(sleep 1; touch " + marker + ") & wait"},
+		})
+		Expect(err).To(MatchError(gocontext.DeadlineExceeded))
+
+		time.Sleep(1500 * time.Millisecond)
+		Expect(marker).NotTo(BeAnExistingFile())
+	})
+}
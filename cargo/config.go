@@ -0,0 +1,149 @@
+package cargo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KnownEnvironmentVariables lists every BP_CARGO_* variable understood by the buildpack
+var KnownEnvironmentVariables = []string{
+	"BP_CARGO_ALL_FEATURES",
+	"BP_CARGO_ARGS_FILE",
+	"BP_CARGO_ASSUME_BINARY",
+	"BP_CARGO_BIN_LAYER_NAME",
+	"BP_CARGO_CACHE_ONLY",
+	"BP_CARGO_CLEAN",
+	"BP_CARGO_CLEAN_ENV",
+	"BP_CARGO_CLIPPY_DENY_WARNINGS",
+	"BP_CARGO_COLOR",
+	"BP_CARGO_COVERAGE",
+	"BP_CARGO_DEFAULT_BACKTRACE",
+	"BP_CARGO_DISABLE_CACHE",
+	"BP_CARGO_DRY_RUN",
+	"BP_CARGO_EMIT_CHECKSUMS",
+	"BP_CARGO_EMIT_DEPGRAPH",
+	"BP_CARGO_EMIT_OTEL",
+	"BP_CARGO_EMIT_PROCESSES",
+	"BP_CARGO_EMIT_SBOM",
+	"BP_CARGO_EXTRA_LAUNCH_BINS",
+	"BP_CARGO_FEATURES",
+	"BP_CARGO_GIT_SHA",
+	"BP_CARGO_HOME",
+	"BP_CARGO_HTTP_MULTIPLEXING",
+	"BP_CARGO_HTTP_TIMEOUT",
+	"BP_CARGO_INCLUDE_FILES",
+	"BP_CARGO_IONICE",
+	"BP_CARGO_INSTALL_ARGS",
+	"BP_CARGO_INSTALL_BINS",
+	"BP_CARGO_INSTALL_EXAMPLES",
+	"BP_CARGO_INSTALL_METHOD",
+	"BP_CARGO_LAUNCH_BIN",
+	"BP_CARGO_LOCKED",
+	"BP_CARGO_LTO",
+	"BP_CARGO_MANIFEST_PATH",
+	"BP_CARGO_MAX_IMAGE_SIZE",
+	"BP_CARGO_MEMBER_TIMEOUT",
+	"BP_CARGO_NICE",
+	"BP_CARGO_NO_DEFAULT_FEATURES",
+	"BP_CARGO_OFFLINE",
+	"BP_CARGO_OPT_LEVEL",
+	"BP_CARGO_PACKAGE",
+	"BP_CARGO_PREFETCH",
+	"BP_CARGO_PROFILE",
+	"BP_CARGO_PROJECT_PATH",
+	"BP_CARGO_REGISTRY_PROTOCOL_FALLBACK",
+	"BP_CARGO_RENAME_BIN",
+	"BP_CARGO_REQUIRE_REPRODUCIBLE_SOURCES",
+	"BP_CARGO_RETRY_PATTERNS",
+	"BP_CARGO_RUN_CLIPPY",
+	"BP_CARGO_RUN_TESTS",
+	"BP_CARGO_RUSTFLAGS",
+	"BP_CARGO_SBOM_EXCLUDE",
+	"BP_CARGO_SBOM_WITH_HASHES",
+	"BP_CARGO_SCCACHE",
+	"BP_CARGO_SKIP_UNPUBLISHED",
+	"BP_CARGO_STREAM_MEMBERS",
+	"BP_CARGO_STRICT_CONFIG",
+	"BP_CARGO_STRIP",
+	"BP_CARGO_STRIP_NON_BIN",
+	"BP_CARGO_TARGET",
+	"BP_CARGO_TIMESTAMP_FORMAT",
+	"BP_CARGO_UPX",
+	"BP_CARGO_UPX_ARGS",
+	"BP_CARGO_USE_JOBSERVER",
+	"BP_CARGO_VALIDATE_CMD",
+	"BP_CARGO_VALIDATE_TIMEOUT",
+	"BP_CARGO_VERBOSE",
+	"BP_CARGO_VERSION_FILE",
+	"BP_CARGO_WORKSPACE_MEMBERS",
+}
+
+// ValidateEnvironment checks every BP_CARGO_* variable in environ against KnownEnvironmentVariables, suggesting
+// the closest known name for any that are not recognized
+func ValidateEnvironment(environ []string) error {
+	var unknown []string
+	for _, entry := range environ {
+		name := strings.SplitN(entry, "=", 2)[0]
+		if !strings.HasPrefix(name, "BP_CARGO_") || contains(KnownEnvironmentVariables, name) {
+			continue
+		}
+
+		if suggestion := closestMatch(name, KnownEnvironmentVariables); suggestion != "" {
+			unknown = append(unknown, fmt.Sprintf("%s (did you mean %s?)", name, suggestion))
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unrecognized environment variables with BP_CARGO_STRICT_CONFIG enabled:\n  %s",
+			strings.Join(unknown, "\n  "))
+	}
+
+	return nil
+}
+
+// closestMatch returns the candidate nearest to name, or an empty string when nothing is close enough to be a typo
+func closestMatch(name string, candidates []string) string {
+	best, bestDistance := "", 4
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,38 @@
+package cargo_test
+
+import (
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testConfig(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("accepts known variables and ignores other variables", func() {
+		Expect(cargo.ValidateEnvironment([]string{
+			"BP_CARGO_INSTALL_ARGS=--locked",
+			"BP_CARGO_UPX=true",
+			"BP_OTHER_SETTING=1",
+			"PATH=/usr/bin",
+		})).To(Succeed())
+	})
+
+	it("suggests the closest known variable for a typo", func() {
+		err := cargo.ValidateEnvironment([]string{"BP_CARGO_LAUNCH_BNI=app"})
+		Expect(err).To(MatchError(ContainSubstring("BP_CARGO_LAUNCH_BNI (did you mean BP_CARGO_LAUNCH_BIN?)")))
+	})
+
+	it("lists every unrecognized variable", func() {
+		err := cargo.ValidateEnvironment([]string{
+			"BP_CARGO_UPX_ARG=--best",
+			"BP_CARGO_SOMETHING_ELSE_ENTIRELY=1",
+		})
+		Expect(err).To(MatchError("unrecognized environment variables with BP_CARGO_STRICT_CONFIG enabled:\n" +
+			"  BP_CARGO_SOMETHING_ELSE_ENTIRELY\n" +
+			"  BP_CARGO_UPX_ARG (did you mean BP_CARGO_UPX_ARGS?)"))
+	})
+}
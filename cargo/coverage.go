@@ -0,0 +1,18 @@
+package cargo
+
+import (
+	"github.com/paketo-buildpacks/packit"
+)
+
+// CoverageRustFlags are added to RUSTFLAGS when BP_CARGO_COVERAGE is enabled
+const CoverageRustFlags = "-C instrument-coverage"
+
+// DefaultLLVMProfileFile is where instrumented binaries write their coverage data at runtime, `%p` is replaced by
+// the process id and `%m` by a unique id for the binary so concurrent processes do not overwrite each other
+const DefaultLLVMProfileFile = "/tmp/coverage/%p-%m.profraw"
+
+// ConfigureCoverage sets up the launch environment of binaryLayer so that instrumented binaries emit their coverage
+// data, users may override the location by setting LLVM_PROFILE_FILE themselves
+func ConfigureCoverage(binaryLayer *packit.Layer) {
+	binaryLayer.LaunchEnv.Default("LLVM_PROFILE_FILE", DefaultLLVMProfileFile)
+}
@@ -0,0 +1,109 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DependencyGraphFile is the name of the file, inside the rust-depgraph layer, that holds the dependency graph
+const DependencyGraphFile = "dependencies.dot"
+
+type resolveDependency struct {
+	Name string `json:"name"`
+	Pkg  string `json:"pkg"`
+}
+
+type resolveNode struct {
+	ID   string              `json:"id"`
+	Deps []resolveDependency `json:"deps"`
+}
+
+type resolve struct {
+	Nodes []resolveNode `json:"nodes"`
+}
+
+// DependencyGraph renders the resolved dependency graph in Graphviz DOT format. Workspace members are drawn as
+// boxes, their direct dependencies with a solid outline and transitive dependencies with a dashed outline.
+func (m metadata) DependencyGraph() (string, error) {
+	if m.Resolve == nil {
+		return "", fmt.Errorf("cargo metadata does not include a resolved dependency graph")
+	}
+
+	packages := make(map[string]metadataPackage)
+	for _, pkg := range m.Packages {
+		packages[pkg.ID] = pkg
+	}
+
+	nodes := make(map[string]resolveNode)
+	for _, node := range m.Resolve.Nodes {
+		nodes[node.ID] = node
+	}
+
+	direct := make(map[string]bool)
+	for _, member := range m.WorkspaceMembers {
+		for _, dep := range nodes[member].Deps {
+			direct[dep.Pkg] = true
+		}
+	}
+
+	var ids []string
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var graph strings.Builder
+	graph.WriteString("digraph dependencies {\n")
+	graph.WriteString("  node [shape=ellipse];\n")
+
+	for _, id := range ids {
+		style := "style=dashed"
+		switch {
+		case contains(m.WorkspaceMembers, id):
+			style = "shape=box, style=bold"
+		case direct[id]:
+			style = "style=solid"
+		}
+
+		name, version := id, ""
+		if pkg, ok := packages[id]; ok {
+			name, version = pkg.Name, pkg.Version
+		}
+
+		fmt.Fprintf(&graph, "  %q [label=%q, %s];\n", id, fmt.Sprintf("%s %s", name, version), style)
+	}
+
+	for _, id := range ids {
+		var deps []string
+		for _, dep := range nodes[id].Deps {
+			deps = append(deps, dep.Pkg)
+		}
+		sort.Strings(deps)
+
+		for _, dep := range deps {
+			fmt.Fprintf(&graph, "  %q -> %q;\n", id, dep)
+		}
+	}
+
+	graph.WriteString("}\n")
+
+	return graph.String(), nil
+}
+
+// WriteDependencyGraph writes the DOT graph into dir
+func WriteDependencyGraph(dir string, graph string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, DependencyGraphFile), []byte(graph), 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write dependency graph\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/dmikusa/rust-cargo-cnb/cargo/mocks"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDepGraph(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect    = NewWithT(t).Expect
+		workLayer = packit.Layer{Name: "work-layer", Path: "/some/location/1"}
+		destLayer = packit.Layer{Name: "dest-layer", Path: "/some/location/2"}
+	)
+
+	runnerFor := func(fixture string, args *[]string) cargo.CLIRunner {
+		metadata, err := ioutil.ReadFile(fixture)
+		Expect(err).ToNot(HaveOccurred())
+
+		mockExe := mocks.Executable{}
+		mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+			*args = ex.Args
+			_, err := ex.Stdout.Write(metadata)
+			return err
+		})
+
+		return cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+	}
+
+	it("renders the resolved dependencies as DOT", func() {
+		var args []string
+		graph, err := runnerFor("testdata/metadata_resolve.json", &args).DependencyGraph("/workspace", workLayer, destLayer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(args).To(Equal([]string{"metadata", "--format-version=1"}))
+
+		Expect(graph).To(Equal(`digraph dependencies {
+  node [shape=ellipse];
+  "app 0.1.0 (path+file:///workspace)" [label="app 0.1.0", shape=box, style=bold];
+  "serde 1.0.130 (registry+https://github.com/rust-lang/crates.io-index)" [label="serde 1.0.130", style=solid];
+  "serde_derive 1.0.130 (registry+https://github.com/rust-lang/crates.io-index)" [label="serde_derive 1.0.130", style=dashed];
+  "app 0.1.0 (path+file:///workspace)" -> "serde 1.0.130 (registry+https://github.com/rust-lang/crates.io-index)";
+  "serde 1.0.130 (registry+https://github.com/rust-lang/crates.io-index)" -> "serde_derive 1.0.130 (registry+https://github.com/rust-lang/crates.io-index)";
+}
+`))
+	})
+
+	it("fails when the metadata has no resolve graph", func() {
+		var args []string
+		_, err := runnerFor("testdata/metadata.json", &args).DependencyGraph("/workspace", workLayer, destLayer)
+		Expect(err).To(MatchError("cargo metadata does not include a resolved dependency graph"))
+	})
+
+	context("when listing the packages compiled for the build target", func() {
+		var calls [][]string
+
+		targetRunner := func() cargo.CLIRunner {
+			metadata, err := ioutil.ReadFile("testdata/target_deps/metadata_linux.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			calls = nil
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+				calls = append(calls, ex.Args)
+				if ex.Args[0] == "-vV" {
+					_, err := ex.Stdout.Write([]byte("cargo 1.56.0 (4ed5d137b 2021-10-04)\nrelease: 1.56.0\nhost: x86_64-unknown-linux-gnu\n"))
+					return err
+				}
+				_, err := ex.Stdout.Write(metadata)
+				return err
+			})
+
+			return cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{}))
+		}
+
+		it("resolves the dependencies for the host", func() {
+			packages, err := targetRunner().TargetPackages("testdata/target_deps", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal([][]string{
+				{"-vV"},
+				{"metadata", "--format-version=1", "--filter-platform=x86_64-unknown-linux-gnu"},
+			}))
+			Expect(packages).To(Equal([]string{"app 0.1.0", "libc 0.2.101", "serde 1.0.130"}))
+		})
+
+		context("and BP_CARGO_TARGET is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_CARGO_TARGET", "x86_64-unknown-linux-musl")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_CARGO_TARGET")).To(Succeed())
+			})
+
+			it("resolves the dependencies for that target", func() {
+				_, err := targetRunner().TargetPackages("testdata/target_deps", workLayer, destLayer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(calls).To(Equal([][]string{
+					{"metadata", "--format-version=1", "--filter-platform=x86_64-unknown-linux-musl"},
+				}))
+			})
+		})
+	})
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
 )
 
 // PlanDependencyRustCargo is the name of the plan
@@ -16,26 +19,57 @@ const PlanDependencyRustCargo = "rust-cargo"
 type BuildPlanMetadata struct {
 	VersionSource string `toml:"version-source"`
 	Version       string `toml:"version"`
+	ToolchainFile string `toml:"toolchain-file,omitempty"`
 }
 
 // Detect if the Rust binaries should be delivered
-func Detect() packit.DetectFunc {
+func Detect(logger scribe.Emitter) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
-		_, err := os.Stat(filepath.Join(context.WorkingDir, "Cargo.toml"))
+		projectDir, err := ProjectDir(context.WorkingDir)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		err = logManifestChoice(context.WorkingDir, projectDir, logger)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		_, err = os.Stat(filepath.Join(projectDir, "Cargo.toml"))
 		cargoTomlFound := err == nil
 		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return packit.DetectResult{}, err
 		}
 
-		_, err = os.Stat(filepath.Join(context.WorkingDir, "Cargo.lock"))
-		cargoLockFound := err == nil
-		if err != nil && !errors.Is(err, os.ErrNotExist) {
+		// not a Cargo project, so detection fails rather than erroring. An empty Cargo.toml still passes, cargo reports
+		// what is wrong with it during the build. A missing Cargo.lock is reported by the build, where BP_CARGO_LOCKED
+		// decides whether one is needed.
+		if !cargoTomlFound {
+			return packit.DetectResult{}, packit.Fail.WithMessage("Missing Cargo.toml, required")
+		}
+
+		hasBinary, err := HasBinaryTarget(projectDir)
+		if err != nil {
 			return packit.DetectResult{}, err
 		}
 
-		if !cargoTomlFound || !cargoLockFound {
-			//lint:ignore ST1005 Reads nicer when displayed to end user with leading capital letter
-			return packit.DetectResult{}, fmt.Errorf("Missing [Cargo.toml: %v, Cargo.lock: %v], both required", !cargoTomlFound, !cargoLockFound)
+		if !hasBinary {
+			assumeBinary, err := ParseBoolEnv("BP_CARGO_ASSUME_BINARY")
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if !assumeBinary {
+				// a library has nothing for this buildpack to build, so detection fails rather than erroring
+				return packit.DetectResult{}, packit.Fail.WithMessage("No binary target found, Cargo.toml has no [[bin]] and there is no src/main.rs or src/bin, set BP_CARGO_ASSUME_BINARY=true if sources are generated before the build")
+			}
+
+			logger.Subprocess("No binary target found, continuing because BP_CARGO_ASSUME_BINARY is set")
+		}
+
+		rustMetadata, err := RustRequirement(projectDir)
+		if err != nil {
+			return packit.DetectResult{}, err
 		}
 
 		return packit.DetectResult{
@@ -46,14 +80,206 @@ func Detect() packit.DetectFunc {
 				Requires: []packit.BuildPlanRequirement{
 					{Name: PlanDependencyRustCargo},
 					{
-						Name: "rust",
-						Metadata: BuildPlanMetadata{
-							Version:       "",
-							VersionSource: "CARGO",
-						},
+						Name:     "rust",
+						Metadata: rustMetadata,
 					},
 				},
 			},
 		}, nil
 	}
 }
+
+var versionPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+
+// normalizeRustVersion adds the patch version to a version without one, so that 1.60 and 1.60.0 compare equal.
+// Named channels are returned as they are.
+func normalizeRustVersion(version string) string {
+	if versionPattern.MatchString(version) && strings.Count(version, ".") == 1 {
+		return version + ".0"
+	}
+	return version
+}
+
+// RustRequirement builds the metadata for the `rust` plan requirement, deriving a version
+// constraint from BP_RUST_VERSION, `rust-toolchain.toml`, `rust-toolchain` or the MSRV in Cargo.toml, in that order
+func RustRequirement(workingDir string) (BuildPlanMetadata, error) {
+	toolchainFile, err := FindToolchainFile(workingDir)
+	if err != nil {
+		return BuildPlanMetadata{}, err
+	}
+
+	if version := strings.TrimSpace(os.Getenv("BP_RUST_VERSION")); version != "" {
+		// rustup follows the toolchain file no matter which toolchain is installed, so the two have to agree
+		if toolchainFile != "" {
+			toolchain, err := ParseToolchainFile(filepath.Join(workingDir, toolchainFile))
+			if err != nil {
+				return BuildPlanMetadata{}, err
+			}
+
+			if toolchain.Channel != "" && normalizeRustVersion(toolchain.Channel) != normalizeRustVersion(version) {
+				return BuildPlanMetadata{}, fmt.Errorf("BP_RUST_VERSION is %q but %s pins the %q channel, unset one of them or set both to the same version. "+
+					"The Rust version is taken from BP_RUST_VERSION, then rust-toolchain.toml, then rust-toolchain, then the rust-version in Cargo.toml",
+					version, toolchainFile, toolchain.Channel)
+			}
+		}
+
+		return BuildPlanMetadata{Version: version, VersionSource: "BP_RUST_VERSION", ToolchainFile: toolchainFile}, nil
+	}
+
+	for _, name := range ToolchainFiles {
+		toolchain, err := ParseToolchainFile(filepath.Join(workingDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return BuildPlanMetadata{}, err
+		}
+
+		if versionPattern.MatchString(toolchain.Channel) {
+			return BuildPlanMetadata{Version: toolchain.Channel, VersionSource: name, ToolchainFile: toolchainFile}, nil
+		}
+	}
+
+	manifest, err := ParseManifest(filepath.Join(workingDir, "Cargo.toml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return BuildPlanMetadata{}, err
+	}
+
+	if manifest.Package != nil && versionPattern.MatchString(manifest.Package.RustVersion) {
+		return BuildPlanMetadata{Version: fmt.Sprintf(">= %s", manifest.Package.RustVersion), VersionSource: "Cargo.toml", ToolchainFile: toolchainFile}, nil
+	}
+
+	return BuildPlanMetadata{VersionSource: "CARGO", ToolchainFile: toolchainFile}, nil
+}
+
+// ToolchainComponents returns the `components` listed in the project's toolchain file
+func ToolchainComponents(projectDir string) ([]string, error) {
+	toolchain, err := LoadToolchain(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return toolchain.Components, nil
+}
+
+// ProjectDir returns the directory containing the project to build, which is the working directory unless
+// BP_CARGO_PROJECT_PATH or BP_CARGO_MANIFEST_PATH is set
+func ProjectDir(workingDir string) (string, error) {
+	manifestPath, err := ManifestPath(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	projectPath, ok := os.LookupEnv("BP_CARGO_PROJECT_PATH")
+	if manifestPath != "" {
+		if ok && projectPath != "" {
+			return "", fmt.Errorf("BP_CARGO_MANIFEST_PATH and BP_CARGO_PROJECT_PATH cannot both be set")
+		}
+		return filepath.Dir(manifestPath), nil
+	}
+
+	if !ok || projectPath == "" {
+		return workingDir, nil
+	}
+
+	if filepath.IsAbs(projectPath) {
+		return "", fmt.Errorf("BP_CARGO_PROJECT_PATH must be relative to the application root, got %s", projectPath)
+	}
+
+	projectDir := filepath.Join(workingDir, projectPath)
+	if rel, err := filepath.Rel(workingDir, projectDir); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("BP_CARGO_PROJECT_PATH must not point outside of the application root, got %s", projectPath)
+	}
+
+	return projectDir, nil
+}
+
+// ManifestPath returns the Cargo.toml that BP_CARGO_MANIFEST_PATH points at, relative to workingDir, or an empty
+// string when it is not set
+func ManifestPath(workingDir string) (string, error) {
+	path, ok := os.LookupEnv("BP_CARGO_MANIFEST_PATH")
+	if !ok || path == "" {
+		return "", nil
+	}
+
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("BP_CARGO_MANIFEST_PATH must be relative to the application root, got %s", path)
+	}
+
+	manifestPath := filepath.Join(workingDir, path)
+	if rel, err := filepath.Rel(workingDir, manifestPath); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("BP_CARGO_MANIFEST_PATH must not point outside of the application root, got %s", path)
+	}
+
+	// like cargo, only a file named Cargo.toml is accepted as a manifest
+	if filepath.Base(manifestPath) != "Cargo.toml" {
+		return "", fmt.Errorf("BP_CARGO_MANIFEST_PATH must point at a Cargo.toml file, got %s", path)
+	}
+
+	return manifestPath, nil
+}
+
+// logManifestChoice logs which Cargo.toml is used when more than one top-level manifest could be built
+func logManifestChoice(workingDir string, projectDir string, logger scribe.Emitter) error {
+	var candidates []string
+	if _, err := os.Stat(filepath.Join(workingDir, "Cargo.toml")); err == nil {
+		candidates = append(candidates, "Cargo.toml")
+	}
+
+	root, err := ParseManifest(filepath.Join(workingDir, "Cargo.toml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	files, err := os.ReadDir(workingDir)
+	if err != nil {
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") || file.Name() == "target" {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(workingDir, file.Name(), "Cargo.toml")); err != nil {
+			continue
+		}
+
+		if root.Workspace != nil && matchesAny(root.Workspace.Members, file.Name()) {
+			continue
+		}
+
+		candidates = append(candidates, filepath.Join(file.Name(), "Cargo.toml"))
+	}
+
+	if len(candidates) < 2 {
+		return nil
+	}
+
+	chosen, err := filepath.Rel(workingDir, filepath.Join(projectDir, "Cargo.toml"))
+	if err != nil {
+		return err
+	}
+
+	logger.Subprocess("Found multiple Cargo.toml candidates: %s", strings.Join(candidates, ", "))
+	if projectDir != workingDir {
+		setting := "BP_CARGO_PROJECT_PATH"
+		if os.Getenv("BP_CARGO_MANIFEST_PATH") != "" {
+			setting = "BP_CARGO_MANIFEST_PATH"
+		}
+		logger.Subprocess("Using %s because %s is set", chosen, setting)
+	} else {
+		logger.Subprocess("Using %s because it is at the application root, set BP_CARGO_PROJECT_PATH to build a different project", chosen)
+	}
+
+	return nil
+}
+
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(filepath.Clean(pattern), path); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
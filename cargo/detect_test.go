@@ -1,6 +1,7 @@
 package cargo_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/dmikusa/rust-cargo-cnb/cargo"
 	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
@@ -18,6 +20,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect = NewWithT(t).Expect
 
 		workingDir string
+		buffer     *bytes.Buffer
 		detect     packit.DetectFunc
 	)
 
@@ -26,7 +29,8 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		workingDir, err = ioutil.TempDir("", "working-dir")
 		Expect(err).NotTo(HaveOccurred())
 
-		detect = cargo.Detect()
+		buffer = bytes.NewBuffer(nil)
+		detect = cargo.Detect(scribe.NewEmitter(buffer))
 	})
 
 	it.After(func() {
@@ -39,6 +43,8 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 			_, err = os.Create(filepath.Join(workingDir, "Cargo.lock"))
 			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}\n"), 0644)).To(Succeed())
 		})
 
 		it("returns a DetectResult that provides and required rust", func() {
@@ -64,25 +70,329 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				},
 			}))
 		})
-	})
 
-	context("failure cases", func() {
-		context("Cargo.toml and Cargo.lock are missing", func() {
-			it("returns an error", func() {
+		it("passes when Cargo.toml is empty, leaving cargo to report the problem during the build", func() {
+			Expect(os.RemoveAll(filepath.Join(workingDir, "src"))).To(Succeed())
+
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{{Name: cargo.PlanDependencyRustCargo}}))
+		})
+
+		context("when a rust-toolchain.toml pins a version", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"1.55.0\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain"), []byte("1.54.0\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nrust-version = \"1.53\"\n"), 0644)).To(Succeed())
+			})
+
+			it("requires rust with the toolchain version", func() {
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{{Name: cargo.PlanDependencyRustCargo}}))
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       "1.55.0",
+						VersionSource: "rust-toolchain.toml",
+						ToolchainFile: "rust-toolchain.toml",
+					},
+				}))
+			})
+		})
+
+		context("when BP_RUST_VERSION is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_RUST_VERSION", "1.56.1")).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nrust-version = \"1.53\"\n"), 0644)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_RUST_VERSION")).To(Succeed())
+			})
+
+			it("requires rust with that version ahead of the MSRV", func() {
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       "1.56.1",
+						VersionSource: "BP_RUST_VERSION",
+					},
+				}))
+			})
+
+			it("accepts a rust-toolchain.toml that pins the same version", func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"1.56.1\"\n"), 0644)).To(Succeed())
+
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       "1.56.1",
+						VersionSource: "BP_RUST_VERSION",
+						ToolchainFile: "rust-toolchain.toml",
+					},
+				}))
+			})
+
+			it("accepts a version without a patch version that is the same version", func() {
+				Expect(os.Setenv("BP_RUST_VERSION", "1.56")).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"1.56.0\"\n"), 0644)).To(Succeed())
+
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       "1.56",
+						VersionSource: "BP_RUST_VERSION",
+						ToolchainFile: "rust-toolchain.toml",
+					},
+				}))
+			})
+
+			it("fails when rust-toolchain.toml pins another channel", func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"1.55.0\"\n"), 0644)).To(Succeed())
+
 				_, err := detect(packit.DetectContext{WorkingDir: workingDir})
-				Expect(err).To(MatchError(Equal("Missing [Cargo.toml: true, Cargo.lock: true], both required")))
+				Expect(err).To(MatchError(`BP_RUST_VERSION is "1.56.1" but rust-toolchain.toml pins the "1.55.0" channel, unset one of them or set both to the same version. ` +
+					"The Rust version is taken from BP_RUST_VERSION, then rust-toolchain.toml, then rust-toolchain, then the rust-version in Cargo.toml"))
+			})
+		})
+
+		context("when rust-toolchain.toml lists components", func() {
+			it("returns the components", func() {
+				components, err := cargo.ToolchainComponents("testdata/toolchain_components")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(components).To(Equal([]string{"clippy", "rustfmt"}))
+			})
+
+			it("returns nothing when there is no toolchain file", func() {
+				components, err := cargo.ToolchainComponents(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(components).To(BeEmpty())
+			})
+		})
+
+		context("when a legacy rust-toolchain file pins a version", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"stable\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain"), []byte("1.54.0\n"), 0644)).To(Succeed())
+			})
+
+			it("requires rust with the legacy toolchain version", func() {
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       "1.54.0",
+						VersionSource: "rust-toolchain",
+						ToolchainFile: "rust-toolchain.toml",
+					},
+				}))
 			})
 		})
 
-		context("when there is a Cargo.toml without a Cargo.lock file", func() {
+		context("when Cargo.toml declares an MSRV", func() {
 			it.Before(func() {
-				err := ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte{}, 0644)
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"foo\"\nrust-version = \"1.53\"\n"), 0644)).To(Succeed())
+			})
+
+			it("requires rust at or above the MSRV", func() {
+				result, err := detect(packit.DetectContext{WorkingDir: workingDir})
 				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: "rust",
+					Metadata: cargo.BuildPlanMetadata{
+						Version:       ">= 1.53",
+						VersionSource: "Cargo.toml",
+					},
+				}))
+			})
+		})
+
+		context("when rust-toolchain.toml is malformed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain"), 0644)).To(Succeed())
 			})
 
 			it("returns an error", func() {
 				_, err := detect(packit.DetectContext{WorkingDir: workingDir})
-				Expect(err).To(MatchError("Missing [Cargo.toml: false, Cargo.lock: true], both required"))
+				Expect(err).To(MatchError(ContainSubstring("unable to parse")))
+			})
+		})
+
+		context("when there are multiple candidate manifests", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "api"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "api", "Cargo.toml"), []byte("[package]\nname = \"api\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "api", "Cargo.lock"), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "api", "src", "bin"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "member"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "member", "Cargo.toml"), []byte("[package]\nname = \"member\"\n"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[workspace]\nmembers = [\"mem*\"]\n"), 0644)).To(Succeed())
+			})
+
+			it("chooses and logs the root manifest", func() {
+				_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buffer.String()).To(ContainSubstring("Found multiple Cargo.toml candidates: Cargo.toml, api/Cargo.toml"))
+				Expect(buffer.String()).To(ContainSubstring("Using Cargo.toml because it is at the application root, set BP_CARGO_PROJECT_PATH to build a different project"))
+			})
+
+			context("and BP_CARGO_PROJECT_PATH is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_CARGO_PROJECT_PATH", "api")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_CARGO_PROJECT_PATH")).To(Succeed())
+				})
+
+				it("chooses and logs the configured manifest", func() {
+					_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(buffer.String()).To(ContainSubstring("Using api/Cargo.toml because BP_CARGO_PROJECT_PATH is set"))
+				})
+			})
+
+			context("and BP_CARGO_MANIFEST_PATH is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_CARGO_MANIFEST_PATH", "api/Cargo.toml")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_CARGO_MANIFEST_PATH")).To(Succeed())
+				})
+
+				it("chooses and logs the configured manifest", func() {
+					_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(buffer.String()).To(ContainSubstring("Using api/Cargo.toml because BP_CARGO_MANIFEST_PATH is set"))
+				})
+
+				it("fails when BP_CARGO_PROJECT_PATH is set too", func() {
+					Expect(os.Setenv("BP_CARGO_PROJECT_PATH", "api")).To(Succeed())
+					defer os.Unsetenv("BP_CARGO_PROJECT_PATH")
+
+					_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+					Expect(err).To(MatchError("BP_CARGO_MANIFEST_PATH and BP_CARGO_PROJECT_PATH cannot both be set"))
+				})
+			})
+		})
+	})
+
+	context("when the package has no statically detectable binary target", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"generated\"\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_ASSUME_BINARY")).To(Succeed())
+		})
+
+		it("fails by default", func() {
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("No binary target found, Cargo.toml has no [[bin]] and there is no src/main.rs or src/bin, set BP_CARGO_ASSUME_BINARY=true if sources are generated before the build")))
+		})
+
+		it("passes when a member of its workspace has a binary target", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"),
+				[]byte("[package]\nname = \"generated\"\n\n[workspace]\nmembers = [\"cli\"]\n"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "cli", "src"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "cli", "Cargo.toml"), []byte("[package]\nname = \"cli\"\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "cli", "src", "main.rs"), []byte("fn main() {}"), 0644)).To(Succeed())
+
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("passes when BP_CARGO_ASSUME_BINARY is set", func() {
+			Expect(os.Setenv("BP_CARGO_ASSUME_BINARY", "true")).To(Succeed())
+
+			result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{{Name: cargo.PlanDependencyRustCargo}}))
+			Expect(buffer.String()).To(ContainSubstring("No binary target found, continuing because BP_CARGO_ASSUME_BINARY is set"))
+		})
+
+		it("passes when Cargo.toml declares a [[bin]] target", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"),
+				[]byte("[package]\nname = \"generated\"\n\n[[bin]]\nname = \"app\"\npath = \"gen/main.rs\"\n"), 0644)).To(Succeed())
+
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	context("when BP_CARGO_PROJECT_PATH points outside the application", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_PROJECT_PATH")).To(Succeed())
+		})
+
+		it("rejects absolute paths", func() {
+			Expect(os.Setenv("BP_CARGO_PROJECT_PATH", "/etc")).To(Succeed())
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError("BP_CARGO_PROJECT_PATH must be relative to the application root, got /etc"))
+		})
+
+		it("rejects escaping paths", func() {
+			Expect(os.Setenv("BP_CARGO_PROJECT_PATH", "foo/../../etc")).To(Succeed())
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError("BP_CARGO_PROJECT_PATH must not point outside of the application root, got foo/../../etc"))
+		})
+	})
+
+	context("when BP_CARGO_MANIFEST_PATH is invalid", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_MANIFEST_PATH")).To(Succeed())
+		})
+
+		it("rejects absolute paths", func() {
+			Expect(os.Setenv("BP_CARGO_MANIFEST_PATH", "/etc/Cargo.toml")).To(Succeed())
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError("BP_CARGO_MANIFEST_PATH must be relative to the application root, got /etc/Cargo.toml"))
+		})
+
+		it("rejects escaping paths", func() {
+			Expect(os.Setenv("BP_CARGO_MANIFEST_PATH", "app/../../Cargo.toml")).To(Succeed())
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError("BP_CARGO_MANIFEST_PATH must not point outside of the application root, got app/../../Cargo.toml"))
+		})
+
+		it("rejects files that are not a Cargo.toml", func() {
+			Expect(os.Setenv("BP_CARGO_MANIFEST_PATH", "app")).To(Succeed())
+			_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).To(MatchError("BP_CARGO_MANIFEST_PATH must point at a Cargo.toml file, got app"))
+		})
+	})
+
+	context("when there is a Cargo.toml without a Cargo.lock file", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}\n"), 0644)).To(Succeed())
+		})
+
+		it("passes, leaving the build to check the lockfile", func() {
+			result, err := detect(packit.DetectContext{WorkingDir: workingDir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{{Name: cargo.PlanDependencyRustCargo}}))
+		})
+	})
+
+	context("failure cases", func() {
+		context("Cargo.toml and Cargo.lock are missing", func() {
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{WorkingDir: workingDir})
+				Expect(err).To(MatchError(packit.Fail.WithMessage("Missing Cargo.toml, required")))
 			})
 		})
 
@@ -92,9 +402,9 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			it("returns an error", func() {
+			it("fails detection", func() {
 				_, err := detect(packit.DetectContext{WorkingDir: workingDir})
-				Expect(err).To(MatchError("Missing [Cargo.toml: true, Cargo.lock: false], both required"))
+				Expect(err).To(MatchError(packit.Fail.WithMessage("Missing Cargo.toml, required")))
 			})
 		})
 	})
@@ -0,0 +1,190 @@
+package cargo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// planFilters are the variables that decide which members, binaries and features are built
+var planFilters = []string{
+	"BP_CARGO_WORKSPACE_MEMBERS",
+	"BP_CARGO_PACKAGE",
+	"BP_CARGO_SKIP_UNPUBLISHED",
+	"BP_CARGO_INSTALL_ARGS",
+	"BP_CARGO_INSTALL_BINS",
+	"BP_CARGO_INSTALL_EXAMPLES",
+	"BP_CARGO_LAUNCH_BIN",
+	"BP_CARGO_FEATURES",
+	"BP_CARGO_ALL_FEATURES",
+	"BP_CARGO_NO_DEFAULT_FEATURES",
+}
+
+// planBuild logs what the build would do when BP_CARGO_DRY_RUN is set: the workspace members that are found, the
+// filters that select them, the cargo commands that would install them and the layers that would be contributed.
+// Only cargo commands that read the project are run. No layer is changed, so the cache layers of the previous build
+// are returned as they are and nothing is launched.
+func planBuild(runner Runner, context packit.BuildContext, cache bool, logger scribe.Emitter) (packit.BuildResult, error) {
+	logger.Subprocess("BP_CARGO_DRY_RUN is set, the build is planned but nothing is built")
+
+	srcDir, err := ProjectDir(context.WorkingDir)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	err = ValidateWorkspaceMembers(srcDir)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if installBins := RequestedBins(); len(installBins) > 0 {
+		err = ValidateInstallBins(srcDir, installBins)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+	}
+
+	binLayerName, err := BinLayerName()
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	cargoLayer, err := context.Layers.Get("rust-cargo")
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	binaryLayer, err := context.Layers.Get(binLayerName)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	members, err := runner.WorkspaceMembers(srcDir, cargoLayer, binaryLayer)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	virtual, err := IsVirtualManifest(srcDir)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	isPathSet, err := IsPathSet()
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	logger.Process("Build plan")
+	logger.Subprocess("Members:")
+	for _, member := range members {
+		logger.Action("%s", relativeMember(srcDir, member.Path))
+	}
+	if len(members) == 0 {
+		logger.Action("none detected")
+	}
+
+	logger.Subprocess("Filters:")
+	var filtered bool
+	for _, name := range planFilters {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			logger.Action("%s=%s", name, Redact(value, os.Environ()))
+			filtered = true
+		}
+	}
+	if !filtered {
+		logger.Action("none")
+	}
+
+	// members are installed the same way Build installs them
+	paths := []string{"."}
+	if len(members) > 0 && !singleRootPackage(members, virtual) && !isPathSet {
+		paths = nil
+		for _, member := range members {
+			paths = append(paths, member.Path)
+		}
+	}
+
+	logger.Subprocess("Commands:")
+	planner, ok := runner.(CommandPlanner)
+	for _, path := range paths {
+		if !ok {
+			logger.Action("install %s", relativeMember(srcDir, path))
+			continue
+		}
+
+		args, err := planner.InstallCommand(path, srcDir, cargoLayer, binaryLayer)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if args == nil {
+			logger.Action("%s is skipped, it has none of the binaries in BP_CARGO_INSTALL_BINS", relativeMember(srcDir, path))
+			continue
+		}
+		logger.Action("cargo %s", Redact(strings.Join(args, " "), os.Environ()))
+	}
+
+	customHome, err := CustomCargoHome()
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	useSccache, err := SccacheEnabled()
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	cacheNames := []string{cargoLayer.Name, RegistryLayerName, TargetLayerName}
+	if customHome != "" {
+		cacheNames = []string{TargetLayerName}
+	}
+	if useSccache {
+		cacheNames = append(cacheNames, SccacheLayerName)
+	}
+
+	cached := "cached"
+	if !cache {
+		cached = "not cached, BP_CARGO_DISABLE_CACHE is set"
+	}
+
+	logger.Subprocess("Layers:")
+	logger.Action("%s: binaries, launched", binaryLayer.Name)
+	for _, name := range cacheNames {
+		logger.Action("%s: %s", name, cached)
+	}
+	if len(ParseListEnv("BP_CARGO_INCLUDE_FILES")) > 0 {
+		logger.Action("rust-assets: files from BP_CARGO_INCLUDE_FILES, launched")
+	}
+	logger.Break()
+
+	// returning the restored cache layers unchanged keeps them for the next build
+	var layers []packit.Layer
+	for _, name := range cacheNames {
+		layer, err := context.Layers.Get(name)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if layer.Cache {
+			layers = append(layers, layer)
+		}
+	}
+
+	return packit.BuildResult{Layers: layers}, nil
+}
+
+// relativeMember returns the path of a member relative to srcDir, or the path as it is if it isn't inside srcDir
+func relativeMember(srcDir string, path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+
+	rel, err := filepath.Rel(srcDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
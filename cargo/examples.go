@@ -0,0 +1,78 @@
+package cargo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+)
+
+// AllExamples in BP_CARGO_INSTALL_EXAMPLES selects every example
+const AllExamples = "*"
+
+// RequestedExamples returns the examples listed in BP_CARGO_INSTALL_EXAMPLES
+func RequestedExamples() []string {
+	return ParseListEnv("BP_CARGO_INSTALL_EXAMPLES")
+}
+
+// SelectExamples picks the examples out of available that requested lists, or all of them when it includes `*`.
+// Examples are installed into the same directory as the binaries, so an example named like one of bins fails.
+func SelectExamples(requested []string, available []string, bins []string) ([]string, error) {
+	var selected []string
+	for _, name := range available {
+		if contains(requested, AllExamples) || contains(requested, name) {
+			selected = append(selected, name)
+		}
+	}
+
+	for _, name := range selected {
+		if contains(bins, name) {
+			return nil, fmt.Errorf("example %s has the same name as a binary, they can't both be installed, rename the example or remove it from BP_CARGO_INSTALL_EXAMPLES", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// ExampleArgs returns the arguments that build examples next to the binaries. Naming an example would otherwise
+// make cargo only build the example, so `--bins` is added unless args already select binaries.
+func ExampleArgs(args []string, examples []string) []string {
+	if len(examples) == 0 {
+		return nil
+	}
+
+	var exampleArgs []string
+	if !contains(args, "--bins") && !contains(args, "--bin") && !hasPrefix(args, "--bin=") {
+		exampleArgs = append(exampleArgs, "--bins")
+	}
+
+	for _, name := range examples {
+		exampleArgs = append(exampleArgs, "--example", name)
+	}
+	return exampleArgs
+}
+
+// cachedExamples lists the examples that the previous build recorded in the metadata of binaryLayer
+func cachedExamples(binaryLayer packit.Layer) []string {
+	var names []string
+	switch examples := binaryLayer.Metadata["examples"].(type) {
+	case []interface{}:
+		for _, name := range examples {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+	case []string:
+		names = examples
+	}
+	return names
+}
+
+func hasPrefix(args []string, prefix string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package cargo_test
+
+import (
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testExamples(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("SelectExamples", func() {
+		it("selects the requested examples that are available", func() {
+			Expect(cargo.SelectExamples([]string{"client", "other"}, []string{"client", "server"}, []string{"app"})).To(Equal([]string{"client"}))
+		})
+
+		it("selects every example with *", func() {
+			Expect(cargo.SelectExamples([]string{"*"}, []string{"client", "server"}, []string{"app"})).To(Equal([]string{"client", "server"}))
+		})
+
+		it("fails when an example is named like a binary", func() {
+			_, err := cargo.SelectExamples([]string{"*"}, []string{"client", "app"}, []string{"app"})
+			Expect(err).To(MatchError("example app has the same name as a binary, they can't both be installed, rename the example or remove it from BP_CARGO_INSTALL_EXAMPLES"))
+		})
+	})
+
+	context("ExampleArgs", func() {
+		it("builds the binaries along with the examples", func() {
+			Expect(cargo.ExampleArgs([]string{"install"}, []string{"client", "server"})).To(Equal([]string{"--bins", "--example", "client", "--example", "server"}))
+		})
+
+		it("keeps the binaries that the args select", func() {
+			Expect(cargo.ExampleArgs([]string{"install", "--bin=app"}, []string{"client"})).To(Equal([]string{"--example", "client"}))
+		})
+
+		it("adds nothing without examples", func() {
+			Expect(cargo.ExampleArgs([]string{"install"}, nil)).To(BeEmpty())
+		})
+	})
+}
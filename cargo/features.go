@@ -0,0 +1,153 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParseFeatures splits a comma or space separated list of features, like the value of BP_CARGO_FEATURES
+func ParseFeatures(features string) []string {
+	return strings.FieldsFunc(features, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// FeatureSelection is the set of features to build with, from BP_CARGO_FEATURES, BP_CARGO_NO_DEFAULT_FEATURES and
+// BP_CARGO_ALL_FEATURES
+type FeatureSelection struct {
+	Features  []string
+	NoDefault bool
+	All       bool
+}
+
+// LoadFeatureSelection reads the feature selection from the environment
+func LoadFeatureSelection() (FeatureSelection, error) {
+	noDefault, err := ParseBoolEnv("BP_CARGO_NO_DEFAULT_FEATURES")
+	if err != nil {
+		return FeatureSelection{}, err
+	}
+
+	all, err := ParseBoolEnv("BP_CARGO_ALL_FEATURES")
+	if err != nil {
+		return FeatureSelection{}, err
+	}
+
+	return FeatureSelection{
+		Features:  ParseFeatures(os.Getenv("BP_CARGO_FEATURES")),
+		NoDefault: noDefault,
+		All:       all,
+	}, nil
+}
+
+// Requested returns the features that have to be defined by a member, none when every feature is enabled
+func (f FeatureSelection) Requested() []string {
+	if f.All {
+		return nil
+	}
+	return f.Features
+}
+
+// Key describes the selection in a single string, which is recorded in the cache metadata so that changes are
+// noticed. It is empty when the package's default features are used.
+func (f FeatureSelection) Key() string {
+	if f.All {
+		return "all"
+	}
+
+	var parts []string
+	if f.NoDefault {
+		parts = append(parts, "no-default")
+	}
+	if len(f.Features) > 0 {
+		features := append([]string{}, f.Features...)
+		sort.Strings(features)
+		parts = append(parts, strings.Join(features, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Features returns the features that the package in memberDir defines. This includes the `[features]` table and the
+// implicit features of optional dependencies, including dependencies inherited with `workspace = true`, unless
+// they are only referenced with the `dep:` prefix.
+func Features(memberDir string) (map[string]bool, error) {
+	manifest, err := ParseManifest(filepath.Join(memberDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	features := map[string]bool{}
+	hidden := map[string]bool{}
+	for name, enables := range manifest.Features {
+		features[name] = true
+		for _, enable := range enables {
+			if strings.HasPrefix(enable, "dep:") {
+				hidden[strings.TrimPrefix(enable, "dep:")] = true
+			}
+		}
+	}
+
+	for name, dep := range manifest.Dependencies {
+		table, ok := dep.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if optional, _ := table["optional"].(bool); optional && !hidden[name] {
+			features[name] = true
+		}
+	}
+
+	return features, nil
+}
+
+// MemberFeatures returns the requested features that the package in memberDir defines. Features qualified with a
+// package name, like `serde/derive`, are always included since Cargo resolves them against the dependency.
+func MemberFeatures(memberDir string, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, nil
+	}
+
+	defined, err := Features(memberDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var features []string
+	for _, feature := range requested {
+		if strings.Contains(feature, "/") || defined[feature] {
+			features = append(features, feature)
+		}
+	}
+
+	return features, nil
+}
+
+// CheckFeatures fails if a requested feature is not defined by any of the packages in memberDirs. Features that are
+// defined by some, but not all, members are applied only to the members that define them.
+func CheckFeatures(memberDirs []string, requested []string) error {
+	found := map[string]bool{}
+	for _, memberDir := range memberDirs {
+		features, err := MemberFeatures(memberDir, requested)
+		if err != nil {
+			return err
+		}
+		for _, feature := range features {
+			found[feature] = true
+		}
+	}
+
+	var missing []string
+	for _, feature := range requested {
+		if !found[feature] {
+			missing = append(missing, feature)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("BP_CARGO_FEATURES requests [%s], which no workspace member defines", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cargo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFeatures(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workspaceDir = filepath.Join("testdata", "workspace_features")
+	)
+
+	context("ParseFeatures", func() {
+		it("splits on commas and spaces", func() {
+			Expect(cargo.ParseFeatures("metrics, json  serde/derive")).To(Equal([]string{"metrics", "json", "serde/derive"}))
+			Expect(cargo.ParseFeatures("")).To(BeEmpty())
+		})
+	})
+
+	context("when members inherit dependencies from the workspace", func() {
+		it("includes inherited optional dependencies as features", func() {
+			features, err := cargo.Features(filepath.Join(workspaceDir, "api"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal(map[string]bool{"metrics": true, "serde": true}))
+		})
+
+		it("excludes optional dependencies referenced with dep:", func() {
+			features, err := cargo.Features(filepath.Join(workspaceDir, "cli"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal(map[string]bool{"json": true}))
+		})
+
+		it("applies each feature only to the members that define it", func() {
+			features, err := cargo.MemberFeatures(filepath.Join(workspaceDir, "api"), []string{"metrics", "json", "serde", "serde/derive"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal([]string{"metrics", "serde", "serde/derive"}))
+
+			features, err = cargo.MemberFeatures(filepath.Join(workspaceDir, "cli"), []string{"metrics", "json", "serde", "serde/derive"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal([]string{"json", "serde/derive"}))
+		})
+
+		it("succeeds when every feature is defined by some member", func() {
+			Expect(cargo.CheckFeatures([]string{
+				filepath.Join(workspaceDir, "api"),
+				filepath.Join(workspaceDir, "cli"),
+			}, []string{"metrics", "json"})).To(Succeed())
+		})
+
+		it("fails when a feature is not defined by any member", func() {
+			err := cargo.CheckFeatures([]string{
+				filepath.Join(workspaceDir, "api"),
+				filepath.Join(workspaceDir, "cli"),
+			}, []string{"metrics", "tls", "tracing"})
+			Expect(err).To(MatchError("BP_CARGO_FEATURES requests [tls, tracing], which no workspace member defines"))
+		})
+	})
+
+	context("the feature selection", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_NO_DEFAULT_FEATURES")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_ALL_FEATURES")).To(Succeed())
+		})
+
+		it("uses the default features when nothing is set", func() {
+			selection, err := cargo.LoadFeatureSelection()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.Features).To(BeEmpty())
+			Expect(selection.NoDefault).To(BeFalse())
+			Expect(selection.All).To(BeFalse())
+			Expect(selection.Key()).To(BeEmpty())
+		})
+
+		it("describes the features for the cache metadata", func() {
+			Expect(os.Setenv("BP_CARGO_FEATURES", "tls json")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_NO_DEFAULT_FEATURES", "true")).To(Succeed())
+
+			selection, err := cargo.LoadFeatureSelection()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.Requested()).To(Equal([]string{"tls", "json"}))
+			Expect(selection.Key()).To(Equal("no-default;json,tls"))
+		})
+
+		it("requests no specific features when every feature is enabled", func() {
+			Expect(os.Setenv("BP_CARGO_FEATURES", "tls")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_ALL_FEATURES", "true")).To(Succeed())
+
+			selection, err := cargo.LoadFeatureSelection()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.Requested()).To(BeEmpty())
+			Expect(selection.Key()).To(Equal("all"))
+		})
+
+		it("fails on an invalid boolean", func() {
+			Expect(os.Setenv("BP_CARGO_ALL_FEATURES", "yes please")).To(Succeed())
+
+			_, err := cargo.LoadFeatureSelection()
+			Expect(err).To(MatchError(ContainSubstring("invalid value for BP_CARGO_ALL_FEATURES")))
+		})
+	})
+}
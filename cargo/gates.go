@@ -0,0 +1,47 @@
+package cargo
+
+import (
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// GateStatus is the outcome of a build gate
+type GateStatus string
+
+const (
+	GatePassed GateStatus = "passed"
+	GateFailed GateStatus = "failed"
+)
+
+// GateResult is the outcome of one build gate
+type GateResult struct {
+	Name   string
+	Status GateStatus
+}
+
+// GateSummary collects the outcome of the optional checks that can stop a build, like BP_CARGO_VALIDATE_CMD, in
+// the order they ran
+type GateSummary struct {
+	Results []GateResult
+}
+
+// Record adds the outcome of a gate that ran, err is the error it returned
+func (g *GateSummary) Record(name string, err error) {
+	status := GatePassed
+	if err != nil {
+		status = GateFailed
+	}
+	g.Results = append(g.Results, GateResult{Name: name, Status: status})
+}
+
+// Log prints the outcome of every gate, it prints nothing when no gate was enabled
+func (g GateSummary) Log(logger scribe.Emitter) {
+	if len(g.Results) == 0 {
+		return
+	}
+
+	logger.Process("Build gates")
+	for _, result := range g.Results {
+		logger.Subprocess("%s: %s", result.Name, result.Status)
+	}
+	logger.Break()
+}
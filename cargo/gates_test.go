@@ -0,0 +1,53 @@
+package cargo_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGates(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it("lists each gate with its outcome in order", func() {
+		var gates cargo.GateSummary
+		gates.Record("reproducible sources", nil)
+		gates.Record("clippy", nil)
+		gates.Record("validation command", errors.New("exit status 1"))
+
+		Expect(gates.Results).To(Equal([]cargo.GateResult{
+			{Name: "reproducible sources", Status: cargo.GatePassed},
+			{Name: "clippy", Status: cargo.GatePassed},
+			{Name: "validation command", Status: cargo.GateFailed},
+		}))
+
+		gates.Log(logger)
+		Expect(logBuf.String()).To(Equal(`  Build gates
+    reproducible sources: passed
+    clippy: passed
+    validation command: failed
+
+`))
+	})
+
+	it("prints nothing when no gate is enabled", func() {
+		cargo.GateSummary{}.Log(logger)
+		Expect(logBuf.String()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,79 @@
+package cargo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VersionFile is the name of the file, inside the rust-bin layer, that holds the git commit the binaries were built from
+const VersionFile = "version.txt"
+
+var gitSHA = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// GitCommit returns the commit the project in srcDir was built from. BP_CARGO_GIT_SHA takes precedence, otherwise
+// HEAD is resolved from the `.git` directory. An empty string means the commit is not known.
+func GitCommit(srcDir string) (string, error) {
+	if sha := strings.TrimSpace(os.Getenv("BP_CARGO_GIT_SHA")); sha != "" {
+		sha = strings.ToLower(sha)
+		if !gitSHA.MatchString(sha) {
+			return "", fmt.Errorf("invalid value for BP_CARGO_GIT_SHA %q, must be a commit hash of 7 to 40 hexadecimal characters", sha)
+		}
+		return sha, nil
+	}
+
+	gitDir := filepath.Join(srcDir, ".git")
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to read git HEAD\n%w", err)
+	}
+
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref: ") {
+		// a detached HEAD holds the commit itself
+		if gitSHA.MatchString(ref) {
+			return ref, nil
+		}
+		return "", nil
+	}
+	ref = strings.TrimPrefix(ref, "ref: ")
+
+	sha, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref)))
+	if err == nil {
+		return strings.TrimSpace(string(sha)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("unable to read git ref %s\n%w", ref, err)
+	}
+
+	return packedRef(gitDir, ref)
+}
+
+// packedRef looks ref up in `.git/packed-refs`, where git moves refs when it garbage collects
+func packedRef(gitDir string, ref string) (string, error) {
+	file, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to read git packed-refs\n%w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0], nil
+		}
+	}
+
+	return "", scanner.Err()
+}
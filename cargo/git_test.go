@@ -0,0 +1,83 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGit(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir string
+	)
+
+	it.Before(func() {
+		var err error
+		srcDir, err = ioutil.TempDir("", "git")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_CARGO_GIT_SHA")).To(Succeed())
+		Expect(os.RemoveAll(srcDir)).To(Succeed())
+	})
+
+	it("uses BP_CARGO_GIT_SHA", func() {
+		Expect(os.Setenv("BP_CARGO_GIT_SHA", "1A2B3C4")).To(Succeed())
+
+		Expect(cargo.GitCommit(srcDir)).To(Equal("1a2b3c4"))
+	})
+
+	it("rejects an invalid BP_CARGO_GIT_SHA", func() {
+		Expect(os.Setenv("BP_CARGO_GIT_SHA", "main")).To(Succeed())
+
+		_, err := cargo.GitCommit(srcDir)
+		Expect(err).To(MatchError(`invalid value for BP_CARGO_GIT_SHA "main", must be a commit hash of 7 to 40 hexadecimal characters`))
+	})
+
+	it("returns nothing without a .git directory", func() {
+		Expect(cargo.GitCommit(srcDir)).To(BeEmpty())
+	})
+
+	context("when there is a .git directory", func() {
+		const sha = "0a2f6a1c3b4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f"
+
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(srcDir, ".git", "refs", "heads"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644)).To(Succeed())
+		})
+
+		it("resolves HEAD to a commit", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, ".git", "refs", "heads", "main"), []byte(sha+"\n"), 0644)).To(Succeed())
+
+			Expect(cargo.GitCommit(srcDir)).To(Equal(sha))
+		})
+
+		it("looks in packed-refs", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, ".git", "packed-refs"), []byte("# pack-refs with: peeled fully-peeled sorted\n"+sha+" refs/heads/main\n"), 0644)).To(Succeed())
+
+			Expect(cargo.GitCommit(srcDir)).To(Equal(sha))
+		})
+
+		it("reads a detached HEAD", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, ".git", "HEAD"), []byte(sha+"\n"), 0644)).To(Succeed())
+
+			Expect(cargo.GitCommit(srcDir)).To(Equal(sha))
+		})
+
+		it("prefers BP_CARGO_GIT_SHA", func() {
+			Expect(ioutil.WriteFile(filepath.Join(srcDir, ".git", "refs", "heads", "main"), []byte(sha+"\n"), 0644)).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_GIT_SHA", "1a2b3c4")).To(Succeed())
+
+			Expect(cargo.GitCommit(srcDir)).To(Equal("1a2b3c4"))
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package cargo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+)
+
+// BuildHomeDir is the directory, inside the work layer, that is used as HOME when the build user's HOME is unset
+// or not writable
+const BuildHomeDir = "build-home"
+
+// ensureWritableHome points HOME in env at a directory in workLayer when it is unset or cannot be written to, so
+// that cargo and the tools it runs have somewhere to write. If rustup keeps its toolchains in the original home,
+// RUSTUP_HOME is set so that they are still found.
+func ensureWritableHome(env []string, workLayer packit.Layer) ([]string, error) {
+	home := lookupEnv(env, "HOME")
+	if home != "" && isWritableDir(home) {
+		return env, nil
+	}
+
+	buildHome := filepath.Join(workLayer.Path, BuildHomeDir)
+	err := os.MkdirAll(buildHome, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s\n%w", buildHome, err)
+	}
+
+	if rustupHome := filepath.Join(home, ".rustup"); home != "" && !containsEnv(env, "RUSTUP_HOME") {
+		if info, err := os.Stat(rustupHome); err == nil && info.IsDir() {
+			env = append(env, fmt.Sprintf("RUSTUP_HOME=%s", rustupHome))
+		}
+	}
+
+	for i, e := range env {
+		if strings.HasPrefix(e, "HOME=") {
+			env[i] = fmt.Sprintf("HOME=%s", buildHome)
+			return env, nil
+		}
+	}
+
+	return append(env, fmt.Sprintf("HOME=%s", buildHome)), nil
+}
+
+// lookupEnv returns the value of the variable name in env, or an empty string if it is not set
+func lookupEnv(env []string, name string) string {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return strings.TrimPrefix(e, name+"=")
+		}
+	}
+	return ""
+}
+
+// isWritableDir reports whether a file can be created in dir
+func isWritableDir(dir string) bool {
+	file, err := ioutil.TempFile(dir, ".write-check-")
+	if err != nil {
+		return false
+	}
+
+	file.Close()
+	return os.Remove(file.Name()) == nil
+}
+
+// CustomCargoHome returns the directory set in BP_CARGO_HOME, or an empty string when it is not set. The directory
+// has to exist and be writable, since cargo keeps its registry and git checkouts there.
+func CustomCargoHome() (string, error) {
+	home := os.Getenv("BP_CARGO_HOME")
+	if home == "" {
+		return "", nil
+	}
+
+	if !filepath.IsAbs(home) {
+		return "", fmt.Errorf("BP_CARGO_HOME must be an absolute path, got %q", home)
+	}
+
+	info, err := os.Stat(home)
+	if err != nil {
+		return "", fmt.Errorf("BP_CARGO_HOME is set to %s, which cannot be used as CARGO_HOME\n%w", home, err)
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("BP_CARGO_HOME is set to %s, which is not a directory", home)
+	}
+
+	if !isWritableDir(home) {
+		return "", fmt.Errorf("BP_CARGO_HOME is set to %s, which is not writable by the build user", home)
+	}
+
+	return home, nil
+}
+
+// CargoHome is the CARGO_HOME that cargo runs with, the directory in BP_CARGO_HOME or `home` in workLayer
+func CargoHome(workLayer packit.Layer) string {
+	if home := os.Getenv("BP_CARGO_HOME"); home != "" {
+		return home
+	}
+	return filepath.Join(workLayer.Path, "home")
+}
@@ -0,0 +1,127 @@
+package cargo
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"K":  1 << 10,
+	"KB": 1 << 10,
+	"M":  1 << 20,
+	"MB": 1 << 20,
+	"G":  1 << 30,
+	"GB": 1 << 30,
+}
+
+// ParseSize reads a size like `512`, `200K`, `50MB` or `1G`. Units are powers of 1024 and are case insensitive.
+func ParseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	digits := strings.TrimRight(value, "BKMG")
+
+	unit, ok := sizeUnits[value[len(digits):]]
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid size %q, use a number of bytes with an optional K, M or G suffix", value)
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, use a number of bytes with an optional K, M or G suffix", value)
+	}
+
+	return n * unit, nil
+}
+
+// MaxImageSize returns the budget for the launch layers from BP_CARGO_MAX_IMAGE_SIZE, zero means no budget
+func MaxImageSize() (int64, error) {
+	value := os.Getenv("BP_CARGO_MAX_IMAGE_SIZE")
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+
+	size, err := ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for BP_CARGO_MAX_IMAGE_SIZE\n%w", err)
+	}
+
+	return size, nil
+}
+
+// DirSize adds up the size of the regular files below dir
+func DirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to measure %s\n%w", dir, err)
+	}
+
+	return total, nil
+}
+
+// CheckImageSize logs the size of each launch layer and their total, and fails when the total is over budget. A
+// budget of zero only reports.
+func CheckImageSize(layers []packit.Layer, budget int64, logger scribe.Emitter) error {
+	var total int64
+
+	logger.Subprocess("Launch layer sizes:")
+	for _, layer := range layers {
+		if !layer.Launch {
+			continue
+		}
+
+		size, err := DirSize(layer.Path)
+		if err != nil {
+			return err
+		}
+
+		logger.Action("%s: %s", layer.Name, FormatSize(size))
+		total += size
+	}
+	logger.Action("total: %s", FormatSize(total))
+	logger.Break()
+
+	if budget > 0 && total > budget {
+		return fmt.Errorf("launch layers use %s, which exceeds BP_CARGO_MAX_IMAGE_SIZE of %s", FormatSize(total), FormatSize(budget))
+	}
+
+	return nil
+}
+
+// FormatSize renders a number of bytes with the largest unit that keeps it at or above one
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,99 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testImageSize(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layersDir string
+		layers    []packit.Layer
+		logBuf    bytes.Buffer
+		logger    scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		layersDir, err = ioutil.TempDir("", "layers")
+		Expect(err).NotTo(HaveOccurred())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+
+		layer := func(name string, launch bool, size int) packit.Layer {
+			path := filepath.Join(layersDir, name)
+			Expect(os.MkdirAll(filepath.Join(path, "bin"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "bin", "file"), make([]byte, size), 0644)).To(Succeed())
+			return packit.Layer{Name: name, Path: path, Launch: launch}
+		}
+
+		layers = []packit.Layer{
+			layer("rust-cargo", false, 4096),
+			layer("rust-bin", true, 2048),
+			layer("rust-assets", true, 1024),
+		}
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layersDir)).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_MAX_IMAGE_SIZE")).To(Succeed())
+	})
+
+	it("reports the size of each launch layer", func() {
+		Expect(cargo.CheckImageSize(layers, 0, logger)).To(Succeed())
+
+		Expect(logBuf.String()).To(ContainSubstring("rust-bin: 2.0 KiB"))
+		Expect(logBuf.String()).To(ContainSubstring("rust-assets: 1.0 KiB"))
+		Expect(logBuf.String()).To(ContainSubstring("total: 3.0 KiB"))
+		Expect(logBuf.String()).NotTo(ContainSubstring("rust-cargo"))
+	})
+
+	it("passes when the launch layers are within the budget", func() {
+		Expect(cargo.CheckImageSize(layers, 3072, logger)).To(Succeed())
+	})
+
+	it("fails when the launch layers exceed the budget", func() {
+		err := cargo.CheckImageSize(layers, 3071, logger)
+		Expect(err).To(MatchError("launch layers use 3.0 KiB, which exceeds BP_CARGO_MAX_IMAGE_SIZE of 3.0 KiB"))
+	})
+
+	context("BP_CARGO_MAX_IMAGE_SIZE", func() {
+		it("has no budget by default", func() {
+			Expect(cargo.MaxImageSize()).To(BeZero())
+		})
+
+		for value, expected := range map[string]int64{
+			"512":  512,
+			"200K": 200 << 10,
+			"50mb": 50 << 20,
+			"1G":   1 << 30,
+		} {
+			value, expected := value, expected
+
+			it("parses "+value, func() {
+				Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", value)).To(Succeed())
+				Expect(cargo.MaxImageSize()).To(Equal(expected))
+			})
+		}
+
+		it("rejects other values", func() {
+			Expect(os.Setenv("BP_CARGO_MAX_IMAGE_SIZE", "10T")).To(Succeed())
+
+			_, err := cargo.MaxImageSize()
+			Expect(err).To(MatchError(ContainSubstring(`invalid size "10T"`)))
+		})
+	})
+}
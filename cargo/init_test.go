@@ -12,5 +12,39 @@ func TestUnitRustCargo(t *testing.T) {
 	suite("Build", testBuild)
 	suite("Detect", testDetect)
 	suite("CLI Runner", testCLIRunner)
+	suite("UPX", testUPX)
+	suite("Binaries", testBinaries)
+	suite("Permissions", testPermissions)
+	suite("Lockfile", testLockfile)
+	suite("Telemetry", testTelemetry)
+	suite("Manifest", testManifest)
+	suite("Build Script", testBuildScript)
+	suite("Jobserver", testJobserver)
+	suite("Workspace", testWorkspace)
+	suite("Config", testConfig)
+	suite("DepGraph", testDepGraph)
+	suite("Validate", testValidate)
+	suite("Cargo Config", testCargoConfig)
+	suite("Assets", testAssets)
+	suite("Processes", testProcesses)
+	suite("Native", testNative)
+	suite("Args File", testArgsFile)
+	suite("Features", testFeatures)
+	suite("Tasks", testTasks)
+	suite("SBOM", testSBOM)
+	suite("Priority", testPriority)
+	suite("Registry Auth", testRegistryAuth)
+	suite("Sources", testSources)
+	suite("Git", testGit)
+	suite("Profile", testProfile)
+	suite("Image Size", testImageSize)
+	suite("Gates", testGates)
+	suite("BuildConfiguration", testBuildConfiguration)
+	suite("Toolchain", testToolchain)
+	suite("Registry", testRegistry)
+	suite("SourceChecksum", testSourceChecksum)
+	suite("Examples", testExamples)
+	suite("Install Bins", testInstallBins)
+	suite("Command", testCommand)
 	suite.Run(t)
 }
@@ -0,0 +1,148 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RequestedBins returns the binaries listed in BP_CARGO_INSTALL_BINS
+func RequestedBins() []string {
+	return ParseListEnv("BP_CARGO_INSTALL_BINS")
+}
+
+// PackageBinaries lists the binary targets of the package in projectDir that can be seen without building: the
+// `[[bin]]` tables, `src/main.rs`, which is named after the package, and the files and directories in `src/bin`.
+// A manifest without a `[package]`, like a virtual workspace, has no binaries.
+func PackageBinaries(projectDir string) ([]string, error) {
+	manifest, err := ParseManifest(filepath.Join(projectDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Package == nil {
+		return nil, nil
+	}
+
+	var names []string
+	paths := map[string]bool{}
+	for _, bin := range manifest.Bins {
+		names = appendUnique(names, bin.Name)
+		if bin.Path != "" {
+			paths[filepath.Clean(bin.Path)] = true
+		}
+	}
+
+	mainPath := filepath.Join("src", "main.rs")
+	if info, err := os.Stat(filepath.Join(projectDir, mainPath)); err == nil && info.Mode().IsRegular() && !paths[mainPath] {
+		names = appendUnique(names, manifest.Package.Name)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(projectDir, "src", "bin"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read %s\n%w", filepath.Join(projectDir, "src", "bin"), err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join("src", "bin", entry.Name())
+		switch {
+		case entry.IsDir():
+			if _, err := os.Stat(filepath.Join(projectDir, path, "main.rs")); err == nil && !paths[filepath.Join(path, "main.rs")] {
+				names = appendUnique(names, entry.Name())
+			}
+		case strings.HasSuffix(entry.Name(), ".rs") && !paths[path]:
+			names = appendUnique(names, strings.TrimSuffix(entry.Name(), ".rs"))
+		}
+	}
+
+	return names, nil
+}
+
+// ProjectBinaries lists the binary targets of the package in srcDir and, if it is a workspace, of all of its members
+func ProjectBinaries(srcDir string) ([]string, error) {
+	root, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := PackageBinaries(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Workspace == nil {
+		return names, nil
+	}
+
+	dirs, err := root.Workspace.MemberDirs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		memberNames, err := PackageBinaries(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range memberNames {
+			names = appendUnique(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// ValidateInstallBins checks that every binary in requested is defined by the project in srcDir, so that a typo fails
+// before anything is built. The error lists the binaries that can be selected.
+func ValidateInstallBins(srcDir string, requested []string) error {
+	available, err := ProjectBinaries(srcDir)
+	if err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, name := range requested {
+		if !contains(available, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(available)
+		return fmt.Errorf("binaries [%s] in BP_CARGO_INSTALL_BINS are not defined by the project, the available binaries are [%s]",
+			strings.Join(unknown, ", "), strings.Join(available, ", "))
+	}
+
+	return nil
+}
+
+// SelectBins picks the binaries out of requested that are in available, keeping the order of requested
+func SelectBins(requested []string, available []string) []string {
+	var selected []string
+	for _, name := range requested {
+		if contains(available, name) {
+			selected = appendUnique(selected, name)
+		}
+	}
+	return selected
+}
+
+// BinArgs returns a `--bin <name>` argument for each of bins
+func BinArgs(bins []string) []string {
+	var args []string
+	for _, name := range bins {
+		args = append(args, "--bin", name)
+	}
+	return args
+}
+
+func appendUnique(list []string, name string) []string {
+	if contains(list, name) {
+		return list
+	}
+	return append(list, name)
+}
@@ -0,0 +1,91 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testInstallBins(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	writeFile := func(path string, contents string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(workingDir, path)), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, path), []byte(contents), 0644)).To(Succeed())
+	}
+
+	context("PackageBinaries", func() {
+		it("lists the [[bin]] tables and the binaries found in src", func() {
+			writeFile("Cargo.toml", "[package]\nname = \"app\"\n\n[[bin]]\nname = \"migrate\"\npath = \"tools/migrate.rs\"\n")
+			writeFile("src/main.rs", "fn main() {}")
+			writeFile("src/bin/worker.rs", "fn main() {}")
+			writeFile("src/bin/cli/main.rs", "fn main() {}")
+
+			Expect(cargo.PackageBinaries(workingDir)).To(ConsistOf("migrate", "app", "worker", "cli"))
+		})
+
+		it("does not name src/main.rs after the package when a [[bin]] uses it", func() {
+			writeFile("Cargo.toml", "[package]\nname = \"app\"\n\n[[bin]]\nname = \"server\"\npath = \"src/main.rs\"\n")
+			writeFile("src/main.rs", "fn main() {}")
+
+			Expect(cargo.PackageBinaries(workingDir)).To(Equal([]string{"server"}))
+		})
+
+		it("has no binaries without a package", func() {
+			writeFile("Cargo.toml", "[workspace]\nmembers = [\"api\"]\n")
+
+			Expect(cargo.PackageBinaries(workingDir)).To(BeEmpty())
+		})
+	})
+
+	context("ValidateInstallBins", func() {
+		it.Before(func() {
+			writeFile("Cargo.toml", "[workspace]\nmembers = [\"crates/*\"]\n")
+			writeFile("crates/api/Cargo.toml", "[package]\nname = \"api\"\n")
+			writeFile("crates/api/src/main.rs", "fn main() {}")
+			writeFile("crates/worker/Cargo.toml", "[package]\nname = \"worker\"\n")
+			writeFile("crates/worker/src/bin/reindex.rs", "fn main() {}")
+		})
+
+		it("accepts the binaries of any member", func() {
+			Expect(cargo.ValidateInstallBins(workingDir, []string{"api", "reindex"})).To(Succeed())
+		})
+
+		it("lists the available binaries when one is not defined", func() {
+			Expect(cargo.ValidateInstallBins(workingDir, []string{"api", "worker"})).To(MatchError(
+				"binaries [worker] in BP_CARGO_INSTALL_BINS are not defined by the project, the available binaries are [api, reindex]"))
+		})
+	})
+
+	context("SelectBins", func() {
+		it("keeps the requested binaries that are available", func() {
+			Expect(cargo.SelectBins([]string{"worker", "api", "other"}, []string{"api", "worker"})).To(Equal([]string{"worker", "api"}))
+		})
+	})
+
+	context("BinArgs", func() {
+		it("repeats --bin for each binary", func() {
+			Expect(cargo.BinArgs([]string{"api", "worker"})).To(Equal([]string{"--bin", "api", "--bin", "worker"}))
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package cargo
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// JobserverFlags returns the make flags of a jobserver provided by the platform that cargo can join, or an empty
+// string if there is none. Only fifo based jobservers are supported, as file descriptor based jobservers require
+// the descriptors to be inherited by cargo.
+func JobserverFlags() string {
+	for _, name := range []string{"CARGO_MAKEFLAGS", "MAKEFLAGS", "MFLAGS"} {
+		flags := os.Getenv(name)
+		for _, flag := range strings.Fields(flags) {
+			if strings.HasPrefix(flag, "--jobserver-auth=fifo:") {
+				return flags
+			}
+		}
+	}
+	return ""
+}
+
+// JobsArgs returns the arguments that control cargo's parallelism when BP_CARGO_USE_JOBSERVER is enabled. If a
+// jobserver is available cargo coordinates through it, otherwise the number of jobs is set to the CPU count.
+func JobsArgs(args []string) ([]string, error) {
+	useJobserver, err := ParseBoolEnv("BP_CARGO_USE_JOBSERVER")
+	if err != nil {
+		return nil, err
+	}
+
+	if !useJobserver || JobserverFlags() != "" {
+		return nil, nil
+	}
+
+	for _, arg := range args {
+		if arg == "--jobs" || strings.HasPrefix(arg, "--jobs=") || strings.HasPrefix(arg, "-j") {
+			return nil, nil
+		}
+	}
+
+	return []string{"--jobs", strconv.Itoa(runtime.NumCPU())}, nil
+}
@@ -0,0 +1,77 @@
+package cargo_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/dmikusa/rust-cargo-cnb/cargo/mocks"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/gomega"
+)
+
+func testJobserver(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect    = NewWithT(t).Expect
+		workLayer = packit.Layer{Name: "work-layer", Path: "/some/location/1"}
+		destLayer = packit.Layer{Name: "dest-layer", Path: "/some/location/2"}
+	)
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_CARGO_USE_JOBSERVER")).To(Succeed())
+		Expect(os.Unsetenv("MAKEFLAGS")).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_INSTALL_ARGS")).To(Succeed())
+	})
+
+	context("when BP_CARGO_USE_JOBSERVER is not set", func() {
+		it("leaves parallelism to cargo", func() {
+			Expect(os.Setenv("MAKEFLAGS", "-j8 --jobserver-auth=fifo:/tmp/jobserver")).To(Succeed())
+			Expect(cargo.JobsArgs(nil)).To(BeEmpty())
+		})
+	})
+
+	context("when BP_CARGO_USE_JOBSERVER is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_USE_JOBSERVER", "true")).To(Succeed())
+		})
+
+		it("hands the jobserver to cargo", func() {
+			Expect(os.Setenv("MAKEFLAGS", "-j8 --jobserver-auth=fifo:/tmp/jobserver")).To(Succeed())
+
+			var execution pexec.Execution
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution = args.Get(0).(pexec.Execution)
+			}).Return(nil)
+
+			err := cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&bytes.Buffer{})).Install("/does/not/matter", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(execution.Env).To(ContainElement("CARGO_MAKEFLAGS=-j8 --jobserver-auth=fifo:/tmp/jobserver"))
+			Expect(execution.Args).To(Equal([]string{"install", "--locked", "--color=never", "--root=/some/location/2", "--path=."}))
+		})
+
+		it("falls back to the CPU count without a usable jobserver", func() {
+			Expect(os.Setenv("MAKEFLAGS", "-j8 --jobserver-auth=3,4")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"install", "--locked", "--jobs", fmt.Sprint(runtime.NumCPU()), "--color=never", "--root=/some/location/2", "--path=."}))
+		})
+
+		it("respects jobs set by the user", func() {
+			Expect(os.Setenv("BP_CARGO_INSTALL_ARGS", "-j2")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.BuildArgs(destLayer, ".")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"install", "-j2", "--locked", "--color=never", "--root=/some/location/2", "--path=."}))
+		})
+	})
+}
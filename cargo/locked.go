@@ -0,0 +1,47 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockedBuild reports whether cargo has to build with the versions in Cargo.lock, which BP_CARGO_LOCKED turns off
+func LockedBuild() (bool, error) {
+	return ParseBoolEnvDefault("BP_CARGO_LOCKED", true)
+}
+
+// LockedArgs returns `--locked` for locked builds, unless args already keep cargo from changing Cargo.lock
+func LockedArgs(args []string) ([]string, error) {
+	locked, err := LockedBuild()
+	if err != nil {
+		return nil, err
+	}
+
+	if !locked || contains(args, "--locked") || contains(args, "--frozen") {
+		return nil, nil
+	}
+
+	return []string{"--locked"}, nil
+}
+
+// CheckLockfile fails a locked build of srcDir that has no Cargo.lock, which cargo would otherwise only report
+// after resolving the dependencies
+func CheckLockfile(srcDir string) error {
+	locked, err := LockedBuild()
+	if err != nil || !locked {
+		return err
+	}
+
+	_, err = os.Stat(filepath.Join(srcDir, "Cargo.lock"))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("there is no Cargo.lock in %s, which is required because builds are locked by default, "+
+			"commit Cargo.lock or set BP_CARGO_LOCKED=false to let cargo resolve the dependencies", srcDir)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read Cargo.lock\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package cargo
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LockPackage is a single `[[package]]` entry from Cargo.lock
+type LockPackage struct {
+	Name         string   `toml:"name"`
+	Version      string   `toml:"version"`
+	Source       string   `toml:"source"`
+	Checksum     string   `toml:"checksum"`
+	Dependencies []string `toml:"dependencies"`
+}
+
+// Lockfile is the parsed contents of Cargo.lock
+type Lockfile struct {
+	Version  int           `toml:"version"`
+	Packages []LockPackage `toml:"package"`
+}
+
+// ParseLockfile reads and parses the Cargo.lock file at path
+func ParseLockfile(path string) (Lockfile, error) {
+	var lockfile Lockfile
+	_, err := toml.DecodeFile(path, &lockfile)
+	if err != nil {
+		return Lockfile{}, fmt.Errorf("unable to parse %s\n%w", path, err)
+	}
+	return lockfile, nil
+}
+
+// Select keeps the packages whose "name version" is listed in packages and returns the ones that were left out
+func (l Lockfile) Select(packages []string) (Lockfile, []LockPackage) {
+	var selected, omitted []LockPackage
+	for _, pkg := range l.Packages {
+		if contains(packages, fmt.Sprintf("%s %s", pkg.Name, pkg.Version)) {
+			selected = append(selected, pkg)
+		} else {
+			omitted = append(omitted, pkg)
+		}
+	}
+
+	return Lockfile{Version: l.Version, Packages: selected}, omitted
+}
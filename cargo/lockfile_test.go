@@ -0,0 +1,39 @@
+package cargo_test
+
+import (
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLockfile(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("parses the packages", func() {
+		lockfile, err := cargo.ParseLockfile("testdata/lockfile.toml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lockfile.Version).To(Equal(3))
+		Expect(lockfile.Packages).To(HaveLen(3))
+		Expect(lockfile.Packages[0]).To(Equal(cargo.LockPackage{
+			Name:         "app",
+			Version:      "0.1.0",
+			Dependencies: []string{"libc", "serde"},
+		}))
+		Expect(lockfile.Packages[1]).To(Equal(cargo.LockPackage{
+			Name:     "libc",
+			Version:  "0.2.101",
+			Source:   "registry+https://github.com/rust-lang/crates.io-index",
+			Checksum: "3cb00336871be5ed2c8ed44b60ae9959dc5b9f08539422ed43f09e34ecaeba21",
+		}))
+	})
+
+	it("fails when the file does not exist", func() {
+		_, err := cargo.ParseLockfile("testdata/missing.lock")
+		Expect(err).To(MatchError(ContainSubstring("unable to parse testdata/missing.lock")))
+	})
+}
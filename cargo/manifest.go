@@ -0,0 +1,239 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ManifestPackage is the `[package]` table from Cargo.toml
+type ManifestPackage struct {
+	Name        string `toml:"name"`
+	RustVersion string `toml:"rust-version"`
+	DefaultRun  string `toml:"default-run"`
+}
+
+// ManifestWorkspace is the `[workspace]` table from Cargo.toml
+type ManifestWorkspace struct {
+	Members        []string `toml:"members"`
+	DefaultMembers []string `toml:"default-members"`
+	Exclude        []string `toml:"exclude"`
+
+	Dependencies map[string]interface{} `toml:"dependencies"`
+}
+
+// Excludes reports whether dir is, or is inside, a path that `exclude` lists relative to the workspace root srcDir
+func (w ManifestWorkspace) Excludes(srcDir string, dir string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, excluded := range w.Exclude {
+		base, err := filepath.Abs(filepath.Join(srcDir, filepath.Clean(excluded)))
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(base, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDefaultMember reports whether dir is one of the `default-members`, which are paths or glob patterns relative to the
+// workspace root srcDir. Every member is a default member when the workspace doesn't list any.
+func (w ManifestWorkspace) IsDefaultMember(srcDir string, dir string) bool {
+	if len(w.DefaultMembers) == 0 {
+		return true
+	}
+
+	root, err := filepath.Abs(srcDir)
+	if err != nil {
+		return false
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, member := range w.DefaultMembers {
+		pattern := filepath.Join(root, filepath.Clean(member))
+		if matched, err := filepath.Match(pattern, dir); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MemberDirs lists the directories of the members of the workspace rooted at srcDir. Glob patterns are expanded like
+// cargo does, and excluded directories are skipped.
+func (w ManifestWorkspace) MemberDirs(srcDir string) ([]string, error) {
+	var dirs []string
+	for _, pattern := range w.Members {
+		matches := []string{filepath.Join(srcDir, filepath.Clean(pattern))}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(matches[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid workspace member %q\n%w", pattern, err)
+			}
+		}
+
+		for _, dir := range matches {
+			if w.Excludes(srcDir, dir) {
+				continue
+			}
+
+			// a glob also matches files, which cargo skips
+			if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+				continue
+			}
+
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// ValidateWorkspaceMembers checks that every member that the workspace in srcDir lists has a Cargo.toml, so that a
+// broken member is reported before cargo runs. All of the broken members are listed in the error.
+func ValidateWorkspaceMembers(srcDir string) error {
+	manifestPath := filepath.Join(srcDir, "Cargo.toml")
+	root, err := ParseManifest(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if root.Workspace == nil {
+		return nil
+	}
+
+	dirs, err := root.Workspace.MemberDirs(srcDir)
+	if err != nil {
+		return err
+	}
+
+	var broken []string
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, "Cargo.toml")); err != nil {
+			rel, _ := filepath.Rel(srcDir, dir)
+			broken = append(broken, rel)
+		}
+	}
+
+	if len(broken) > 0 {
+		return fmt.Errorf("workspace members in %s have no Cargo.toml: %s, fix `members` or add the missing packages",
+			manifestPath, strings.Join(broken, ", "))
+	}
+
+	return nil
+}
+
+// ManifestBin is a `[[bin]]` target from Cargo.toml
+type ManifestBin struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+}
+
+// Manifest is the parsed contents of Cargo.toml
+type Manifest struct {
+	Package   *ManifestPackage   `toml:"package"`
+	Workspace *ManifestWorkspace `toml:"workspace"`
+	Bins      []ManifestBin      `toml:"bin"`
+
+	Features          map[string][]string    `toml:"features"`
+	Dependencies      map[string]interface{} `toml:"dependencies"`
+	BuildDependencies map[string]interface{} `toml:"build-dependencies"`
+	Profiles          map[string]interface{} `toml:"profile"`
+}
+
+// IsVirtualManifest reports whether the Cargo.toml in srcDir only defines a workspace. There is no package at the
+// root of a virtual manifest for a plain `cargo install` to build, so its members have to be installed by path.
+func IsVirtualManifest(srcDir string) (bool, error) {
+	manifest, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return manifest.Package == nil && manifest.Workspace != nil, nil
+}
+
+// ParseManifest reads and parses the Cargo.toml file at path
+func ParseManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	_, err := toml.DecodeFile(path, &manifest)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to parse %s\n%w", path, err)
+	}
+	return manifest, nil
+}
+
+// HasBinaryTarget reports whether the package in projectDir, or a member of its workspace, has a binary target that
+// can be seen without building, either a `[[bin]]` table, `src/main.rs` or `src/bin`. Manifests without a
+// `[package]`, like virtual workspaces, are assumed to have one.
+func HasBinaryTarget(projectDir string) (bool, error) {
+	manifest, err := ParseManifest(filepath.Join(projectDir, "Cargo.toml"))
+	if err != nil {
+		return false, err
+	}
+
+	if manifest.Package == nil || hasBinaryTarget(projectDir, manifest) {
+		return true, nil
+	}
+
+	if manifest.Workspace == nil {
+		return false, nil
+	}
+
+	dirs, err := manifest.Workspace.MemberDirs(projectDir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, dir := range dirs {
+		member, err := ParseManifest(filepath.Join(dir, "Cargo.toml"))
+		if errors.Is(err, os.ErrNotExist) {
+			// reported by ValidateWorkspaceMembers during the build
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if member.Package != nil && hasBinaryTarget(dir, member) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func hasBinaryTarget(dir string, manifest Manifest) bool {
+	if len(manifest.Bins) > 0 {
+		return true
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "src", "main.rs")); err == nil && info.Mode().IsRegular() {
+		return true
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "src", "bin")); err == nil && info.IsDir() {
+		return true
+	}
+
+	return false
+}
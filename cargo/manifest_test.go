@@ -0,0 +1,122 @@
+package cargo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testManifest(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("parses a package manifest", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte(`
+[package]
+name = "app"
+rust-version = "1.56"
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+`), 0644)).To(Succeed())
+
+		manifest, err := cargo.ParseManifest(filepath.Join(workingDir, "Cargo.toml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifest.Package).To(Equal(&cargo.ManifestPackage{Name: "app", RustVersion: "1.56"}))
+		Expect(manifest.Workspace).To(BeNil())
+	})
+
+	it("parses a workspace manifest", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte(`
+[workspace]
+members = ["api", "crates/*"]
+default-members = ["api"]
+exclude = ["crates/old"]
+`), 0644)).To(Succeed())
+
+		manifest, err := cargo.ParseManifest(filepath.Join(workingDir, "Cargo.toml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifest.Package).To(BeNil())
+		Expect(manifest.Workspace).To(Equal(&cargo.ManifestWorkspace{
+			Members:        []string{"api", "crates/*"},
+			DefaultMembers: []string{"api"},
+			Exclude:        []string{"crates/old"},
+		}))
+	})
+
+	it("matches default members by path or glob pattern", func() {
+		workspace := cargo.ManifestWorkspace{DefaultMembers: []string{"api", "./crates/worker-*"}}
+
+		Expect(workspace.IsDefaultMember(workingDir, filepath.Join(workingDir, "api"))).To(BeTrue())
+		Expect(workspace.IsDefaultMember(workingDir, filepath.Join(workingDir, "crates", "worker-email"))).To(BeTrue())
+		Expect(workspace.IsDefaultMember(workingDir, filepath.Join(workingDir, "crates", "tools"))).To(BeFalse())
+		Expect(workspace.IsDefaultMember(workingDir, filepath.Join(workingDir, "api", "nested"))).To(BeFalse())
+
+		Expect(cargo.ManifestWorkspace{}.IsDefaultMember(workingDir, filepath.Join(workingDir, "crates", "tools"))).To(BeTrue())
+	})
+
+	context("ValidateWorkspaceMembers", func() {
+		writeMember := func(dir string) {
+			Expect(os.MkdirAll(filepath.Join(workingDir, dir), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, dir, "Cargo.toml"), []byte("[package]\nname = \"member\"\n"), 0644)).To(Succeed())
+		}
+
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte(`
+[workspace]
+members = ["api", "crates/*"]
+exclude = ["crates/old"]
+`), 0644)).To(Succeed())
+		})
+
+		it("accepts members that all have a Cargo.toml", func() {
+			writeMember("api")
+			writeMember("crates/one")
+			Expect(os.MkdirAll(filepath.Join(workingDir, "crates", "old"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "crates", "README.md"), []byte{}, 0644)).To(Succeed())
+
+			Expect(cargo.ValidateWorkspaceMembers(workingDir)).To(Succeed())
+		})
+
+		it("lists every member without a Cargo.toml", func() {
+			writeMember("crates/one")
+			Expect(os.MkdirAll(filepath.Join(workingDir, "crates", "two"), 0755)).To(Succeed())
+
+			err := cargo.ValidateWorkspaceMembers(workingDir)
+			Expect(err).To(MatchError(fmt.Sprintf("workspace members in %s have no Cargo.toml: api, crates/two, fix `members` or add the missing packages",
+				filepath.Join(workingDir, "Cargo.toml"))))
+		})
+
+		it("ignores projects that are not workspaces", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+			Expect(cargo.ValidateWorkspaceMembers(workingDir)).To(Succeed())
+		})
+	})
+
+	it("fails on a malformed manifest", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte(`[package`), 0644)).To(Succeed())
+
+		_, err := cargo.ParseManifest(filepath.Join(workingDir, "Cargo.toml"))
+		Expect(err).To(MatchError(ContainSubstring("unable to parse")))
+	})
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	packit "github.com/paketo-buildpacks/packit"
 	mock "github.com/stretchr/testify/mock"
 
@@ -14,6 +16,120 @@ type Runner struct {
 	mock.Mock
 }
 
+// CheckToolchain provides a mock function with given fields:
+func (_m *Runner) CheckToolchain() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Clean provides a mock function with given fields: workingDir, cargoLayer, binLayer
+func (_m *Runner) Clean(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error {
+	ret := _m.Called(workingDir, cargoLayer, binLayer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) error); ok {
+		r0 = rf(workingDir, cargoLayer, binLayer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Clippy provides a mock function with given fields: workingDir, cargoLayer, binLayer
+func (_m *Runner) Clippy(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error {
+	ret := _m.Called(workingDir, cargoLayer, binLayer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) error); ok {
+		r0 = rf(workingDir, cargoLayer, binLayer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DependencyGraph provides a mock function with given fields: srcDir, workLayer, destLayer
+func (_m *Runner) DependencyGraph(srcDir string, workLayer packit.Layer, destLayer packit.Layer) (string, error) {
+	ret := _m.Called(srcDir, workLayer, destLayer)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) string); ok {
+		r0 = rf(srcDir, workLayer, destLayer)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, packit.Layer, packit.Layer) error); ok {
+		r1 = rf(srcDir, workLayer, destLayer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EnsureComponents provides a mock function with given fields: srcDir, components
+func (_m *Runner) EnsureComponents(srcDir string, components []string) error {
+	ret := _m.Called(srcDir, components)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(srcDir, components)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Examples provides a mock function with given fields: srcDir, workLayer, destLayer
+func (_m *Runner) Examples(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	ret := _m.Called(srcDir, workLayer, destLayer)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) []string); ok {
+		r0 = rf(srcDir, workLayer, destLayer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, packit.Layer, packit.Layer) error); ok {
+		r1 = rf(srcDir, workLayer, destLayer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Fetch provides a mock function with given fields: srcDir, workLayer, destLayer
+func (_m *Runner) Fetch(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	ret := _m.Called(srcDir, workLayer, destLayer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) error); ok {
+		r0 = rf(srcDir, workLayer, destLayer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Install provides a mock function with given fields: srcDir, workLayer, destLayer
 func (_m *Runner) Install(srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
 	ret := _m.Called(srcDir, workLayer, destLayer)
@@ -28,13 +144,13 @@ func (_m *Runner) Install(srcDir string, workLayer packit.Layer, destLayer packi
 	return r0
 }
 
-// InstallMember provides a mock function with given fields: memberPath, srcDir, workLayer, destLayer
-func (_m *Runner) InstallMember(memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
-	ret := _m.Called(memberPath, srcDir, workLayer, destLayer)
+// InstallMember provides a mock function with given fields: ctx, memberPath, srcDir, workLayer, destLayer
+func (_m *Runner) InstallMember(ctx context.Context, memberPath string, srcDir string, workLayer packit.Layer, destLayer packit.Layer) error {
+	ret := _m.Called(ctx, memberPath, srcDir, workLayer, destLayer)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, packit.Layer, packit.Layer) error); ok {
-		r0 = rf(memberPath, srcDir, workLayer, destLayer)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, packit.Layer, packit.Layer) error); ok {
+		r0 = rf(ctx, memberPath, srcDir, workLayer, destLayer)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -42,6 +158,85 @@ func (_m *Runner) InstallMember(memberPath string, srcDir string, workLayer pack
 	return r0
 }
 
+// TargetInstalled provides a mock function with given fields: srcDir, target
+func (_m *Runner) TargetInstalled(srcDir string, target string) (bool, error) {
+	ret := _m.Called(srcDir, target)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(srcDir, target)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(srcDir, target)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TargetPackages provides a mock function with given fields: srcDir, workLayer, destLayer
+func (_m *Runner) TargetPackages(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]string, error) {
+	ret := _m.Called(srcDir, workLayer, destLayer)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) []string); ok {
+		r0 = rf(srcDir, workLayer, destLayer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, packit.Layer, packit.Layer) error); ok {
+		r1 = rf(srcDir, workLayer, destLayer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Test provides a mock function with given fields: workingDir, cargoLayer, binLayer
+func (_m *Runner) Test(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) error {
+	ret := _m.Called(workingDir, cargoLayer, binLayer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) error); ok {
+		r0 = rf(workingDir, cargoLayer, binLayer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Version provides a mock function with given fields: workingDir, cargoLayer, binLayer
+func (_m *Runner) Version(workingDir string, cargoLayer packit.Layer, binLayer packit.Layer) (string, error) {
+	ret := _m.Called(workingDir, cargoLayer, binLayer)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, packit.Layer, packit.Layer) string); ok {
+		r0 = rf(workingDir, cargoLayer, binLayer)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, packit.Layer, packit.Layer) error); ok {
+		r1 = rf(workingDir, cargoLayer, binLayer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WorkspaceMembers provides a mock function with given fields: srcDir, workLayer, destLayer
 func (_m *Runner) WorkspaceMembers(srcDir string, workLayer packit.Layer, destLayer packit.Layer) ([]url.URL, error) {
 	ret := _m.Called(srcDir, workLayer, destLayer)
@@ -0,0 +1,37 @@
+package cargo
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// MuslCompiler is the C compiler wrapper that links against musl, which C code in dependencies needs to be compiled
+// with for a musl target
+const MuslCompiler = "musl-gcc"
+
+// IsMuslTarget is true for targets that link against musl, like `x86_64-unknown-linux-musl`. Rust links binaries
+// for these targets statically, so they run without a libc in the image.
+func IsMuslTarget(target string) bool {
+	return strings.Contains(target, "-linux-musl")
+}
+
+// muslEnviron points the cc crate at musl-gcc for C code that is compiled for a musl target, unless a compiler for the
+// target is set already. Without musl-gcc, C code is compiled with the default compiler, which is fine for crates
+// that are pure Rust.
+func muslEnviron(env []string, target string) []string {
+	if !IsMuslTarget(target) {
+		return env
+	}
+
+	name := fmt.Sprintf("CC_%s", strings.ReplaceAll(target, "-", "_"))
+	if containsEnv(env, name) || containsEnv(env, fmt.Sprintf("CC_%s", target)) {
+		return env
+	}
+
+	if _, err := exec.LookPath(MuslCompiler); err != nil {
+		return env
+	}
+
+	return append(env, fmt.Sprintf("%s=%s", name, MuslCompiler))
+}
@@ -0,0 +1,59 @@
+package cargo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// NativeTools are the tools that `-sys` crates commonly need to build their C dependencies
+var NativeTools = []string{"cc", "pkg-config"}
+
+// SysCrates lists the `-sys` crates in the lockfile, sorted by name
+func (l Lockfile) SysCrates() []string {
+	var crates []string
+	for _, pkg := range l.Packages {
+		if strings.HasSuffix(pkg.Name, "-sys") && !contains(crates, pkg.Name) {
+			crates = append(crates, pkg.Name)
+		}
+	}
+	sort.Strings(crates)
+	return crates
+}
+
+// CheckNativeToolchain warns when the project depends on `-sys` crates but NativeTools are missing from the PATH, so
+// that a missing toolchain is reported before it surfaces as a build script or linker error
+func CheckNativeToolchain(srcDir string, logger scribe.Emitter) error {
+	lockfile, err := ParseLockfile(filepath.Join(srcDir, "Cargo.lock"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	crates := lockfile.SysCrates()
+	if len(crates) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, tool := range NativeTools {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+
+	if len(missing) > 0 {
+		logger.Subprocess("WARNING: these crates usually build native code [%s], but [%s] could not be found on the PATH",
+			strings.Join(crates, ", "), strings.Join(missing, ", "))
+		logger.Subprocess("WARNING: if the build fails in a build script or when linking, use a builder whose build image includes a C toolchain and pkg-config")
+	}
+
+	return nil
+}
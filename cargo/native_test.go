@@ -0,0 +1,85 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/fs"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testNative(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir       string
+		toolsDir     string
+		originalPath string
+		logBuf       bytes.Buffer
+		logger       scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		srcDir, err = ioutil.TempDir("", "src-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		toolsDir, err = ioutil.TempDir("", "tools")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(toolsDir, "cc"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+		originalPath = os.Getenv("PATH")
+		Expect(os.Setenv("PATH", toolsDir)).To(Succeed())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.Setenv("PATH", originalPath)).To(Succeed())
+		Expect(os.RemoveAll(srcDir)).To(Succeed())
+		Expect(os.RemoveAll(toolsDir)).To(Succeed())
+	})
+
+	it("lists the -sys crates", func() {
+		lockfile, err := cargo.ParseLockfile("testdata/lockfile_sys.toml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lockfile.SysCrates()).To(Equal([]string{"libz-sys", "openssl-sys"}))
+	})
+
+	it("warns about missing tools when there are -sys crates", func() {
+		Expect(fs.Copy("testdata/lockfile_sys.toml", filepath.Join(srcDir, "Cargo.lock"))).To(Succeed())
+
+		Expect(cargo.CheckNativeToolchain(srcDir, logger)).To(Succeed())
+		Expect(logBuf.String()).To(ContainSubstring("WARNING: these crates usually build native code [libz-sys, openssl-sys], but [pkg-config] could not be found on the PATH"))
+	})
+
+	it("does not warn when the tools are available", func() {
+		Expect(fs.Copy("testdata/lockfile_sys.toml", filepath.Join(srcDir, "Cargo.lock"))).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(toolsDir, "pkg-config"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+
+		Expect(cargo.CheckNativeToolchain(srcDir, logger)).To(Succeed())
+		Expect(logBuf.String()).To(BeEmpty())
+	})
+
+	it("does not warn when there are no -sys crates", func() {
+		Expect(os.Setenv("PATH", "")).To(Succeed())
+		Expect(fs.Copy("testdata/lockfile.toml", filepath.Join(srcDir, "Cargo.lock"))).To(Succeed())
+
+		Expect(cargo.CheckNativeToolchain(srcDir, logger)).To(Succeed())
+		Expect(logBuf.String()).To(BeEmpty())
+	})
+
+	it("does nothing without a lockfile", func() {
+		Expect(cargo.CheckNativeToolchain(srcDir, logger)).To(Succeed())
+		Expect(logBuf.String()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,38 @@
+package cargo
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// OfflineArgs returns `--offline` when BP_CARGO_OFFLINE is set, unless args already keep cargo off the network
+func OfflineArgs(args []string) ([]string, error) {
+	offline, err := ParseBoolEnv("BP_CARGO_OFFLINE")
+	if err != nil {
+		return nil, err
+	}
+
+	if !offline || contains(args, "--offline") || contains(args, "--frozen") {
+		return nil, nil
+	}
+
+	return []string{"--offline"}, nil
+}
+
+// CheckOfflineSources warns when an offline build has neither a `vendor` directory nor a `.cargo/config.toml` in
+// srcDir, in which case every dependency has to be in the cached cargo home already
+func CheckOfflineSources(srcDir string, logger scribe.Emitter) {
+	if info, err := os.Stat(filepath.Join(srcDir, "vendor")); err == nil && info.IsDir() {
+		return
+	}
+
+	for _, name := range []string{"config.toml", "config"} {
+		if _, err := os.Stat(filepath.Join(srcDir, ".cargo", name)); err == nil {
+			return
+		}
+	}
+
+	logger.Subprocess("WARNING: BP_CARGO_OFFLINE is set but there is no vendor directory or .cargo/config.toml, dependencies that are not in the cache will fail to resolve")
+}
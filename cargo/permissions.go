@@ -0,0 +1,94 @@
+package cargo
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// NormalizePermissions ensures everything under path is writable by the build user. Files owned by a
+// different uid are chowned to the build user, if that is not possible the contents of path are removed
+// so that cargo can rebuild from scratch instead of failing part way through.
+func NormalizePermissions(path string, logger scribe.Emitter) error {
+	uid, gid := os.Getuid(), os.Getgid()
+
+	repaired := 0
+	foreign := make(map[uint32]int)
+	unrepairable := false
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == path {
+				return filepath.SkipDir
+			}
+			return fmt.Errorf("unable to read directory\n%w", err)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("unable to read file\n%w", err)
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != uid {
+			foreign[stat.Uid]++
+			if err := os.Lchown(p, uid, gid); err != nil {
+				unrepairable = true
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		required := fs.FileMode(0600)
+		if d.IsDir() {
+			required = 0700
+		}
+
+		if info.Mode().Perm()&required != required {
+			err := os.Chmod(p, info.Mode().Perm()|required)
+			if err != nil {
+				return fmt.Errorf("unable to change permissions of %s\n%w", p, err)
+			}
+			repaired++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to normalize permissions of %s\n%w", path, err)
+	}
+
+	for owner, count := range foreign {
+		logger.Subprocess("Found %d cached files owned by uid %d, build user is uid %d", count, owner, uid)
+	}
+
+	if repaired > 0 {
+		logger.Subprocess("Repaired permissions on %d cached files", repaired)
+	}
+
+	if unrepairable {
+		logger.Subprocess("WARNING: unable to take ownership of cached files, clearing the cache at %s", path)
+		files, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("unable to read directory\n%w", err)
+		}
+
+		for _, file := range files {
+			err := os.RemoveAll(filepath.Join(path, file.Name()))
+			if err != nil {
+				return fmt.Errorf("unable to remove files\n%w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPermissions(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerDir string
+		logBuf   bytes.Buffer
+		logger   scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		layerDir, err = ioutil.TempDir("", "layer-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layerDir)).To(Succeed())
+	})
+
+	context("when the cache is read-restricted", func() {
+		it.Before(func() {
+			targetDir := filepath.Join(layerDir, "target", "release")
+			Expect(os.MkdirAll(targetDir, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(targetDir, "app.d"), []byte("deps"), 0444)).To(Succeed())
+			Expect(os.Chmod(targetDir, 0555)).To(Succeed())
+			Expect(os.Chmod(filepath.Join(layerDir, "target"), 0500)).To(Succeed())
+		})
+
+		it("makes the cache writable again", func() {
+			Expect(cargo.NormalizePermissions(layerDir, logger)).To(Succeed())
+
+			info, err := os.Stat(filepath.Join(layerDir, "target"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+
+			info, err = os.Stat(filepath.Join(layerDir, "target", "release"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			info, err = os.Stat(filepath.Join(layerDir, "target", "release", "app.d"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+
+			Expect(logBuf.String()).To(ContainSubstring("Repaired permissions on 3 cached files"))
+		})
+	})
+
+	context("when the cache is already writable", func() {
+		it("does nothing", func() {
+			Expect(ioutil.WriteFile(filepath.Join(layerDir, "file"), []byte("file"), 0644)).To(Succeed())
+			Expect(cargo.NormalizePermissions(layerDir, logger)).To(Succeed())
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+	})
+
+	context("when the cache does not exist", func() {
+		it("does nothing", func() {
+			Expect(cargo.NormalizePermissions(filepath.Join(layerDir, "missing"), logger)).To(Succeed())
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// IOClasses maps the values accepted by BP_CARGO_IONICE to the kernel's I/O scheduling classes
+var IOClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// Priority is the CPU and I/O priority cargo runs at
+type Priority struct {
+	// Nice is the niceness, from -20 to 19, or nil to leave it unchanged
+	Nice *int
+	// IOClass is one of the IOClasses, or empty to leave it unchanged
+	IOClass string
+}
+
+// LoadPriority reads the priority from BP_CARGO_NICE and BP_CARGO_IONICE
+func LoadPriority() (Priority, error) {
+	var priority Priority
+
+	if value := os.Getenv("BP_CARGO_NICE"); value != "" {
+		nice, err := strconv.Atoi(value)
+		if err != nil || nice < -20 || nice > 19 {
+			return Priority{}, fmt.Errorf("invalid value for BP_CARGO_NICE %q, must be a number from -20 to 19", value)
+		}
+		priority.Nice = &nice
+	}
+
+	if value := os.Getenv("BP_CARGO_IONICE"); value != "" {
+		if _, ok := IOClasses[value]; !ok {
+			return Priority{}, fmt.Errorf("invalid value for BP_CARGO_IONICE %q, must be one of realtime, best-effort or idle", value)
+		}
+		priority.IOClass = value
+	}
+
+	return priority, nil
+}
+
+// ApplyPriority changes the priority of the buildpack process, which cargo and the compilers it starts inherit. If
+// the platform does not permit the change, a warning is logged and the build continues at the current priority.
+func ApplyPriority(priority Priority, logger scribe.Emitter) {
+	if priority.Nice != nil {
+		err := setNice(*priority.Nice)
+		if err != nil {
+			logger.Subprocess("WARNING: unable to set the nice value to %d, continuing with the current priority: %s", *priority.Nice, err)
+		} else {
+			logger.Subprocess("Running cargo with nice value %d", *priority.Nice)
+		}
+	}
+
+	if priority.IOClass != "" {
+		err := setIOClass(IOClasses[priority.IOClass])
+		if err != nil {
+			logger.Subprocess("WARNING: unable to set the I/O scheduling class to %s, continuing with the current priority: %s", priority.IOClass, err)
+		} else {
+			logger.Subprocess("Running cargo with I/O scheduling class %s", priority.IOClass)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	// ioprioDefaultLevel is the level used for the realtime and best-effort classes, the idle class has no levels
+	ioprioDefaultLevel = 4
+)
+
+// threads lists the ids of the threads in this process. On Linux, the nice value and I/O priority belong to each
+// thread rather than the process, and a child process inherits them from the thread that started it.
+func threads() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list threads\n%w", err)
+	}
+
+	var tids []int
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		tids = append(tids, tid)
+	}
+
+	return tids, nil
+}
+
+func setNice(nice int) error {
+	tids, err := threads()
+	if err != nil {
+		return err
+	}
+
+	for _, tid := range tids {
+		err = syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setIOClass(class int) error {
+	tids, err := threads()
+	if err != nil {
+		return err
+	}
+
+	level := ioprioDefaultLevel
+	if class == IOClasses["idle"] {
+		level = 0
+	}
+
+	for _, tid := range tids {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(class<<ioprioClassShift|level))
+		if errno != 0 {
+			return errno
+		}
+	}
+
+	return nil
+}
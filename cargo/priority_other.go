@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package cargo
+
+import "errors"
+
+var errPriorityUnsupported = errors.New("changing the priority is only supported on Linux")
+
+func setNice(nice int) error {
+	return errPriorityUnsupported
+}
+
+func setIOClass(class int) error {
+	return errPriorityUnsupported
+}
@@ -0,0 +1,96 @@
+package cargo_test
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPriority(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_CARGO_NICE")).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_IONICE")).To(Succeed())
+	})
+
+	context("LoadPriority", func() {
+		it("leaves the priority unchanged by default", func() {
+			Expect(cargo.LoadPriority()).To(Equal(cargo.Priority{}))
+		})
+
+		it("reads the nice value and I/O class", func() {
+			Expect(os.Setenv("BP_CARGO_NICE", "10")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_IONICE", "idle")).To(Succeed())
+
+			priority, err := cargo.LoadPriority()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*priority.Nice).To(Equal(10))
+			Expect(priority.IOClass).To(Equal("idle"))
+		})
+
+		it("rejects an out of range nice value", func() {
+			Expect(os.Setenv("BP_CARGO_NICE", "20")).To(Succeed())
+
+			_, err := cargo.LoadPriority()
+			Expect(err).To(MatchError(`invalid value for BP_CARGO_NICE "20", must be a number from -20 to 19`))
+		})
+
+		it("rejects an unknown I/O class", func() {
+			Expect(os.Setenv("BP_CARGO_IONICE", "low")).To(Succeed())
+
+			_, err := cargo.LoadPriority()
+			Expect(err).To(MatchError(`invalid value for BP_CARGO_IONICE "low", must be one of realtime, best-effort or idle`))
+		})
+	})
+
+	context("ApplyPriority", func() {
+		it("lowers the priority of the process", func() {
+			if runtime.GOOS != "linux" {
+				t.Skip("priorities are only applied on Linux")
+			}
+
+			// the kernel reports 20 - nice, and raising the nice value is always permitted
+			current, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+			Expect(err).NotTo(HaveOccurred())
+			nice := 20 - current
+			if nice < 19 {
+				nice++
+			}
+
+			cargo.ApplyPriority(cargo.Priority{Nice: &nice}, logger)
+			Expect(logBuf.String()).To(ContainSubstring("Running cargo with nice value %d", nice))
+
+			updated, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(20 - updated).To(Equal(nice))
+		})
+
+		it("logs and continues when the priority cannot be changed", func() {
+			if os.Geteuid() == 0 {
+				t.Skip("root is permitted to use the realtime class")
+			}
+
+			cargo.ApplyPriority(cargo.Priority{IOClass: "realtime"}, logger)
+			Expect(logBuf.String()).To(ContainSubstring("WARNING: unable to set the I/O scheduling class to realtime, continuing with the current priority"))
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit"
+)
+
+// ProcessesFile is the name of the file, inside the rust-processes layer, that lists the registered processes
+const ProcessesFile = "processes.json"
+
+// DefaultProcessType is the type of the process that runs the primary binary, the lifecycle starts it when no
+// process type is requested
+const DefaultProcessType = "web"
+
+// ProcessesSchemaVersion is the version of the processes.json format, it changes whenever a field is removed or
+// changes meaning
+const ProcessesSchemaVersion = 1
+
+// ProcessEntry describes a single registered process in processes.json
+type ProcessEntry struct {
+	Type    string   `json:"type"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Default bool     `json:"default"`
+}
+
+// ProcessList is the content of processes.json
+type ProcessList struct {
+	SchemaVersion int            `json:"schema_version"`
+	Processes     []ProcessEntry `json:"processes"`
+}
+
+// NewProcessList describes processes, marking the process of type defaultType as the default
+func NewProcessList(processes []packit.Process, defaultType string) ProcessList {
+	list := ProcessList{
+		SchemaVersion: ProcessesSchemaVersion,
+		Processes:     []ProcessEntry{},
+	}
+
+	for _, process := range processes {
+		args := process.Args
+		if args == nil {
+			args = []string{}
+		}
+
+		list.Processes = append(list.Processes, ProcessEntry{
+			Type:    process.Type,
+			Command: process.Command,
+			Args:    args,
+			Default: process.Type == defaultType,
+		})
+	}
+
+	return list
+}
+
+// WriteProcesses writes list as processes.json into dir
+func WriteProcesses(dir string, list ProcessList) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode processes\n%w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ProcessesFile), content, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write processes\n%w", err)
+	}
+
+	return nil
+}
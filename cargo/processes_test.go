@@ -0,0 +1,43 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProcesses(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("writes a versioned list of processes", func() {
+		dir, err := ioutil.TempDir("", "processes")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		list := cargo.NewProcessList([]packit.Process{
+			{Type: "web", Command: "/layers/rust-bin/bin/server", Args: []string{"--port", "8080"}, Direct: true},
+			{Type: "migrate", Command: "/layers/rust-bin/bin/migrate", Direct: true},
+		}, "web")
+		Expect(cargo.WriteProcesses(dir, list)).To(Succeed())
+
+		Expect(ioutil.ReadFile(filepath.Join(dir, cargo.ProcessesFile))).To(MatchJSON(`{
+			"schema_version": 1,
+			"processes": [
+				{"type": "web", "command": "/layers/rust-bin/bin/server", "args": ["--port", "8080"], "default": true},
+				{"type": "migrate", "command": "/layers/rust-bin/bin/migrate", "args": [], "default": false}
+			]
+		}`))
+	})
+
+	it("writes an empty list when there are no processes", func() {
+		Expect(cargo.NewProcessList(nil, "").Processes).To(BeEmpty())
+		Expect(cargo.NewProcessList(nil, "").Processes).NotTo(BeNil())
+	})
+}
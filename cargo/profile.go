@@ -0,0 +1,162 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the Cargo profile binaries are built with when BP_CARGO_PROFILE is not set
+const DefaultProfile = "release"
+
+// BuiltinProfiles are the profiles Cargo defines without a `[profile]` table in Cargo.toml
+var BuiltinProfiles = []string{"dev", "release", "test", "bench"}
+
+// OptLevels are the values accepted by BP_CARGO_OPT_LEVEL
+var OptLevels = []string{"0", "1", "2", "3", "s", "z"}
+
+// LTOValues are the values accepted by BP_CARGO_LTO
+var LTOValues = []string{"off", "thin", "fat", "true", "false"}
+
+// OptLevel returns the optimization level set with BP_CARGO_OPT_LEVEL, empty means the profile's own setting is used
+func OptLevel() (string, error) {
+	level := strings.TrimSpace(os.Getenv("BP_CARGO_OPT_LEVEL"))
+	if level == "" {
+		return "", nil
+	}
+
+	if !contains(OptLevels, level) {
+		return "", fmt.Errorf("invalid value for BP_CARGO_OPT_LEVEL %q, must be one of %s", level, strings.Join(OptLevels, ", "))
+	}
+
+	return level, nil
+}
+
+// LTO returns the link-time optimization setting from BP_CARGO_LTO, empty means the profile's own setting is used
+func LTO() (string, error) {
+	lto := strings.ToLower(strings.TrimSpace(os.Getenv("BP_CARGO_LTO")))
+	if lto == "" {
+		return "", nil
+	}
+
+	if !contains(LTOValues, lto) {
+		return "", fmt.Errorf("invalid value for BP_CARGO_LTO %q, must be one of %s", lto, strings.Join(LTOValues, ", "))
+	}
+
+	return lto, nil
+}
+
+// ProfileName returns the profile set with BP_CARGO_PROFILE, or DefaultProfile
+func ProfileName() string {
+	profile := strings.TrimSpace(os.Getenv("BP_CARGO_PROFILE"))
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// ValidateProfile returns the profile set with BP_CARGO_PROFILE, failing unless it is one of BuiltinProfiles or is
+// defined by a `[profile.<name>]` table in the Cargo.toml in srcDir
+func ValidateProfile(srcDir string) (string, error) {
+	profile := ProfileName()
+	if contains(BuiltinProfiles, profile) {
+		return profile, nil
+	}
+
+	manifest, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := manifest.Profiles[profile]; ok {
+		return profile, nil
+	}
+
+	valid := append([]string{}, BuiltinProfiles...)
+	for name := range manifest.Profiles {
+		if !contains(valid, name) {
+			valid = append(valid, name)
+		}
+	}
+	sort.Strings(valid)
+
+	return "", fmt.Errorf("unknown profile %q in BP_CARGO_PROFILE, valid profiles are [%s]", profile, strings.Join(valid, ", "))
+}
+
+// ProfileDir is the directory, below the target directory, that Cargo writes the output of profile to
+func ProfileDir(profile string) string {
+	switch profile {
+	case "dev", "test":
+		return "debug"
+	case "bench":
+		return "release"
+	default:
+		return profile
+	}
+}
+
+// ProfileSettings returns the profile settings from the environment, keyed by the name of the setting in Cargo.toml
+func ProfileSettings() (map[string]string, error) {
+	settings := map[string]string{}
+
+	level, err := OptLevel()
+	if err != nil {
+		return nil, err
+	}
+
+	if level != "" {
+		// numeric levels are integers in TOML, `s` and `z` are strings
+		if level == "s" || level == "z" {
+			level = fmt.Sprintf("%q", level)
+		}
+		settings["opt-level"] = level
+	}
+
+	lto, err := LTO()
+	if err != nil {
+		return nil, err
+	}
+
+	if lto != "" {
+		// `true` and `false` are booleans in TOML, the named settings are strings
+		if lto != "true" && lto != "false" {
+			lto = fmt.Sprintf("%q", lto)
+		}
+		settings["lto"] = lto
+	}
+
+	return settings, nil
+}
+
+// ProfileConfigArgs returns the `--config` arguments that apply the profile settings from the environment, sorted
+// by setting so that the arguments are stable
+func ProfileConfigArgs() ([]string, error) {
+	settings, err := ProfileSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, setting := range sortedSettings(settings) {
+		args = append(args, fmt.Sprintf("--config=profile.%s.%s", ProfileName(), setting))
+	}
+
+	return args, nil
+}
+
+// ProfileSettingsKey describes settings in a single string, which is recorded in the cache metadata so that changes
+// are noticed
+func ProfileSettingsKey(settings map[string]string) string {
+	return strings.Join(sortedSettings(settings), ",")
+}
+
+func sortedSettings(settings map[string]string) []string {
+	var pairs []string
+	for key, value := range settings {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
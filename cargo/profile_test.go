@@ -0,0 +1,164 @@
+package cargo_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProfile(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_CARGO_OPT_LEVEL")).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_LTO")).To(Succeed())
+		Expect(os.Unsetenv("BP_CARGO_PROFILE")).To(Succeed())
+	})
+
+	it("adds nothing by default", func() {
+		args, err := cargo.ProfileConfigArgs()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(BeEmpty())
+	})
+
+	context("BP_CARGO_OPT_LEVEL", func() {
+		for level, expected := range map[string]string{
+			"0": "--config=profile.release.opt-level=0",
+			"1": "--config=profile.release.opt-level=1",
+			"2": "--config=profile.release.opt-level=2",
+			"3": "--config=profile.release.opt-level=3",
+			"s": `--config=profile.release.opt-level="s"`,
+			"z": `--config=profile.release.opt-level="z"`,
+		} {
+			level, expected := level, expected
+
+			it("sets opt-level "+level, func() {
+				Expect(os.Setenv("BP_CARGO_OPT_LEVEL", level)).To(Succeed())
+
+				args, err := cargo.ProfileConfigArgs()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(args).To(Equal([]string{expected}))
+			})
+		}
+
+		it("rejects other values", func() {
+			Expect(os.Setenv("BP_CARGO_OPT_LEVEL", "4")).To(Succeed())
+
+			_, err := cargo.ProfileConfigArgs()
+			Expect(err).To(MatchError(`invalid value for BP_CARGO_OPT_LEVEL "4", must be one of 0, 1, 2, 3, s, z`))
+		})
+
+		it("describes the settings for the cache metadata", func() {
+			Expect(os.Setenv("BP_CARGO_OPT_LEVEL", "s")).To(Succeed())
+
+			settings, err := cargo.ProfileSettings()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cargo.ProfileSettingsKey(settings)).To(Equal(`opt-level="s"`))
+		})
+
+		it("is passed to cargo install and cargo build", func() {
+			Expect(os.Setenv("BP_CARGO_OPT_LEVEL", "z")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.BuildArgs(packit.Layer{Name: "rust-bin", Path: "/layers/rust-bin"}, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement(`--config=profile.release.opt-level="z"`))
+
+			args, _, err = cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement(`--config=profile.release.opt-level="z"`))
+		})
+	})
+
+	context("BP_CARGO_LTO", func() {
+		for lto, expected := range map[string]string{
+			"off":   `--config=profile.release.lto="off"`,
+			"thin":  `--config=profile.release.lto="thin"`,
+			"fat":   `--config=profile.release.lto="fat"`,
+			"true":  "--config=profile.release.lto=true",
+			"false": "--config=profile.release.lto=false",
+		} {
+			lto, expected := lto, expected
+
+			it("sets lto "+lto, func() {
+				Expect(os.Setenv("BP_CARGO_LTO", lto)).To(Succeed())
+
+				args, err := cargo.ProfileConfigArgs()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(args).To(Equal([]string{expected}))
+			})
+		}
+
+		it("rejects other values", func() {
+			Expect(os.Setenv("BP_CARGO_LTO", "full")).To(Succeed())
+
+			_, err := cargo.ProfileConfigArgs()
+			Expect(err).To(MatchError(`invalid value for BP_CARGO_LTO "full", must be one of off, thin, fat, true, false`))
+		})
+
+		it("is part of the cache metadata alongside the opt-level", func() {
+			Expect(os.Setenv("BP_CARGO_LTO", "thin")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_OPT_LEVEL", "3")).To(Succeed())
+
+			settings, err := cargo.ProfileSettings()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cargo.ProfileSettingsKey(settings)).To(Equal(`lto="thin",opt-level=3`))
+
+			args, err := cargo.ProfileConfigArgs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{`--config=profile.release.lto="thin"`, "--config=profile.release.opt-level=3"}))
+		})
+	})
+
+	context("BP_CARGO_PROFILE", func() {
+		it("builds with the release profile by default", func() {
+			Expect(cargo.ValidateProfile("testdata/profiles")).To(Equal("release"))
+
+			args, err := cargo.CLIRunner{}.BuildArgs(packit.Layer{Name: "rust-bin", Path: "/layers/rust-bin"}, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"install", "--locked", "--color=never", "--root=/layers/rust-bin", "--path=."}))
+
+			args, _, err = cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"build", "--release", "--locked", "--color=never", "--manifest-path=Cargo.toml"}))
+		})
+
+		it("passes --profile instead of --release for other profiles", func() {
+			Expect(os.Setenv("BP_CARGO_PROFILE", "dev")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_OPT_LEVEL", "1")).To(Succeed())
+
+			args, err := cargo.CLIRunner{}.BuildArgs(packit.Layer{Name: "rust-bin", Path: "/layers/rust-bin"}, ".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"install", "--locked", "--profile=dev", "--config=profile.dev.opt-level=1", "--color=never", "--root=/layers/rust-bin", "--path=."}))
+
+			args, _, err = cargo.CLIRunner{}.CompileArgs(".")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{"build", "--profile=dev", "--locked", "--config=profile.dev.opt-level=1", "--color=never", "--manifest-path=Cargo.toml"}))
+		})
+
+		it("accepts a custom profile from Cargo.toml", func() {
+			Expect(os.Setenv("BP_CARGO_PROFILE", "staging")).To(Succeed())
+
+			Expect(cargo.ValidateProfile("testdata/profiles")).To(Equal("staging"))
+		})
+
+		it("lists the valid profiles when the profile is unknown", func() {
+			Expect(os.Setenv("BP_CARGO_PROFILE", "stagign")).To(Succeed())
+
+			_, err := cargo.ValidateProfile("testdata/profiles")
+			Expect(err).To(MatchError(`unknown profile "stagign" in BP_CARGO_PROFILE, valid profiles are [bench, dev, release, staging, test]`))
+		})
+
+		it("knows where cargo writes the output of each profile", func() {
+			Expect(cargo.ProfileDir("dev")).To(Equal("debug"))
+			Expect(cargo.ProfileDir("test")).To(Equal("debug"))
+			Expect(cargo.ProfileDir("release")).To(Equal("release"))
+			Expect(cargo.ProfileDir("bench")).To(Equal("release"))
+			Expect(cargo.ProfileDir("staging")).To(Equal("staging"))
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package cargo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RegistryLayerName is the cache layer that holds the registry directory of cargo home, so that the index and
+// downloaded crates are kept separately from the build output
+const RegistryLayerName = "rust-registry"
+
+// LockfileChecksum returns the sha256 of Cargo.lock in srcDir, or an empty string when there is no Cargo.lock
+func LockfileChecksum(srcDir string) (string, error) {
+	checksum, err := HashFile(filepath.Join(srcDir, "Cargo.lock"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return checksum, err
+}
+
+// HashFile returns the hex encoded sha256 of the contents of the file at path
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// LinkRegistry makes the registry directory in cargoHome a link to registryDir. A registry directory left in
+// cargoHome by an earlier build is moved to registryDir, unless registryDir already exists.
+func LinkRegistry(cargoHome string, registryDir string) error {
+	link := filepath.Join(cargoHome, "registry")
+
+	info, err := os.Lstat(link)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to read %s\n%w", link, err)
+	}
+
+	if err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, _ := os.Readlink(link); target == registryDir {
+				// the layer may not have been restored from the cache, even though the link was
+				return ensureDir(registryDir)
+			}
+		} else if info.IsDir() {
+			if _, err := os.Stat(registryDir); errors.Is(err, os.ErrNotExist) {
+				err = os.Rename(link, registryDir)
+				if err != nil {
+					return fmt.Errorf("unable to move %s to %s\n%w", link, registryDir, err)
+				}
+			}
+		}
+
+		err = os.RemoveAll(link)
+		if err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", link, err)
+		}
+	}
+
+	err = ensureDir(registryDir)
+	if err != nil {
+		return err
+	}
+
+	err = ensureDir(cargoHome)
+	if err != nil {
+		return err
+	}
+
+	err = os.Symlink(registryDir, link)
+	if err != nil {
+		return fmt.Errorf("unable to link %s to %s\n%w", link, registryDir, err)
+	}
+
+	return nil
+}
+
+func ensureDir(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create %s\n%w", dir, err)
+	}
+	return nil
+}
+
+// PruneGitCheckouts removes the working trees that cargo checked out for git dependencies from cargoHome. The
+// cloned repositories in `git/db` are kept, so checking out another revision doesn't clone them again.
+func PruneGitCheckouts(cargoHome string) error {
+	checkouts := filepath.Join(cargoHome, "git", "checkouts")
+	err := os.RemoveAll(checkouts)
+	if err != nil {
+		return fmt.Errorf("unable to remove %s\n%w", checkouts, err)
+	}
+	return nil
+}
+
+// PruneRegistrySources removes the crate sources that cargo extracted into registryDir. The index and the
+// downloaded archives are kept, cargo extracts the crates it needs again.
+func PruneRegistrySources(registryDir string) error {
+	srcDir := filepath.Join(registryDir, "src")
+	err := os.RemoveAll(srcDir)
+	if err != nil {
+		return fmt.Errorf("unable to remove %s\n%w", srcDir, err)
+	}
+	return nil
+}
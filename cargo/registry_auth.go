@@ -0,0 +1,98 @@
+package cargo
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registryToken matches the environment variables cargo reads registry tokens from, CARGO_REGISTRY_TOKEN for
+// crates.io and CARGO_REGISTRIES_<NAME>_TOKEN for other registries
+var registryToken = regexp.MustCompile(`^CARGO_(REGISTRY|REGISTRIES_[A-Z0-9_]+)_TOKEN$`)
+
+// RegistryTokens returns the names of the registry token variables set in environ, sorted by name
+func RegistryTokens(environ []string) []string {
+	var names []string
+	for _, entry := range environ {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 && parts[1] != "" && registryToken.MatchString(parts[0]) {
+			names = append(names, parts[0])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RedactingWriter redacts the values of secret looking environment variables, see Redact, from everything written
+// to it. Output is passed on a line at a time, so that a secret split across writes is still redacted. Flush passes
+// on the last line when it doesn't end with a newline.
+type RedactingWriter struct {
+	writer  io.Writer
+	environ []string
+
+	mutex   sync.Mutex
+	pending []byte
+}
+
+// NewRedactingWriter wraps writer so that the values of secret looking variables in environ are redacted
+func NewRedactingWriter(writer io.Writer, environ []string) *RedactingWriter {
+	return &RedactingWriter{writer: writer, environ: environ}
+}
+
+// redactTokens wraps writer with a redactingWriter when registry tokens are set in environ, otherwise writer is
+// returned as is
+func redactTokens(writer io.Writer, environ []string) io.Writer {
+	if len(RegistryTokens(environ)) == 0 {
+		return writer
+	}
+	return NewRedactingWriter(writer, environ)
+}
+
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pending = append(r.pending, p...)
+	end := bytes.LastIndexByte(r.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+
+	lines := string(r.pending[:end+1])
+	r.pending = append([]byte(nil), r.pending[end+1:]...)
+
+	_, err := r.writer.Write([]byte(Redact(lines, r.environ)))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush redacts and passes on what is left of the output
+func (r *RedactingWriter) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	rest := string(r.pending)
+	r.pending = nil
+
+	_, err := r.writer.Write([]byte(Redact(rest, r.environ)))
+	return err
+}
+
+// flushRedacted flushes the writers that are RedactingWriters, once the command writing to them is done. The output
+// is only logged, so an error writing it doesn't fail the command.
+func flushRedacted(writers ...io.Writer) {
+	for _, writer := range writers {
+		if redacting, ok := writer.(*RedactingWriter); ok {
+			_ = redacting.Flush()
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cargo_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistryAuth(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("finds the registry token variables", func() {
+		Expect(cargo.RegistryTokens([]string{
+			"PATH=/usr/bin",
+			"CARGO_REGISTRIES_INTERNAL_TOKEN=s3cr3t-internal",
+			"CARGO_REGISTRY_TOKEN=s3cr3t-crates-io",
+			"CARGO_REGISTRIES_EMPTY_TOKEN=",
+			"CARGO_REGISTRIES_INTERNAL_INDEX=https://crates.example.com/index",
+		})).To(Equal([]string{"CARGO_REGISTRIES_INTERNAL_TOKEN", "CARGO_REGISTRY_TOKEN"}))
+	})
+
+	it("redacts tokens from everything written", func() {
+		buf := bytes.Buffer{}
+		writer := cargo.NewRedactingWriter(&buf, []string{"CARGO_REGISTRIES_INTERNAL_TOKEN=s3cr3t-internal"})
+
+		n, err := writer.Write([]byte("Updating index with s3cr3t-internal\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(len("Updating index with s3cr3t-internal\n")))
+		Expect(buf.String()).To(Equal("Updating index with [REDACTED]\n"))
+	})
+
+	it("redacts a token that is split across writes", func() {
+		buf := bytes.Buffer{}
+		writer := cargo.NewRedactingWriter(&buf, []string{"CARGO_REGISTRIES_INTERNAL_TOKEN=s3cr3t-internal"})
+
+		_, err := writer.Write([]byte("Updating index with s3cr"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.String()).To(BeEmpty())
+
+		_, err = writer.Write([]byte("3t-internal\nDownloading s3cr3t"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write([]byte("-internal"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Flush()).To(Succeed())
+
+		Expect(buf.String()).To(Equal("Updating index with [REDACTED]\nDownloading [REDACTED]"))
+	})
+}
@@ -0,0 +1,128 @@
+package cargo_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dir string
+	)
+
+	it.Before(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "registry")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	context("LockfileChecksum", func() {
+		it("hashes Cargo.lock", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "Cargo.lock"), []byte{}, 0644)).To(Succeed())
+
+			checksum, err := cargo.LockfileChecksum(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checksum).To(Equal("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+		})
+
+		it("is empty when there is no Cargo.lock", func() {
+			checksum, err := cargo.LockfileChecksum(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checksum).To(BeEmpty())
+		})
+	})
+
+	context("HashFile", func() {
+		it("hashes the contents of the file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "Cargo.lock"), []byte("version = 3\n"), 0644)).To(Succeed())
+
+			checksum, err := cargo.HashFile(filepath.Join(dir, "Cargo.lock"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checksum).To(HaveLen(64))
+			Expect(checksum).NotTo(Equal("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+		})
+
+		it("fails when the file does not exist", func() {
+			_, err := cargo.HashFile(filepath.Join(dir, "Cargo.lock"))
+			Expect(err).To(MatchError(ContainSubstring("unable to read")))
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+
+	context("PruneGitCheckouts", func() {
+		it("removes the checkouts and keeps the cloned repositories", func() {
+			Expect(os.MkdirAll(filepath.Join(dir, "git", "checkouts", "dep-1234", "abcdef"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(dir, "git", "db", "dep-1234"), 0755)).To(Succeed())
+
+			Expect(cargo.PruneGitCheckouts(dir)).To(Succeed())
+			Expect(filepath.Join(dir, "git", "checkouts")).NotTo(BeADirectory())
+			Expect(filepath.Join(dir, "git", "db", "dep-1234")).To(BeADirectory())
+		})
+	})
+
+	context("LinkRegistry", func() {
+		var cargoHome, registryDir string
+
+		it.Before(func() {
+			cargoHome = filepath.Join(dir, "rust-cargo", "home")
+			registryDir = filepath.Join(dir, "rust-registry")
+		})
+
+		it("links the registry in cargo home to the registry layer", func() {
+			Expect(cargo.LinkRegistry(cargoHome, registryDir)).To(Succeed())
+
+			target, err := os.Readlink(filepath.Join(cargoHome, "registry"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(registryDir))
+			Expect(registryDir).To(BeADirectory())
+
+			// running again keeps the link
+			Expect(cargo.LinkRegistry(cargoHome, registryDir)).To(Succeed())
+			Expect(filepath.Join(cargoHome, "registry", "index")).NotTo(BeAnExistingFile())
+		})
+
+		it("recreates the registry layer when only the link was cached", func() {
+			Expect(cargo.LinkRegistry(cargoHome, registryDir)).To(Succeed())
+			Expect(os.RemoveAll(registryDir)).To(Succeed())
+
+			Expect(cargo.LinkRegistry(cargoHome, registryDir)).To(Succeed())
+			Expect(registryDir).To(BeADirectory())
+		})
+
+		it("moves a registry left in cargo home into the registry layer", func() {
+			index := filepath.Join(cargoHome, "registry", "index")
+			Expect(os.MkdirAll(index, 0755)).To(Succeed())
+
+			Expect(cargo.LinkRegistry(cargoHome, registryDir)).To(Succeed())
+			Expect(filepath.Join(registryDir, "index")).To(BeADirectory())
+			Expect(filepath.Join(cargoHome, "registry", "index")).To(BeADirectory())
+		})
+	})
+
+	context("PruneRegistrySources", func() {
+		it("removes extracted sources but keeps the index and archives", func() {
+			for _, name := range []string{"index", "cache", "src"} {
+				Expect(os.MkdirAll(filepath.Join(dir, name), 0755)).To(Succeed())
+			}
+
+			Expect(cargo.PruneRegistrySources(dir)).To(Succeed())
+			Expect(filepath.Join(dir, "index")).To(BeADirectory())
+			Expect(filepath.Join(dir, "cache")).To(BeADirectory())
+			Expect(filepath.Join(dir, "src")).NotTo(BeADirectory())
+		})
+	})
+}
@@ -0,0 +1,146 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ProjectRustFlags returns the `build.rustflags` set in the project's `.cargo/config.toml` and the path of the file
+// that sets them. Like for Cargo, they can be a string of space separated flags or a list of flags.
+func ProjectRustFlags(srcDir string) ([]string, string, error) {
+	path, config, err := findProjectConfig(srcDir, func(config projectConfig) bool {
+		return config.Build.RustFlags != nil
+	})
+	if err != nil || path == "" {
+		return nil, "", err
+	}
+
+	switch flags := config.Build.RustFlags.(type) {
+	case string:
+		return strings.Fields(flags), path, nil
+	case []interface{}:
+		var list []string
+		for _, flag := range flags {
+			f, ok := flag.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("build.rustflags in %s must be a string or a list of strings", path)
+			}
+			list = append(list, f)
+		}
+		return list, path, nil
+	default:
+		return nil, "", fmt.Errorf("build.rustflags in %s must be a string or a list of strings", path)
+	}
+}
+
+// RustFlagConflicts returns the codegen options, like `opt-level`, that flags sets to a different value than project
+func RustFlagConflicts(project []string, flags []string) []string {
+	options := codegenOptions(project)
+
+	var conflicts []string
+	for name, value := range codegenOptions(flags) {
+		if previous, ok := options[name]; ok && previous != value {
+			conflicts = append(conflicts, name)
+		}
+	}
+	sort.Strings(conflicts)
+
+	return conflicts
+}
+
+// codegenOptions maps the names of the `-C` options in flags to their values, the last value wins like it does for
+// rustc
+func codegenOptions(flags []string) map[string]string {
+	options := make(map[string]string)
+	for i := 0; i < len(flags); i++ {
+		var option string
+		switch flag := flags[i]; {
+		case flag == "-C" || flag == "--codegen":
+			if i+1 < len(flags) {
+				i++
+				option = flags[i]
+			}
+		case strings.HasPrefix(flag, "--codegen="):
+			option = strings.TrimPrefix(flag, "--codegen=")
+		case strings.HasPrefix(flag, "-C"):
+			option = strings.TrimPrefix(flag, "-C")
+		default:
+			continue
+		}
+
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		options[parts[0]] = parts[1]
+	}
+	return options
+}
+
+// applyRustFlags adds BP_CARGO_RUSTFLAGS, and the flags needed for BP_CARGO_COVERAGE and BP_CARGO_STRIP, to RUSTFLAGS
+// in env. Cargo ignores `build.rustflags` from the project's configuration when RUSTFLAGS is set, so unless it is set
+// already, the project's flags are put first. They are kept that way, and the added flags take precedence.
+func applyRustFlags(srcDir string, env []string) ([]string, error) {
+	var flags []string
+	if value := strings.TrimSpace(os.Getenv("BP_CARGO_RUSTFLAGS")); value != "" {
+		flags = append(flags, value)
+	}
+
+	coverage, err := ParseBoolEnv("BP_CARGO_COVERAGE")
+	if err != nil {
+		return nil, err
+	}
+
+	if coverage {
+		flags = append(flags, CoverageRustFlags)
+	}
+
+	strip, err := StripMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	if strip == StripWithRustFlags {
+		flags = append(flags, StripRustFlags)
+	}
+
+	if len(flags) == 0 {
+		return env, nil
+	}
+
+	if !containsEnv(env, "RUSTFLAGS") {
+		project, _, err := ProjectRustFlags(srcDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(project) > 0 {
+			env = append(env, fmt.Sprintf("RUSTFLAGS=%s", strings.Join(project, " ")))
+		}
+	}
+
+	return appendRustFlags(env, strings.Join(flags, " ")), nil
+}
+
+// EffectiveRustFlags returns the RUSTFLAGS that cargo is run with in srcDir. It is empty when cargo only uses the
+// project's configuration.
+func EffectiveRustFlags(srcDir string) (string, error) {
+	env, err := baseEnviron()
+	if err != nil {
+		return "", err
+	}
+
+	env, err = applyRustFlags(srcDir, env)
+	if err != nil {
+		return "", err
+	}
+
+	for _, e := range env {
+		if strings.HasPrefix(e, "RUSTFLAGS=") {
+			return strings.TrimPrefix(e, "RUSTFLAGS="), nil
+		}
+	}
+	return "", nil
+}
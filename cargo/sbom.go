@@ -0,0 +1,151 @@
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// SBOMFile is the name of the file, inside the rust-sbom layer, that lists the crates in the application
+const SBOMFile = "sbom.json"
+
+// SBOMComponent is a single crate in sbom.json. Source is empty for crates in the project itself, like workspace
+// members and path dependencies. The remaining fields are only filled in when hashes are requested.
+type SBOMComponent struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+
+	DownloadURL string `json:"download_url,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	Repository  string `json:"repository,omitempty"`
+	Commit      string `json:"commit,omitempty"`
+}
+
+// CratesIODownloadURL is the pattern for the download URL of a crate on crates.io, filled in with the name, name
+// and version
+const CratesIODownloadURL = "https://static.crates.io/crates/%s/%s-%s.crate"
+
+var cratesIOSources = []string{
+	"registry+https://github.com/rust-lang/crates.io-index",
+	"sparse+https://index.crates.io/",
+}
+
+// addSourceDetails fills in where a component was downloaded from and how to verify it. Registry crates get their
+// checksum from Cargo.lock, and a download URL when they come from crates.io, since other registries publish their
+// download URL in the index. Git dependencies get the repository and the commit that was resolved.
+func addSourceDetails(component *SBOMComponent, pkg LockPackage) {
+	switch {
+	case strings.HasPrefix(pkg.Source, "git+"):
+		repository := strings.TrimPrefix(pkg.Source, "git+")
+		if i := strings.Index(repository, "#"); i >= 0 {
+			component.Commit = repository[i+1:]
+			repository = repository[:i]
+		}
+		if i := strings.Index(repository, "?"); i >= 0 {
+			repository = repository[:i]
+		}
+		component.Repository = repository
+	case strings.HasPrefix(pkg.Source, "registry+"), strings.HasPrefix(pkg.Source, "sparse+"):
+		component.SHA256 = pkg.Checksum
+		if contains(cratesIOSources, pkg.Source) {
+			component.DownloadURL = fmt.Sprintf(CratesIODownloadURL, pkg.Name, pkg.Name, pkg.Version)
+		}
+	}
+}
+
+// SBOM is the content of sbom.json
+type SBOM struct {
+	Components []SBOMComponent `json:"components"`
+}
+
+// NewSBOM lists the crates in lockfile, leaving out those whose name matches one of the exclude patterns. Patterns
+// use path.Match syntax, like `internal-*`. Every excluded crate is logged. With withHashes, each crate also lists
+// its download URL and checksum, or its repository and commit.
+func NewSBOM(lockfile Lockfile, exclude []string, withHashes bool, logger scribe.Emitter) (SBOM, error) {
+	sbom := SBOM{Components: []SBOMComponent{}}
+
+	for _, pkg := range lockfile.Packages {
+		excluded := false
+		for _, pattern := range exclude {
+			match, err := path.Match(pattern, pkg.Name)
+			if err != nil {
+				return SBOM{}, fmt.Errorf("invalid pattern %q in BP_CARGO_SBOM_EXCLUDE\n%w", pattern, err)
+			}
+			if match {
+				excluded = true
+				break
+			}
+		}
+
+		if excluded {
+			logger.Subprocess("Excluding %s %s from the SBOM", pkg.Name, pkg.Version)
+			continue
+		}
+
+		component := SBOMComponent{
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Source:  pkg.Source,
+		}
+
+		if withHashes {
+			addSourceDetails(&component, pkg)
+		}
+
+		sbom.Components = append(sbom.Components, component)
+	}
+
+	return sbom, nil
+}
+
+// ResolvedPackages lists the packages in the resolved dependency graph as "name version". Resolve cargo metadata
+// with `--filter-platform` to get the packages compiled for a single target.
+func (m metadata) ResolvedPackages() ([]string, error) {
+	if m.Resolve == nil {
+		return nil, fmt.Errorf("cargo metadata does not include a resolved dependency graph")
+	}
+
+	packages := make(map[string]metadataPackage)
+	for _, pkg := range m.Packages {
+		packages[pkg.ID] = pkg
+	}
+
+	var resolved []string
+	for _, node := range m.Resolve.Nodes {
+		pkg, ok := packages[node.ID]
+		if !ok {
+			return nil, fmt.Errorf("cargo metadata does not include resolved package %s", node.ID)
+		}
+		resolved = append(resolved, fmt.Sprintf("%s %s", pkg.Name, pkg.Version))
+	}
+
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// WriteSBOM writes sbom as sbom.json into dir
+func WriteSBOM(dir string, sbom SBOM) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	content, err := json.MarshalIndent(sbom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode SBOM\n%w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, SBOMFile), content, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write SBOM\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package cargo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSBOM(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		lockfile cargo.Lockfile
+		logBuf   bytes.Buffer
+		logger   scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		lockfile, err = cargo.ParseLockfile(filepath.Join("testdata", "lockfile_internal.toml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it("lists every crate in the lockfile", func() {
+		sbom, err := cargo.NewSBOM(lockfile, nil, false, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sbom.Components).To(HaveLen(3))
+		Expect(sbom.Components[1]).To(Equal(cargo.SBOMComponent{
+			Name:    "internal-tools",
+			Version: "0.3.0",
+			Source:  "registry+https://crates.example.com/index",
+		}))
+	})
+
+	it("leaves out and logs crates matching an exclude pattern", func() {
+		sbom, err := cargo.NewSBOM(lockfile, []string{"internal-*", "app"}, false, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sbom.Components).To(Equal([]cargo.SBOMComponent{
+			{Name: "serde", Version: "1.0.130", Source: "registry+https://github.com/rust-lang/crates.io-index"},
+		}))
+		Expect(logBuf.String()).To(ContainSubstring("Excluding internal-tools 0.3.0 from the SBOM"))
+		Expect(logBuf.String()).To(ContainSubstring("Excluding app 0.1.0 from the SBOM"))
+	})
+
+	it("rejects invalid patterns", func() {
+		_, err := cargo.NewSBOM(lockfile, []string{"internal-["}, false, logger)
+		Expect(err).To(MatchError(ContainSubstring(`invalid pattern "internal-[" in BP_CARGO_SBOM_EXCLUDE`)))
+	})
+
+	it("leaves out crates that are not compiled for the target", func() {
+		lockfile, err := cargo.ParseLockfile(filepath.Join("testdata", "target_deps", "Cargo.lock"))
+		Expect(err).NotTo(HaveOccurred())
+
+		selected, omitted := lockfile.Select([]string{"app 0.1.0", "libc 0.2.101", "serde 1.0.130"})
+		Expect(omitted).To(HaveLen(3))
+		Expect(omitted[0].Name).To(Equal("winapi"))
+
+		sbom, err := cargo.NewSBOM(selected, nil, false, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sbom.Components).To(Equal([]cargo.SBOMComponent{
+			{Name: "app", Version: "0.1.0"},
+			{Name: "libc", Version: "0.2.101", Source: "registry+https://github.com/rust-lang/crates.io-index"},
+			{Name: "serde", Version: "1.0.130", Source: "registry+https://github.com/rust-lang/crates.io-index"},
+		}))
+	})
+
+	it("adds download URLs and checksums when hashes are requested", func() {
+		lockfile, err := cargo.ParseLockfile(filepath.Join("testdata", "lockfile_sources.toml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		sbom, err := cargo.NewSBOM(lockfile, nil, true, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sbom.Components).To(Equal([]cargo.SBOMComponent{
+			{Name: "app", Version: "0.1.0"},
+			{
+				Name:    "internal-tools",
+				Version: "0.3.0",
+				Source:  "registry+https://crates.example.com/index",
+				SHA256:  "9a2e0b6c1f1d7e4b8c3a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b2a3f4e5d6c",
+			},
+			{
+				Name:        "serde",
+				Version:     "1.0.130",
+				Source:      "registry+https://github.com/rust-lang/crates.io-index",
+				DownloadURL: "https://static.crates.io/crates/serde/serde-1.0.130.crate",
+				SHA256:      "f12d06de37cf59146fbdecab66aa99f9fe4f78722e3607577a5375d66bd0c913",
+			},
+			{
+				Name:        "tokio",
+				Version:     "1.12.0",
+				Source:      "sparse+https://index.crates.io/",
+				DownloadURL: "https://static.crates.io/crates/tokio/tokio-1.12.0.crate",
+				SHA256:      "c2c2416fdedca8443ae44b4527de1ea633af61d8f7169ffa6e72c5b53d24efcc",
+			},
+			{
+				Name:       "tracing",
+				Version:    "0.2.0",
+				Source:     "git+https://github.com/tokio-rs/tracing?branch=master#2f0f9e5a6b0a3a4b1f7c8d9e0a1b2c3d4e5f6a7b",
+				Repository: "https://github.com/tokio-rs/tracing",
+				Commit:     "2f0f9e5a6b0a3a4b1f7c8d9e0a1b2c3d4e5f6a7b",
+			},
+		}))
+	})
+
+	it("leaves hashes out of sbom.json by default", func() {
+		lockfile, err := cargo.ParseLockfile(filepath.Join("testdata", "lockfile_sources.toml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		sbom, err := cargo.NewSBOM(lockfile, nil, false, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := json.Marshal(sbom)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).NotTo(ContainSubstring("sha256"))
+		Expect(string(content)).NotTo(ContainSubstring("commit"))
+	})
+}
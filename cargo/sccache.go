@@ -0,0 +1,64 @@
+package cargo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// SccacheLayerName is the cache layer that sccache keeps its compilation cache in when BP_CARGO_SCCACHE is set
+const SccacheLayerName = "rust-sccache"
+
+// SccacheEnabled is true when BP_CARGO_SCCACHE is set and sccache can be found on the PATH
+func SccacheEnabled() (bool, error) {
+	enabled, err := ParseBoolEnv("BP_CARGO_SCCACHE")
+	if err != nil || !enabled {
+		return false, err
+	}
+
+	_, err = exec.LookPath("sccache")
+	return err == nil, nil
+}
+
+// sccacheDir is the rust-sccache layer, which sits next to the work layer in the layers directory
+func sccacheDir(workLayer packit.Layer) string {
+	return filepath.Join(filepath.Dir(workLayer.Path), SccacheLayerName)
+}
+
+// SccacheVersion returns the output of `sccache --version`, like `sccache 0.2.15`
+func SccacheVersion() (string, error) {
+	output := bytes.Buffer{}
+	err := pexec.NewExecutable("sccache").Execute(pexec.Execution{
+		Stdout: &output,
+		Stderr: &output,
+		Args:   []string{"--version"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("sccache --version failed: %w", err)
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
+
+// StopSccache stops the sccache server that cargo started, which prints the cache statistics of the build
+func StopSccache(logger scribe.Emitter) error {
+	logger.Process("sccache statistics")
+	err := pexec.NewExecutable("sccache").Execute(pexec.Execution{
+		Stdout: scribe.NewWriter(os.Stdout, scribe.WithIndent(2)),
+		Stderr: scribe.NewWriter(os.Stderr, scribe.WithIndent(2)),
+		Args:   []string{"--stop-server"},
+	})
+	logger.Break()
+	if err != nil {
+		return fmt.Errorf("sccache --stop-server failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package cargo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vcsDirs are the directories of version control systems, which don't affect what cargo builds
+var vcsDirs = []string{".git", ".hg", ".svn"}
+
+// SourceChecksum computes a SHA256 over the names and contents of the files in workingDir that cargo builds from,
+// like Cargo.toml, Cargo.lock and the src tree of every package. The target directory and VCS folders are skipped.
+// Path dependencies outside of workingDir, like `../shared`, are built from too, so their files are included.
+func SourceChecksum(workingDir string) (string, error) {
+	hash := sha256.New()
+	err := checksumTree(hash, workingDir, workingDir, "")
+	if err != nil {
+		return "", err
+	}
+
+	external, err := ExternalPathDependencies(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.Abs(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range external {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return "", err
+		}
+
+		err = checksumTree(hash, dir, root, rel)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checksumTree adds the files in root to hash, naming them relative to root behind prefix. workingDir is skipped
+// when root contains it, as it is checksummed on its own.
+func checksumTree(hash hash.Hash, root string, workingDir string, prefix string) error {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.Join(prefix, rel)
+
+		if info.IsDir() {
+			if path != root && path == workingDir {
+				return filepath.SkipDir
+			}
+			if path == filepath.Join(root, "target") || contains(vcsDirs, info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "%s\x00->%s\x00", rel, target)
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		// the executable bit matters for scripts that build scripts run
+		fmt.Fprintf(hash, "%s\x00%o\x00", rel, info.Mode().Perm()&0111)
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(hash, f)
+		f.Close()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to checksum %s\n%w", root, err)
+	}
+
+	return nil
+}
+
+// ExternalPathDependencies lists the directories of the path dependencies that are outside of srcDir. The
+// `[dependencies]`, `[build-dependencies]` and `[workspace.dependencies]` of the project and its workspace members are
+// followed, as are the path dependencies of the packages that are found.
+func ExternalPathDependencies(srcDir string) ([]string, error) {
+	root, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rootManifest, err := ParseManifest(filepath.Join(root, "Cargo.toml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queue := []string{root}
+	if rootManifest.Workspace != nil {
+		members, err := rootManifest.Workspace.MemberDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, members...)
+	}
+
+	seen := map[string]bool{}
+	for _, dir := range queue {
+		seen[dir] = true
+	}
+
+	var external []string
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		manifest, err := ParseManifest(filepath.Join(dir, "Cargo.toml"))
+		if errors.Is(err, os.ErrNotExist) {
+			// cargo reports a path dependency without a manifest
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tables := []map[string]interface{}{manifest.Dependencies, manifest.BuildDependencies}
+		if manifest.Workspace != nil {
+			tables = append(tables, manifest.Workspace.Dependencies)
+		}
+
+		for _, table := range tables {
+			for _, dep := range table {
+				spec, ok := dep.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				path, _ := spec["path"].(string)
+				if path == "" {
+					continue
+				}
+
+				depDir := filepath.Join(dir, filepath.FromSlash(path))
+				if seen[depDir] {
+					continue
+				}
+				seen[depDir] = true
+				queue = append(queue, depDir)
+
+				if rel, err := filepath.Rel(root, depDir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+					external = append(external, depDir)
+				}
+			}
+		}
+	}
+
+	sort.Strings(external)
+	return external, nil
+}
+
+// ignoredConfigVariables are the BP_CARGO_* variables that don't change the binaries, they only affect the
+// logs, the build's priority or the layers that are written next to the binaries, which happens when they are reused too
+var ignoredConfigVariables = []string{
+	"BP_CARGO_COLOR",
+	"BP_CARGO_DRY_RUN",
+	"BP_CARGO_EMIT_DEPGRAPH",
+	"BP_CARGO_EMIT_OTEL",
+	"BP_CARGO_EMIT_PROCESSES",
+	"BP_CARGO_EMIT_SBOM",
+	"BP_CARGO_INCLUDE_FILES",
+	"BP_CARGO_IONICE",
+	"BP_CARGO_NICE",
+	"BP_CARGO_SBOM_EXCLUDE",
+	"BP_CARGO_SBOM_WITH_HASHES",
+	"BP_CARGO_STRICT_CONFIG",
+	"BP_CARGO_TIMESTAMP_FORMAT",
+	"BP_CARGO_VERBOSE",
+}
+
+// ConfigChecksum computes a SHA256 over the values of the BP_CARGO_* variables that are set and the settings that
+// are resolved from them and the project in srcDir: the build target, the RUSTFLAGS cargo is run with and the pinned
+// toolchain channel. A change in configuration is noticed even when the sources are unchanged. Variables that don't
+// change the binaries are left out.
+func ConfigChecksum(srcDir string) (string, error) {
+	hash := sha256.New()
+	for _, name := range KnownEnvironmentVariables {
+		if contains(ignoredConfigVariables, name) {
+			continue
+		}
+
+		if value, ok := os.LookupEnv(name); ok {
+			fmt.Fprintf(hash, "%s=%s\x00", name, value)
+		}
+	}
+
+	target, err := BuildTarget(srcDir)
+	if err != nil {
+		return "", err
+	}
+
+	rustFlags, err := EffectiveRustFlags(srcDir)
+	if err != nil {
+		return "", err
+	}
+
+	toolchain, err := LoadToolchain(srcDir)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(hash, "target=%s\x00rustflags=%s\x00toolchain=%s\x00", target, rustFlags, toolchain.Channel)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,145 @@
+package cargo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSourceChecksum(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		checksum   string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "src"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), []byte("version = 3\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() {}\n"), 0644)).To(Succeed())
+
+		checksum, err = cargo.SourceChecksum(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(HaveLen(64))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("changes when a source file changes", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "main.rs"), []byte("fn main() { println!(\"hi\"); }\n"), 0644)).To(Succeed())
+
+		Expect(cargo.SourceChecksum(workingDir)).NotTo(Equal(checksum))
+	})
+
+	it("changes when Cargo.lock changes", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), []byte("version = 4\n"), 0644)).To(Succeed())
+
+		Expect(cargo.SourceChecksum(workingDir)).NotTo(Equal(checksum))
+	})
+
+	it("changes when a file is added", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "src", "lib.rs"), []byte{}, 0644)).To(Succeed())
+
+		Expect(cargo.SourceChecksum(workingDir)).NotTo(Equal(checksum))
+	})
+
+	it("ignores the target directory and VCS folders", func() {
+		for _, dir := range []string{"target/release", ".git/objects", ".hg", ".svn"} {
+			Expect(os.MkdirAll(filepath.Join(workingDir, dir), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, dir, "file"), []byte("ignored"), 0644)).To(Succeed())
+		}
+
+		Expect(cargo.SourceChecksum(workingDir)).To(Equal(checksum))
+	})
+
+	context("when a path dependency is outside of the project", func() {
+		var shared string
+
+		it.Before(func() {
+			shared = filepath.Join(filepath.Dir(workingDir), filepath.Base(workingDir)+"-shared")
+			Expect(os.MkdirAll(filepath.Join(shared, "src"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(shared, "Cargo.toml"), []byte("[package]\nname = \"shared\"\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(shared, "src", "lib.rs"), []byte{}, 0644)).To(Succeed())
+
+			manifest := fmt.Sprintf("[package]\nname = \"app\"\n[dependencies]\nshared = { path = \"../%s\" }\n", filepath.Base(shared))
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.toml"), []byte(manifest), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(shared)).To(Succeed())
+		})
+
+		it("lists it as an external path dependency", func() {
+			Expect(cargo.ExternalPathDependencies(workingDir)).To(Equal([]string{shared}))
+		})
+
+		it("changes when one of its files changes", func() {
+			before, err := cargo.SourceChecksum(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(shared, "src", "lib.rs"), []byte("pub fn f() {}\n"), 0644)).To(Succeed())
+			Expect(cargo.SourceChecksum(workingDir)).NotTo(Equal(before))
+		})
+	})
+
+	context("ConfigChecksum", func() {
+		var before string
+
+		it.Before(func() {
+			var err error
+			before, err = cargo.ConfigChecksum(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_FEATURES")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_VERBOSE")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_COLOR")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_EMIT_SBOM")).To(Succeed())
+			Expect(os.Unsetenv("CARGO_BUILD_TARGET")).To(Succeed())
+			Expect(os.Unsetenv("RUSTFLAGS")).To(Succeed())
+		})
+
+		it("changes when a BP_CARGO_* variable changes", func() {
+			Expect(os.Setenv("BP_CARGO_FEATURES", "tls")).To(Succeed())
+			Expect(cargo.ConfigChecksum(workingDir)).NotTo(Equal(before))
+		})
+
+		it("does not change for settings that don't affect the binaries", func() {
+			Expect(os.Setenv("BP_CARGO_VERBOSE", "true")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_COLOR", "always")).To(Succeed())
+			Expect(os.Setenv("BP_CARGO_EMIT_SBOM", "true")).To(Succeed())
+			Expect(cargo.ConfigChecksum(workingDir)).To(Equal(before))
+		})
+
+		it("changes when the target is set with CARGO_BUILD_TARGET", func() {
+			Expect(os.Setenv("CARGO_BUILD_TARGET", "x86_64-unknown-linux-musl")).To(Succeed())
+			Expect(cargo.ConfigChecksum(workingDir)).NotTo(Equal(before))
+		})
+
+		it("changes when RUSTFLAGS changes", func() {
+			Expect(os.Setenv("RUSTFLAGS", "-C target-cpu=native")).To(Succeed())
+			Expect(cargo.ConfigChecksum(workingDir)).NotTo(Equal(before))
+		})
+
+		it("changes when the pinned toolchain changes", func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain"), []byte("nightly\n"), 0644)).To(Succeed())
+			Expect(cargo.ConfigChecksum(workingDir)).NotTo(Equal(before))
+		})
+	})
+}
@@ -0,0 +1,127 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// NonReproducibleSource is a locked dependency whose contents may change between builds without Cargo.lock changing
+type NonReproducibleSource struct {
+	Name    string
+	Version string
+	Source  string
+}
+
+// LocalPackages returns the names of the packages that are part of the project in srcDir, the root package and the
+// workspace members listed in its Cargo.toml
+func LocalPackages(srcDir string) (map[string]bool, error) {
+	root, err := ParseManifest(filepath.Join(srcDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	if root.Package != nil {
+		names[root.Package.Name] = true
+	}
+
+	if root.Workspace == nil {
+		return names, nil
+	}
+
+	for _, pattern := range root.Workspace.Members {
+		dirs, err := filepath.Glob(filepath.Join(srcDir, filepath.Clean(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace member %q\n%w", pattern, err)
+		}
+
+		for _, dir := range dirs {
+			if root.Workspace.Excludes(srcDir, dir) {
+				continue
+			}
+
+			manifest, err := ParseManifest(filepath.Join(dir, "Cargo.toml"))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+
+			if manifest.Package != nil {
+				names[manifest.Package.Name] = true
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// NonReproducibleSources lists the locked dependencies that come from a git branch or tag, rather than a pinned
+// `rev`, or from a path outside the local packages. The commit a branch or tag points to can move, and a path
+// dependency is whatever happens to be on disk, so neither is fixed by Cargo.lock.
+func (l Lockfile) NonReproducibleSources(local map[string]bool) []NonReproducibleSource {
+	var sources []NonReproducibleSource
+	for _, pkg := range l.Packages {
+		switch {
+		case pkg.Source == "":
+			if local[pkg.Name] {
+				continue
+			}
+			sources = append(sources, NonReproducibleSource{Name: pkg.Name, Version: pkg.Version, Source: "path"})
+		case strings.HasPrefix(pkg.Source, "git+"):
+			u, err := url.Parse(strings.TrimPrefix(pkg.Source, "git+"))
+			if err == nil && u.Query().Get("rev") != "" {
+				continue
+			}
+			sources = append(sources, NonReproducibleSource{Name: pkg.Name, Version: pkg.Version, Source: pkg.Source})
+		}
+	}
+	return sources
+}
+
+// CheckReproducibleSources warns about each non-reproducible dependency in the project's Cargo.lock, see
+// NonReproducibleSources. When require is set, they fail the build instead.
+func CheckReproducibleSources(srcDir string, require bool, logger scribe.Emitter) error {
+	lockfile, err := ParseLockfile(filepath.Join(srcDir, "Cargo.lock"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	local, err := LocalPackages(srcDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	sources := lockfile.NonReproducibleSources(local)
+	if len(sources) == 0 {
+		return nil
+	}
+
+	var names []string
+	for _, source := range sources {
+		names = append(names, fmt.Sprintf("%s %s", source.Name, source.Version))
+		if !require {
+			logger.Subprocess("WARNING: %s %s comes from %s, which is not reproducible across builds", source.Name, source.Version, source.Source)
+		}
+	}
+
+	if require {
+		return fmt.Errorf("BP_CARGO_REQUIRE_REPRODUCIBLE_SOURCES is set, but these dependencies come from a git branch or tag or a path [%s], pin git dependencies with `rev`", strings.Join(names, ", "))
+	}
+
+	logger.Subprocess("WARNING: pin git dependencies with `rev` and publish path dependencies to a registry to make builds reproducible")
+	return nil
+}
@@ -0,0 +1,73 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSources(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir = filepath.Join("testdata", "nonreproducible")
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it("finds the root package and workspace members", func() {
+		Expect(cargo.LocalPackages(srcDir)).To(Equal(map[string]bool{"app": true, "api": true, "worker": true}))
+	})
+
+	it("leaves out directories listed in the workspace exclude", func() {
+		Expect(cargo.LocalPackages("testdata/workspace_exclude")).To(Equal(map[string]bool{"app": true}))
+	})
+
+	it("lists git branch and tag sources and path dependencies", func() {
+		lockfile, err := cargo.ParseLockfile(filepath.Join(srcDir, "Cargo.lock"))
+		Expect(err).NotTo(HaveOccurred())
+
+		local, err := cargo.LocalPackages(srcDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lockfile.NonReproducibleSources(local)).To(Equal([]cargo.NonReproducibleSource{
+			{Name: "hyper", Version: "0.14.10", Source: "git+https://github.com/hyperium/hyper?tag=v0.14.10#5b3e2b3b0f5c1f5d2f8c1c1c4b1c8e5c2a0a5f1e"},
+			{Name: "shared", Version: "0.2.0", Source: "path"},
+			{Name: "tracing", Version: "0.2.0", Source: "git+https://github.com/tokio-rs/tracing?branch=master#0a2f6a1c3b4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f"},
+		}))
+	})
+
+	it("warns about each non-reproducible dependency", func() {
+		Expect(cargo.CheckReproducibleSources(srcDir, false, logger)).To(Succeed())
+		Expect(logBuf.String()).To(ContainSubstring("WARNING: tracing 0.2.0 comes from git+https://github.com/tokio-rs/tracing?branch=master"))
+		Expect(logBuf.String()).To(ContainSubstring("WARNING: shared 0.2.0 comes from path, which is not reproducible across builds"))
+		Expect(logBuf.String()).NotTo(ContainSubstring("serde"))
+		Expect(logBuf.String()).NotTo(ContainSubstring("worker"))
+	})
+
+	it("fails when reproducible sources are required", func() {
+		err := cargo.CheckReproducibleSources(srcDir, true, logger)
+		Expect(err).To(MatchError("BP_CARGO_REQUIRE_REPRODUCIBLE_SOURCES is set, but these dependencies come from a git branch or tag or a path [hyper 0.14.10, shared 0.2.0, tracing 0.2.0], pin git dependencies with `rev`"))
+	})
+
+	it("does nothing without a Cargo.lock", func() {
+		dir, err := ioutil.TempDir("", "sources")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Expect(cargo.CheckReproducibleSources(dir, true, logger)).To(Succeed())
+	})
+}
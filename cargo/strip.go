@@ -0,0 +1,86 @@
+package cargo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+const (
+	// StripWithTool runs `strip` on the installed binaries
+	StripWithTool = "strip"
+
+	// StripWithRustFlags has rustc leave the symbols out while linking, for stacks without `strip`
+	StripWithRustFlags = "rustflags"
+)
+
+// StripRustFlags are the flags added to RUSTFLAGS when binaries are stripped by rustc
+const StripRustFlags = "-C strip=symbols"
+
+// StripMethod returns how debug symbols are removed from the binaries when BP_CARGO_STRIP is set, with `strip`
+// when it can be found on the PATH and by rustc otherwise. It is empty when BP_CARGO_STRIP is not set.
+func StripMethod() (string, error) {
+	enabled, err := ParseBoolEnv("BP_CARGO_STRIP")
+	if err != nil || !enabled {
+		return "", err
+	}
+
+	if _, err := exec.LookPath("strip"); err != nil {
+		return StripWithRustFlags, nil
+	}
+	return StripWithTool, nil
+}
+
+// StripBinaries runs `strip` on every binary in binDir and logs the size of each before and after. Binaries that
+// strip doesn't understand, like scripts, are skipped.
+func StripBinaries(binDir string, logger scribe.Emitter) error {
+	files, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	strip := pexec.NewExecutable("strip")
+
+	logger.Process("Stripping debug symbols from binaries")
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+
+		binPath := filepath.Join(binDir, file.Name())
+		before, err := os.Stat(binPath)
+		if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", binPath, err)
+		}
+
+		output := bytes.Buffer{}
+		err = strip.Execute(pexec.Execution{
+			Stdout: &output,
+			Stderr: &output,
+			Args:   []string{binPath},
+		})
+		if err != nil {
+			logger.Subprocess("Skipping %s: strip failed: %s %s", file.Name(), err, strings.TrimSpace(output.String()))
+			continue
+		}
+
+		after, err := os.Stat(binPath)
+		if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", binPath, err)
+		}
+
+		logger.Subprocess("%s: %d bytes -> %d bytes, saved %d bytes", file.Name(), before.Size(), after.Size(), before.Size()-after.Size())
+	}
+	logger.Break()
+
+	return nil
+}
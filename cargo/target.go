@@ -0,0 +1,50 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// TargetLayerName is the cache layer that cargo's target directory is kept in between builds
+const TargetLayerName = "rust-target"
+
+// targetDir is the rust-target layer, which sits next to the work layer in the layers directory
+func targetDir(workLayer packit.Layer) string {
+	return filepath.Join(filepath.Dir(workLayer.Path), TargetLayerName)
+}
+
+// PrepareTargetLayer makes targetLayer a cache layer, which is never part of the launch image. Objects built by a
+// different toolchain can't be linked with the new ones, so the layer is emptied when rustVersion differs from the
+// version that the layer was built with. When rustVersion isn't known, the layer is kept as it is.
+func PrepareTargetLayer(targetLayer packit.Layer, rustVersion string, logger scribe.Emitter) (packit.Layer, error) {
+	if previous, _ := targetLayer.Metadata["rust_version"].(string); previous != "" && rustVersion != "" && previous != rustVersion {
+		logger.Subprocess("Rust toolchain has changed from %s to %s since the last build, removing the %s layer", previous, rustVersion, TargetLayerName)
+
+		var err error
+		targetLayer, err = targetLayer.Reset()
+		if err != nil {
+			return packit.Layer{}, err
+		}
+	}
+
+	targetLayer.Build = false
+	targetLayer.Launch = false
+	targetLayer.Cache = true
+
+	err := os.MkdirAll(targetLayer.Path, 0755)
+	if err != nil {
+		return packit.Layer{}, fmt.Errorf("unable to create %s\n%w", targetLayer.Path, err)
+	}
+
+	if rustVersion != "" {
+		targetLayer.Metadata = map[string]interface{}{
+			"rust_version": rustVersion,
+		}
+	}
+
+	return targetLayer, nil
+}
@@ -0,0 +1,76 @@
+package cargo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// PostBuildConcurrency is the number of post-build tasks that may run at the same time
+const PostBuildConcurrency = 4
+
+// Task is an independent unit of work that runs after the build. The output a task logs is buffered and written once
+// every task has finished, so the output of tasks running at the same time is not interleaved. Tasks only work with
+// files, anything that runs an executable through the Runner is done before the tasks are started.
+type Task func(logger scribe.Emitter) error
+
+// TaskErrors is returned by RunTasks when more than one task fails
+type TaskErrors []error
+
+func (t TaskErrors) Error() string {
+	msgs := make([]string, len(t))
+	for i, err := range t {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d post-build tasks failed\n%s", len(t), strings.Join(msgs, "\n"))
+}
+
+// RunTasks runs the tasks with at most limit running at once and waits for all of them to finish, even if one
+// fails. Output is logged in the order the tasks were given. A single failure is returned as is, multiple
+// failures are returned as TaskErrors.
+func RunTasks(logger scribe.Emitter, limit int, tasks ...Task) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	outputs := make([]bytes.Buffer, len(tasks))
+	errs := make([]error, len(tasks))
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task Task) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			errs[i] = task(scribe.NewEmitter(&outputs[i]))
+		}(i, task)
+	}
+	wg.Wait()
+
+	var failed TaskErrors
+	for i := range tasks {
+		if outputs[i].Len() > 0 {
+			// the buffered output is already indented, so it's written without adding any more
+			logger.Title("%s", strings.TrimSuffix(outputs[i].String(), "\n"))
+		}
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	default:
+		return failed
+	}
+}
@@ -0,0 +1,116 @@
+package cargo_test
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTasks(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it("runs the tasks concurrently", func() {
+		first := make(chan struct{})
+		second := make(chan struct{})
+
+		// each task waits for the other to start, which deadlocks if they run one after the other
+		err := cargo.RunTasks(logger, 2,
+			func(logger scribe.Emitter) error {
+				close(first)
+				select {
+				case <-second:
+					return nil
+				case <-time.After(5 * time.Second):
+					return errors.New("second task did not start")
+				}
+			},
+			func(logger scribe.Emitter) error {
+				close(second)
+				select {
+				case <-first:
+					return nil
+				case <-time.After(5 * time.Second):
+					return errors.New("first task did not start")
+				}
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it("runs no more than limit tasks at once", func() {
+		var running, most int32
+		task := func(logger scribe.Emitter) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		}
+
+		Expect(cargo.RunTasks(logger, 2, task, task, task, task, task)).To(Succeed())
+		Expect(atomic.LoadInt32(&most)).To(BeNumerically("<=", 2))
+	})
+
+	it("logs the output of each task in order", func() {
+		err := cargo.RunTasks(logger, 2,
+			func(logger scribe.Emitter) error {
+				time.Sleep(10 * time.Millisecond)
+				logger.Subprocess("first")
+				return nil
+			},
+			func(logger scribe.Emitter) error {
+				logger.Subprocess("second")
+				return nil
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logBuf.String()).To(Equal("    first\n    second\n"))
+	})
+
+	it("returns a single failure as is", func() {
+		expected := errors.New("expected")
+		err := cargo.RunTasks(logger, 2,
+			func(logger scribe.Emitter) error { return nil },
+			func(logger scribe.Emitter) error { return expected },
+		)
+		Expect(err).To(Equal(expected))
+	})
+
+	it("waits for every task and aggregates the failures", func() {
+		var finished int32
+		err := cargo.RunTasks(logger, 1,
+			func(logger scribe.Emitter) error { return errors.New("first failed") },
+			func(logger scribe.Emitter) error {
+				atomic.AddInt32(&finished, 1)
+				return nil
+			},
+			func(logger scribe.Emitter) error { return errors.New("third failed") },
+		)
+		Expect(err).To(MatchError("2 post-build tasks failed\nfirst failed\nthird failed"))
+		Expect(err).To(BeAssignableToTypeOf(cargo.TaskErrors{}))
+		Expect(atomic.LoadInt32(&finished)).To(Equal(int32(1)))
+	})
+}
@@ -0,0 +1,99 @@
+package cargo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/paketo-buildpacks/packit"
+)
+
+// OTelAttributesFile is the name of the file, inside the rust-otel layer, that holds the build attributes
+const OTelAttributesFile = "attributes.json"
+
+// OTelAttributes are the build attributes, keyed by OpenTelemetry style attribute names
+type OTelAttributes map[string]interface{}
+
+// rustcVersionPattern matches the version in the `rustc --version` part of the toolchain version
+var rustcVersionPattern = regexp.MustCompile(`rustc (\S+)`)
+
+// RustcVersion picks the version of rustc, like `1.56.0`, out of the toolchain version that Runner.Version reports.
+// It is empty when the toolchain version could not be read.
+func RustcVersion(rustVersion string) string {
+	if match := rustcVersionPattern.FindStringSubmatch(rustVersion); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// CollectOTelAttributes gathers the attributes describing a completed build. rustVersion is the version of the
+// toolchain that ran the build, as reported by Runner.Version.
+func CollectOTelAttributes(context packit.BuildContext, duration time.Duration, cacheHit bool, rustVersion string, binDir string) (OTelAttributes, error) {
+	attributes := OTelAttributes{
+		"cnb.buildpack.id":             context.BuildpackInfo.ID,
+		"cnb.buildpack.version":        context.BuildpackInfo.Version,
+		"rust.cargo.build.duration_ms": duration.Milliseconds(),
+		"rust.cargo.cache.hit":         cacheHit,
+	}
+
+	projectDir, err := ProjectDir(context.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes["rust.toolchain.version"] = RustcVersion(rustVersion)
+
+	lockfile, err := ParseLockfile(filepath.Join(projectDir, "Cargo.lock"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	attributes["rust.cargo.dependency.count"] = len(lockfile.Packages)
+
+	// reused binaries stay in the previous image, there is nothing on disk to describe
+	if _, err := os.Stat(binDir); errors.Is(err, os.ErrNotExist) {
+		return attributes, nil
+	}
+
+	binaries, err := ListBinaries(binDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalSize int64
+	for _, binary := range binaries {
+		info, err := os.Stat(filepath.Join(binDir, binary))
+		if err != nil {
+			return nil, fmt.Errorf("unable to stat %s\n%w", binary, err)
+		}
+		attributes[fmt.Sprintf("rust.cargo.binary.%s.size_bytes", binary)] = info.Size()
+		totalSize += info.Size()
+	}
+	attributes["rust.cargo.binary.count"] = len(binaries)
+	attributes["rust.cargo.binary.total_size_bytes"] = totalSize
+
+	return attributes, nil
+}
+
+// WriteOTelAttributes writes the attributes as JSON into the given layer
+func WriteOTelAttributes(layer packit.Layer, attributes OTelAttributes) error {
+	err := os.MkdirAll(layer.Path, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create directory\n%w", err)
+	}
+
+	data, err := json.MarshalIndent(attributes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode attributes\n%w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(layer.Path, OTelAttributesFile), data, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write attributes\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package cargo_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTelemetry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		binDir     string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		binDir = filepath.Join(workingDir, "bin")
+		Expect(os.MkdirAll(binDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(binDir, "app"), []byte("some-app"), 0755)).To(Succeed())
+
+		lockfile, err := ioutil.ReadFile("testdata/lockfile.toml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "Cargo.lock"), lockfile, 0644)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("collects the build attributes", func() {
+		attributes, err := cargo.CollectOTelAttributes(packit.BuildContext{
+			WorkingDir:    workingDir,
+			BuildpackInfo: packit.BuildpackInfo{ID: "some-id", Version: "1.2.3"},
+		}, 1500*time.Millisecond, true, "cargo 1.56.0 (4ed5d137b 2021-10-04), rustc 1.56.0 (09c42c458 2021-10-18)", binDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attributes).To(Equal(cargo.OTelAttributes{
+			"cnb.buildpack.id":                   "some-id",
+			"cnb.buildpack.version":              "1.2.3",
+			"rust.cargo.build.duration_ms":       int64(1500),
+			"rust.cargo.cache.hit":               true,
+			"rust.toolchain.version":             "1.56.0",
+			"rust.cargo.dependency.count":        3,
+			"rust.cargo.binary.app.size_bytes":   int64(8),
+			"rust.cargo.binary.count":            1,
+			"rust.cargo.binary.total_size_bytes": int64(8),
+		}))
+	})
+
+	it("leaves the toolchain version empty when it could not be read", func() {
+		attributes, err := cargo.CollectOTelAttributes(packit.BuildContext{WorkingDir: workingDir}, time.Second, false, "", binDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attributes).To(HaveKeyWithValue("rust.toolchain.version", ""))
+	})
+
+	it("leaves out the binaries when they are not on disk", func() {
+		Expect(os.RemoveAll(binDir)).To(Succeed())
+
+		attributes, err := cargo.CollectOTelAttributes(packit.BuildContext{WorkingDir: workingDir}, time.Second, true, "", binDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attributes).NotTo(HaveKey("rust.cargo.binary.count"))
+		Expect(attributes).To(HaveKeyWithValue("rust.cargo.dependency.count", 3))
+	})
+
+	it("writes the attributes as JSON", func() {
+		layer := packit.Layer{Name: "rust-otel", Path: filepath.Join(workingDir, "rust-otel")}
+		Expect(cargo.WriteOTelAttributes(layer, cargo.OTelAttributes{"rust.cargo.cache.hit": false})).To(Succeed())
+
+		data, err := ioutil.ReadFile(filepath.Join(layer.Path, cargo.OTelAttributesFile))
+		Expect(err).ToNot(HaveOccurred())
+
+		var attributes map[string]interface{}
+		Expect(json.Unmarshal(data, &attributes)).To(Succeed())
+		Expect(attributes).To(Equal(map[string]interface{}{"rust.cargo.cache.hit": false}))
+	})
+}
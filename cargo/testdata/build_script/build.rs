@@ -0,0 +1,12 @@
+use std::env;
+use std::fs;
+use std::path::Path;
+
+fn main() {
+    println!("cargo:rerun-if-changed=data/config.txt");
+    println!("cargo:rerun-if-env-changed=APP_CONFIG_PROFILE");
+
+    let config = fs::read_to_string("data/config.txt").unwrap();
+    let out_dir = env::var("OUT_DIR").unwrap();
+    fs::write(Path::new(&out_dir).join("config.rs"), format!("pub const CONFIG: &str = {:?};", config)).unwrap();
+}
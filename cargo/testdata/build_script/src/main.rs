@@ -0,0 +1,5 @@
+include!(concat!(env!("OUT_DIR"), "/config.rs"));
+
+fn main() {
+    println!("{}", CONFIG);
+}
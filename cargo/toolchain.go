@@ -0,0 +1,127 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ToolchainFiles are the files rustup reads to pin a toolchain, in the order the buildpack looks for them
+var ToolchainFiles = []string{"rust-toolchain.toml", "rust-toolchain"}
+
+// Toolchain is the `[toolchain]` table of a rust-toolchain file
+type Toolchain struct {
+	Channel    string
+	Components []string
+	Targets    []string
+}
+
+// ParseToolchainFile reads a `rust-toolchain.toml` or legacy `rust-toolchain` file. A legacy file may hold just the
+// name of a channel instead of TOML.
+func ParseToolchainFile(path string) (Toolchain, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Toolchain{}, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	if filepath.Base(path) != "rust-toolchain.toml" {
+		if channel := strings.TrimSpace(string(contents)); channel != "" && !strings.ContainsAny(channel, "[=\n") {
+			return Toolchain{Channel: channel}, nil
+		}
+	}
+
+	var raw map[string]interface{}
+	err = toml.Unmarshal(contents, &raw)
+	if err != nil {
+		return Toolchain{}, fmt.Errorf("unable to parse %s\n%w", path, err)
+	}
+
+	table, ok := raw["toolchain"]
+	if !ok {
+		return Toolchain{}, nil
+	}
+
+	values, ok := table.(map[string]interface{})
+	if !ok {
+		return Toolchain{}, fmt.Errorf("invalid value for toolchain in %s, must be a table", path)
+	}
+
+	var toolchain Toolchain
+	if value, ok := values["channel"]; ok {
+		toolchain.Channel, ok = value.(string)
+		if !ok {
+			return Toolchain{}, fmt.Errorf("invalid value for toolchain.channel in %s, must be a string", path)
+		}
+	}
+
+	toolchain.Components, err = toolchainList(values, "components", path)
+	if err != nil {
+		return Toolchain{}, err
+	}
+
+	toolchain.Targets, err = toolchainList(values, "targets", path)
+	if err != nil {
+		return Toolchain{}, err
+	}
+
+	return toolchain, nil
+}
+
+// FindToolchainFile returns the name of the first of ToolchainFiles that exists in projectDir, or an empty string
+func FindToolchainFile(projectDir string) (string, error) {
+	for _, name := range ToolchainFiles {
+		_, err := os.Stat(filepath.Join(projectDir, name))
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// LoadToolchain parses the toolchain file in projectDir, returning an empty Toolchain if there is none
+func LoadToolchain(projectDir string) (Toolchain, error) {
+	name, err := FindToolchainFile(projectDir)
+	if err != nil || name == "" {
+		return Toolchain{}, err
+	}
+
+	return ParseToolchainFile(filepath.Join(projectDir, name))
+}
+
+func toolchainList(values map[string]interface{}, key string, path string) ([]string, error) {
+	value, ok := values[key]
+	if !ok {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid value for toolchain.%s in %s, must be a list of strings", key, path)
+	}
+
+	var list []string
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for toolchain.%s in %s, must be a list of strings", key, path)
+		}
+		list = append(list, s)
+	}
+
+	return list, nil
+}
+
+func describeChannel(channel string) string {
+	if channel == "" {
+		return "unpinned"
+	}
+	return channel
+}
@@ -0,0 +1,94 @@
+package cargo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testToolchain(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "toolchain")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("parses the channel, components and targets", func() {
+		path := filepath.Join(workingDir, "rust-toolchain.toml")
+		Expect(ioutil.WriteFile(path, []byte(`[toolchain]
+channel = "1.56.1"
+components = ["clippy"]
+targets = ["x86_64-unknown-linux-musl", "aarch64-unknown-linux-gnu"]
+`), 0644)).To(Succeed())
+
+		toolchain, err := cargo.ParseToolchainFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toolchain).To(Equal(cargo.Toolchain{
+			Channel:    "1.56.1",
+			Components: []string{"clippy"},
+			Targets:    []string{"x86_64-unknown-linux-musl", "aarch64-unknown-linux-gnu"},
+		}))
+	})
+
+	it("parses a legacy file that only names the channel", func() {
+		path := filepath.Join(workingDir, "rust-toolchain")
+		Expect(ioutil.WriteFile(path, []byte("nightly-2021-11-01\n"), 0644)).To(Succeed())
+
+		toolchain, err := cargo.ParseToolchainFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toolchain).To(Equal(cargo.Toolchain{Channel: "nightly-2021-11-01"}))
+	})
+
+	it("parses a legacy file written as TOML", func() {
+		path := filepath.Join(workingDir, "rust-toolchain")
+		Expect(ioutil.WriteFile(path, []byte("[toolchain]\nchannel = \"stable\"\n"), 0644)).To(Succeed())
+
+		toolchain, err := cargo.ParseToolchainFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toolchain.Channel).To(Equal("stable"))
+	})
+
+	it("names the key that is malformed", func() {
+		path := filepath.Join(workingDir, "rust-toolchain.toml")
+		Expect(ioutil.WriteFile(path, []byte("[toolchain]\nchannel = 1\n"), 0644)).To(Succeed())
+
+		_, err := cargo.ParseToolchainFile(path)
+		Expect(err).To(MatchError(ContainSubstring("invalid value for toolchain.channel")))
+
+		Expect(ioutil.WriteFile(path, []byte("[toolchain]\ntargets = \"x86_64-unknown-linux-musl\"\n"), 0644)).To(Succeed())
+
+		_, err = cargo.ParseToolchainFile(path)
+		Expect(err).To(MatchError(ContainSubstring("invalid value for toolchain.targets")))
+	})
+
+	it("prefers rust-toolchain.toml over the legacy file", func() {
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain.toml"), []byte("[toolchain]\nchannel = \"stable\"\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "rust-toolchain"), []byte("beta\n"), 0644)).To(Succeed())
+
+		toolchain, err := cargo.LoadToolchain(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toolchain.Channel).To(Equal("stable"))
+	})
+
+	it("returns an empty toolchain when there is no toolchain file", func() {
+		toolchain, err := cargo.LoadToolchain(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toolchain).To(Equal(cargo.Toolchain{}))
+	})
+}
@@ -0,0 +1,108 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+//go:generate mockery --name Compressor --case=underscore
+
+// Compressor is something capable of compressing binaries in place
+type Compressor interface {
+	Available() bool
+	Compress(binPath string, args []string) error
+}
+
+// UPXCompressor can compress binaries via the upx CLI
+type UPXCompressor struct {
+	exec   Executable
+	logger scribe.Emitter
+}
+
+// NewUPXCompressor creates a new Compressor using the upx cli
+func NewUPXCompressor(exec Executable, logger scribe.Emitter) UPXCompressor {
+	return UPXCompressor{
+		exec:   exec,
+		logger: logger,
+	}
+}
+
+// Available checks if upx can be found on the PATH
+func (u UPXCompressor) Available() bool {
+	_, err := exec.LookPath("upx")
+	return err == nil
+}
+
+// Compress will compress the given binary in place using `upx`
+func (u UPXCompressor) Compress(binPath string, args []string) error {
+	execArgs := append([]string{}, args...)
+	execArgs = append(execArgs, binPath)
+
+	u.logger.Detail("upx %s", strings.Join(execArgs, " "))
+	err := u.exec.Execute(pexec.Execution{
+		Stdout: scribe.NewWriter(os.Stdout, scribe.WithIndent(5)),
+		Stderr: scribe.NewWriter(os.Stderr, scribe.WithIndent(5)),
+		Args:   execArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("compression failed: %w", err)
+	}
+
+	return nil
+}
+
+// CompressBinaries will compress every binary in binDir, skipping any that fail to compress
+func CompressBinaries(compressor Compressor, binDir string, logger scribe.Emitter) error {
+	if !compressor.Available() {
+		return fmt.Errorf("upx not found on PATH, but BP_CARGO_UPX is enabled")
+	}
+
+	args, err := shellwords.Parse(os.Getenv("BP_CARGO_UPX_ARGS"))
+	if err != nil {
+		return fmt.Errorf("parse args failed: %w", err)
+	}
+
+	files, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read directory\n%w", err)
+	}
+
+	logger.Process("Compressing binaries with UPX")
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+
+		binPath := filepath.Join(binDir, file.Name())
+		before, err := os.Stat(binPath)
+		if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", binPath, err)
+		}
+
+		err = compressor.Compress(binPath, args)
+		if err != nil {
+			logger.Subprocess("Skipping %s: %s", file.Name(), err)
+			continue
+		}
+
+		after, err := os.Stat(binPath)
+		if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", binPath, err)
+		}
+
+		logger.Subprocess("%s: %d bytes -> %d bytes", file.Name(), before.Size(), after.Size())
+	}
+	logger.Break()
+
+	return nil
+}
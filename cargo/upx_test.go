@@ -0,0 +1,51 @@
+package cargo_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/dmikusa/rust-cargo-cnb/cargo/mocks"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/gomega"
+)
+
+func testUPX(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("compressing a binary", func() {
+		it("passes args followed by the binary path", func() {
+			logBuf := bytes.Buffer{}
+			logger := scribe.NewEmitter(&logBuf)
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.MatchedBy(func(ex pexec.Execution) bool {
+				return reflect.DeepEqual(ex.Args, []string{"--best", "/some/bin/app"})
+			})).Return(nil)
+
+			err := cargo.NewUPXCompressor(&mockExe, logger).Compress("/some/bin/app", []string{"--best"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logBuf.String()).To(ContainSubstring("upx --best /some/bin/app"))
+			mockExe.AssertExpectations(t)
+		})
+
+		it("bubbles up failures", func() {
+			logBuf := bytes.Buffer{}
+			logger := scribe.NewEmitter(&logBuf)
+
+			mockExe := mocks.Executable{}
+			mockExe.On("Execute", mock.Anything).Return(fmt.Errorf("expected"))
+
+			err := cargo.NewUPXCompressor(&mockExe, logger).Compress("/some/bin/app", nil)
+			Expect(err).To(MatchError("compression failed: expected"))
+		})
+	})
+}
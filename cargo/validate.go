@@ -0,0 +1,105 @@
+package cargo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// DefaultValidateTimeout is how long the validation command may run when BP_CARGO_VALIDATE_TIMEOUT is not set
+const DefaultValidateTimeout = 5 * time.Minute
+
+var secretName = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|API_?KEY|PRIVATE_?KEY)`)
+
+// RunValidation runs command, the name of a built binary followed by its arguments, from srcDir and fails if it
+// exits non-zero or does not finish within timeout. Output is logged with the values of secret looking
+// environment variables redacted.
+func RunValidation(command string, srcDir string, binDir string, timeout time.Duration, logger scribe.Emitter) error {
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return fmt.Errorf("invalid BP_CARGO_VALIDATE_CMD: %w", err)
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	available, err := ListBinaries(binDir)
+	if err != nil {
+		return err
+	}
+
+	if !contains(available, args[0]) {
+		return fmt.Errorf("validation binary %s was not built, available binaries are [%s]", args[0], strings.Join(available, ", "))
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	logger.Process("Validating build with `%s`", strings.Join(args, " "))
+
+	output := bytes.Buffer{}
+	err = execute(ctx, NewCommandExecutable(filepath.Join(binDir, args[0])), pexec.Execution{
+		Dir:    srcDir,
+		Stdout: &output,
+		Stderr: &output,
+		Args:   args[1:],
+	})
+
+	if output.Len() > 0 {
+		writer := scribe.NewWriter(os.Stdout, scribe.WithIndent(2))
+		_, _ = writer.Write([]byte(Redact(output.String(), os.Environ())))
+		logger.Break()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("validation command %s did not finish within %s\n%w", args[0], timeout, err)
+	}
+	if err != nil {
+		return fmt.Errorf("validation command %s failed\n%w", args[0], err)
+	}
+
+	logger.Subprocess("Validation passed")
+	logger.Break()
+
+	return nil
+}
+
+// Redact replaces the values of environment variables in environ whose names suggest they hold a secret
+func Redact(text string, environ []string) string {
+	for _, value := range secretValues(environ) {
+		text = strings.ReplaceAll(text, value, "[REDACTED]")
+	}
+
+	return text
+}
+
+// secretValues returns the values of the secret looking variables in environ, longest first so that a secret
+// containing another secret is fully redacted
+func secretValues(environ []string) []string {
+	var values []string
+	for _, entry := range environ {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 && len(parts[1]) >= 4 && secretName.MatchString(parts[0]) {
+			values = append(values, parts[1])
+		}
+	}
+
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
@@ -0,0 +1,85 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testValidate(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		srcDir string
+		binDir string
+		logBuf bytes.Buffer
+		logger scribe.Emitter
+	)
+
+	writeScript := func(name string, script string) {
+		Expect(ioutil.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755)).To(Succeed())
+	}
+
+	it.Before(func() {
+		var err error
+		srcDir, err = ioutil.TempDir("", "src-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		binDir, err = ioutil.TempDir("", "bin-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		logBuf = bytes.Buffer{}
+		logger = scribe.NewEmitter(&logBuf)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(srcDir)).To(Succeed())
+		Expect(os.RemoveAll(binDir)).To(Succeed())
+	})
+
+	it("runs the binary with its arguments from the source directory", func() {
+		writeScript("myapp", `[ "$1 $2" = "config check" ] && pwd > validated`)
+
+		Expect(cargo.RunValidation("myapp config check", srcDir, binDir, time.Minute, logger)).To(Succeed())
+		Expect(filepath.Join(srcDir, "validated")).To(BeAnExistingFile())
+		Expect(logBuf.String()).To(ContainSubstring("Validating build with `myapp config check`"))
+		Expect(logBuf.String()).To(ContainSubstring("Validation passed"))
+	})
+
+	it("fails when the binary exits non-zero", func() {
+		writeScript("myapp", "exit 3")
+
+		err := cargo.RunValidation("myapp config check", srcDir, binDir, time.Minute, logger)
+		Expect(err).To(MatchError(ContainSubstring("validation command myapp failed")))
+		Expect(err).To(MatchError(ContainSubstring("exit status 3")))
+	})
+
+	it("fails when the binary does not finish in time", func() {
+		writeScript("myapp", "sleep 5")
+
+		err := cargo.RunValidation("myapp config check", srcDir, binDir, 10*time.Millisecond, logger)
+		Expect(err).To(MatchError(ContainSubstring("validation command myapp did not finish within 10ms")))
+	})
+
+	it("fails when the binary was not built", func() {
+		writeScript("myapp", "true")
+
+		err := cargo.RunValidation("other check", srcDir, binDir, time.Minute, logger)
+		Expect(err).To(MatchError("validation binary other was not built, available binaries are [myapp]"))
+	})
+
+	it("redacts secret looking environment variables", func() {
+		environ := []string{"DB_PASSWORD=hunter22", "API_KEY=abcd1234", "HOME=/home/cnb", "GITHUB_TOKEN=ab"}
+		Expect(cargo.Redact("connecting with hunter22 and abcd1234 from /home/cnb as ab", environ)).
+			To(Equal("connecting with [REDACTED] and [REDACTED] from /home/cnb as ab"))
+	})
+}
@@ -0,0 +1,37 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Verbosity returns how verbose cargo is made by BP_CARGO_VERBOSE, 0 when it is not set, 1 for `-v` or 2 for `-vv`
+func Verbosity() (int, error) {
+	switch level := strings.TrimSpace(os.Getenv("BP_CARGO_VERBOSE")); level {
+	case "", "0":
+		return 0, nil
+	case "1":
+		return 1, nil
+	case "2":
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("invalid value for BP_CARGO_VERBOSE %q, must be 1 or 2", level)
+	}
+}
+
+// VerboseArgs returns `-v` or `-vv` for BP_CARGO_VERBOSE, unless args already set cargo's verbosity
+func VerboseArgs(args []string) ([]string, error) {
+	verbosity, err := Verbosity()
+	if err != nil || verbosity == 0 {
+		return nil, err
+	}
+
+	for _, arg := range args {
+		if arg == "--verbose" || arg == "--quiet" || arg == "-q" || strings.HasPrefix(arg, "-v") {
+			return nil, nil
+		}
+	}
+
+	return []string{fmt.Sprintf("-%s", strings.Repeat("v", verbosity))}, nil
+}
@@ -0,0 +1,120 @@
+package cargo
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/scribe"
+)
+
+// CheckSiblingDependencies verifies that workspace members depend on each other through local paths. A member
+// that depends on a sibling through a registry or git source fails the check if the requested version does not
+// match the local sibling, and is logged as a warning if it does.
+func (m metadata) CheckSiblingDependencies(logger scribe.Emitter) error {
+	siblings := make(map[string]string)
+	for _, pkg := range m.Packages {
+		siblings[pkg.Name] = pkg.Version
+	}
+
+	var problems []string
+	for _, pkg := range m.Packages {
+		for _, dep := range pkg.Dependencies {
+			localVersion, isSibling := siblings[dep.Name]
+			if !isSibling || dep.Name == pkg.Name || dep.Source == "" {
+				continue
+			}
+
+			if requirementMatches(dep.Req, localVersion) {
+				logger.Subprocess("WARNING: member %s depends on %s %s from %s rather than the local workspace member", pkg.Name, dep.Name, dep.Req, dep.Source)
+				continue
+			}
+
+			problems = append(problems, fmt.Sprintf("member %s depends on %s %s from %s, but the workspace version of %s is %s",
+				pkg.Name, dep.Name, dep.Req, dep.Source, dep.Name, localVersion))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("workspace members depend on siblings that do not resolve locally, add `path` to these dependencies:\n%s",
+			strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// requirementMatches checks a Cargo version requirement against version, a bare version in a requirement is
+// treated as a caret requirement like Cargo does
+func requirementMatches(req string, version string) bool {
+	var terms []string
+	for _, term := range strings.Split(req, ",") {
+		term = strings.TrimSpace(term)
+		if term != "" && term[0] >= '0' && term[0] <= '9' {
+			term = "^" + term
+		}
+		terms = append(terms, term)
+	}
+
+	constraint, err := semver.NewConstraint(strings.Join(terms, ", "))
+	if err != nil {
+		return false
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(v)
+}
+
+// MemberFilter selects workspace members, like BP_CARGO_WORKSPACE_MEMBERS. Each pattern is a package name or a glob
+// that is matched against the package name and against the member's path relative to the project directory, like
+// `crates/*`.
+type MemberFilter struct {
+	patterns []string
+	matched  map[string]bool
+}
+
+// NewMemberFilter creates a filter from a comma separated list of patterns
+func NewMemberFilter(value string) MemberFilter {
+	filter := MemberFilter{matched: make(map[string]bool)}
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			filter.patterns = append(filter.patterns, pattern)
+		}
+	}
+	return filter
+}
+
+// Match checks if the member called name at relPath matches any pattern, and records the patterns that matched
+func (f MemberFilter) Match(name string, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+
+	found := false
+	for _, pattern := range f.patterns {
+		if pattern == name || globMatch(pattern, name) || globMatch(strings.TrimSuffix(pattern, "/"), relPath) {
+			f.matched[pattern] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// Unmatched lists the patterns that did not match any member
+func (f MemberFilter) Unmatched() []string {
+	var unmatched []string
+	for _, pattern := range f.patterns {
+		if !f.matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return unmatched
+}
+
+func globMatch(pattern string, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
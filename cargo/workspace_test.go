@@ -0,0 +1,114 @@
+package cargo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dmikusa/rust-cargo-cnb/cargo"
+	"github.com/dmikusa/rust-cargo-cnb/cargo/mocks"
+	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/scribe"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/onsi/gomega"
+)
+
+func testWorkspace(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect    = NewWithT(t).Expect
+		workLayer = packit.Layer{Name: "work-layer", Path: "/some/location/1"}
+		destLayer = packit.Layer{Name: "dest-layer", Path: "/some/location/2"}
+
+		logBuf bytes.Buffer
+		runner cargo.CLIRunner
+	)
+
+	runnerFor := func(fixture string) cargo.CLIRunner {
+		metadata, err := ioutil.ReadFile(fixture)
+		Expect(err).ToNot(HaveOccurred())
+
+		mockExe := mocks.Executable{}
+		mockExe.On("Execute", mock.Anything).Return(func(ex pexec.Execution) error {
+			_, err := ex.Stdout.Write(metadata)
+			return err
+		})
+
+		return cargo.NewCLIRunner(&mockExe, scribe.NewEmitter(&logBuf))
+	}
+
+	it.Before(func() {
+		logBuf = bytes.Buffer{}
+	})
+
+	context("when a member depends on a sibling from the registry", func() {
+		it.Before(func() {
+			runner = runnerFor("testdata/metadata_siblings.json")
+		})
+
+		it("fails for a mismatched version and warns for a matching version", func() {
+			_, err := runner.WorkspaceMembers("/workspace", workLayer, destLayer)
+			Expect(err).To(MatchError(ContainSubstring("member api depends on core ^2.0 from registry+https://github.com/rust-lang/crates.io-index, but the workspace version of core is 1.2.0")))
+			Expect(err).ToNot(MatchError(ContainSubstring("member worker")))
+			Expect(err).ToNot(MatchError(ContainSubstring("member cli")))
+			Expect(logBuf.String()).To(ContainSubstring("WARNING: member worker depends on core 1.1 from registry+https://github.com/rust-lang/crates.io-index rather than the local workspace member"))
+			Expect(logBuf.String()).ToNot(ContainSubstring("member cli"))
+		})
+	})
+
+	context("when members only depend on siblings by path", func() {
+		it.Before(func() {
+			runner = runnerFor("testdata/metadata.json")
+		})
+
+		it("passes", func() {
+			_, err := runner.WorkspaceMembers("/workspace", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logBuf.String()).To(BeEmpty())
+		})
+	})
+
+	context("when BP_CARGO_SKIP_UNPUBLISHED is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_CARGO_SKIP_UNPUBLISHED", "true")).To(Succeed())
+			runner = runnerFor("testdata/metadata_publish.json")
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_CARGO_SKIP_UNPUBLISHED")).To(Succeed())
+			Expect(os.Unsetenv("BP_CARGO_WORKSPACE_MEMBERS")).To(Succeed())
+		})
+
+		it("excludes members with publish = false", func() {
+			members, err := runner.WorkspaceMembers("/workspace", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(HaveLen(2))
+			Expect(members[0].Path).To(Equal("/workspace/api"))
+			Expect(members[1].Path).To(Equal("/workspace/admin"))
+			Expect(logBuf.String()).To(ContainSubstring("Skipping xtask because it sets `publish = false`"))
+			Expect(logBuf.String()).To(ContainSubstring("Skipping bench-tool because it sets `publish = false`"))
+		})
+
+		it("fails when every member would be excluded", func() {
+			Expect(os.Setenv("BP_CARGO_WORKSPACE_MEMBERS", "xtask,bench-tool")).To(Succeed())
+
+			_, err := runner.WorkspaceMembers("/workspace", workLayer, destLayer)
+			Expect(err).To(MatchError("BP_CARGO_SKIP_UNPUBLISHED excludes every member [xtask, bench-tool], nothing is left to build"))
+		})
+	})
+
+	context("when BP_CARGO_SKIP_UNPUBLISHED is not set", func() {
+		it.Before(func() {
+			runner = runnerFor("testdata/metadata_publish.json")
+		})
+
+		it("builds every member", func() {
+			members, err := runner.WorkspaceMembers("/workspace", workLayer, destLayer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(members).To(HaveLen(4))
+		})
+	})
+}
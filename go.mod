@@ -3,6 +3,8 @@ module github.com/dmikusa/rust-cargo-cnb
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/mattn/go-shellwords v1.0.12
 	github.com/onsi/gomega v1.14.0
 	github.com/paketo-buildpacks/packit v0.14.1
@@ -11,12 +11,14 @@ import (
 )
 
 func main() {
-	cargoExe := pexec.NewExecutable("cargo")
+	cargoExe := cargo.NewCommandExecutable("cargo")
+	upxExe := pexec.NewExecutable("upx")
 	logger := scribe.NewEmitter(os.Stdout)
 
 	packit.Run(
-		cargo.Detect(),
+		cargo.Detect(logger),
 		cargo.Build(
 			cargo.NewCLIRunner(cargoExe, logger),
+			cargo.NewUPXCompressor(upxExe, logger),
 			chronos.DefaultClock, logger))
 }